@@ -20,7 +20,7 @@ terraform {
   required_providers {
     google-beta = {
       source  = "hashicorp/google-beta"
-      version = "~> 3.57.0"
+      version = "~> 3.90.0"
     }
   }
 }
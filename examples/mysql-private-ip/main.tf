@@ -20,7 +20,7 @@ terraform {
   required_providers {
     google-beta = {
       source  = "hashicorp/google-beta"
-      version = "~> 3.57.0"
+      version = "~> 3.90.0"
     }
   }
 }
@@ -38,34 +38,42 @@ locals {
   instance_name        = var.name_override == null ? format("%s-%s", var.name_prefix, random_id.name.hex) : var.name_override
   private_network_name = "private-network-${random_id.name.hex}"
   private_ip_name      = "private-ip-${random_id.name.hex}"
+
+  # If private_network is specified, use that - otherwise use the network created below
+  create_private_network = var.private_network == null
+  private_network        = local.create_private_network ? google_compute_network.private_network[0].self_link : var.private_network
 }
 
 # ------------------------------------------------------------------------------
 # CREATE COMPUTE NETWORKS
+# Only if no existing private network was passed in
 # ------------------------------------------------------------------------------
 
 # Simple network, auto-creates subnetworks
 resource "google_compute_network" "private_network" {
+  count    = local.create_private_network ? 1 : 0
   provider = google-beta
   name     = local.private_network_name
 }
 
 # Reserve global internal address range for the peering
 resource "google_compute_global_address" "private_ip_address" {
+  count         = local.create_private_network ? 1 : 0
   provider      = google-beta
   name          = local.private_ip_name
   purpose       = "VPC_PEERING"
   address_type  = "INTERNAL"
   prefix_length = 16
-  network       = google_compute_network.private_network.self_link
+  network       = google_compute_network.private_network[0].self_link
 }
 
 # Establish VPC network peering connection using the reserved address range
 resource "google_service_networking_connection" "private_vpc_connection" {
+  count                   = local.create_private_network ? 1 : 0
   provider                = google-beta
-  network                 = google_compute_network.private_network.self_link
+  network                 = google_compute_network.private_network[0].self_link
   service                 = "servicenetworking.googleapis.com"
-  reserved_peering_ranges = [google_compute_global_address.private_ip_address.name]
+  reserved_peering_ranges = [google_compute_global_address.private_ip_address[0].name]
 }
 
 # ------------------------------------------------------------------------------
@@ -101,10 +109,10 @@ module "mysql" {
   master_user_host = "%"
 
   # Pass the private network link to the module
-  private_network = google_compute_network.private_network.self_link
+  private_network = local.private_network
 
-  # Wait for the vpc connection to complete
-  dependencies = [google_service_networking_connection.private_vpc_connection.network]
+  # Wait for the vpc connection to complete. An existing private network must already be connected.
+  dependencies = local.create_private_network ? [google_service_networking_connection.private_vpc_connection[0].network] : []
 
   # Set auto-increment flags to test the
   # feature during automated testing
@@ -119,7 +127,11 @@ module "mysql" {
     },
   ]
 
-  custom_labels = {
-    test-id = "mysql-private-ip-example"
-  }
+  # Additional labels, e.g. to track which test run created the instances
+  custom_labels = merge(
+    {
+      test-id = "mysql-private-ip-example"
+    },
+    var.custom_labels,
+  )
 }
@@ -40,3 +40,12 @@ output "db" {
   description = "Self link to the default database"
   value       = module.mysql.db
 }
+
+# ------------------------------------------------------------------------------
+# NETWORK OUTPUTS
+# ------------------------------------------------------------------------------
+
+output "private_network" {
+  description = "Self link to the private network the instance is connected to"
+  value       = local.private_network
+}
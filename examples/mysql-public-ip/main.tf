@@ -20,7 +20,7 @@ terraform {
   required_providers {
     google-beta = {
       source  = "hashicorp/google-beta"
-      version = "~> 3.57.0"
+      version = "~> 3.90.0"
     }
   }
 }
@@ -56,6 +56,14 @@ module "mysql" {
   engine       = var.mysql_version
   machine_type = var.machine_type
 
+  # Stop the instance by setting this to NEVER and start it again with ALWAYS
+  activation_policy = var.activation_policy
+
+  # Restrict system maintenance, which may restart the instance, to a weekly window
+  maintenance_window_day  = var.maintenance_window_day
+  maintenance_window_hour = var.maintenance_window_hour
+  maintenance_track       = var.maintenance_track
+
   # These together will construct the master_user privileges, i.e.
   # 'master_user_name'@'master_user_host' IDENTIFIED BY 'master_user_password'.
   # These should typically be set as the environment variable TF_VAR_master_user_password, etc.
@@ -65,6 +73,13 @@ module "mysql" {
   master_user_name = var.master_user_name
   master_user_host = "%"
 
+  # Additional databases and users, e.g. one schema and one login per application
+  additional_databases = var.additional_databases
+  additional_users     = var.additional_users
+
+  # IAM principals that log in with an OAuth 2.0 access token instead of a password
+  iam_users = var.iam_users
+
   # To make it easier to test this example, we are giving the instances public IP addresses and allowing inbound
   # connections from anywhere. We also disable deletion protection so we can destroy the databases during the tests.
   # In real-world usage, your instances should live in private subnets, only have private IP addresses, and only allow
@@ -97,7 +112,11 @@ module "mysql" {
     },
   ]
 
-  custom_labels = {
-    test-id = "mysql-public-ip-example"
-  }
+  # Additional labels, e.g. to track which test run created the instances
+  custom_labels = merge(
+    {
+      test-id = "mysql-public-ip-example"
+    },
+    var.custom_labels,
+  )
 }
@@ -40,3 +40,18 @@ output "db" {
   description = "Self link to the default database"
   value       = module.mysql.db
 }
+
+output "additional_db_names" {
+  description = "List of names of the additional databases"
+  value       = module.mysql.additional_db_names
+}
+
+output "additional_user_names" {
+  description = "List of names of the additional users"
+  value       = module.mysql.additional_user_names
+}
+
+output "iam_user_names" {
+  description = "Map of IAM principal emails to the database user names to log in with"
+  value       = module.mysql.iam_user_names
+}
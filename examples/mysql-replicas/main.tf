@@ -20,7 +20,7 @@ terraform {
   required_providers {
     google-beta = {
       source  = "hashicorp/google-beta"
-      version = "~> 3.57.0"
+      version = "~> 3.90.0"
     }
   }
 }
@@ -57,6 +57,7 @@ module "mysql" {
 
   engine       = var.mysql_version
   machine_type = var.machine_type
+  disk_size    = var.disk_size
 
   master_zone = var.master_zone
 
@@ -80,8 +81,25 @@ module "mysql" {
   mysql_failover_replica_zone = var.failover_replica_zone
 
   # Indicate we want read replicas to be created
-  num_read_replicas  = var.num_read_replicas
-  read_replica_zones = var.read_replica_zones
+  num_read_replicas    = var.num_read_replicas
+  read_replica_zones   = var.read_replica_zones
+  read_replica_regions = var.read_replica_regions
+
+  # Read replicas with stable names, which can be removed without affecting the other replicas
+  read_replicas = var.read_replicas
+
+  # Optionally use different settings for some of the replicas
+  read_replica_configs    = var.read_replica_configs
+  failover_replica_config = var.failover_replica_config
+
+  # Optionally encrypt the instances with customer-managed keys
+  encryption_key_name          = var.encryption_key_name
+  replica_encryption_key_names = var.replica_encryption_key_names
+
+  # Optionally collect query performance metrics with Query Insights
+  query_insights_enabled  = var.query_insights_enabled
+  query_string_length     = var.query_string_length
+  record_application_tags = var.record_application_tags
 
   # These together will construct the master_user privileges, i.e.
   # 'master_user_name'@'master_user_host' IDENTIFIED BY 'master_user_password'.
@@ -105,7 +123,11 @@ module "mysql" {
     },
   ]
 
-  custom_labels = {
-    test-id = "mysql-replicas-example"
-  }
+  # Additional labels, e.g. to track which test run created the instances
+  custom_labels = merge(
+    {
+      test-id = "mysql-replicas-example"
+    },
+    var.custom_labels,
+  )
 }
@@ -57,3 +57,15 @@ variable "name_override" {
   type        = string
   default     = null
 }
+
+variable "custom_labels" {
+  description = "A map of additional labels to apply to the instances. The key is the label name and the value is the label value."
+  type        = map(string)
+  default     = {}
+}
+
+variable "private_network" {
+  description = "Self link to an existing network to connect the instance to. The network must already have a private services connection. If not set, a new network with a private services connection is created."
+  type        = string
+  default     = null
+}
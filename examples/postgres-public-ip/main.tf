@@ -20,7 +20,7 @@ terraform {
   required_providers {
     google-beta = {
       source  = "hashicorp/google-beta"
-      version = "~> 3.57.0"
+      version = "~> 3.90.0"
     }
   }
 }
@@ -63,6 +63,13 @@ module "postgres" {
   master_user_password = var.master_user_password
   master_user_name     = var.master_user_name
 
+  # Additional databases and users, e.g. one schema and one login per application
+  additional_databases = var.additional_databases
+  additional_users     = var.additional_users
+
+  # IAM principals that log in with an OAuth 2.0 access token instead of a password
+  iam_users = var.iam_users
+
   # To make it easier to test this example, we are giving the instances public IP addresses and allowing inbound
   # connections from anywhere. We also disable deletion protection so we can destroy the databases during the tests.
   # In real-world usage, your instances should live in private subnets, only have private IP addresses, and only allow
@@ -91,7 +98,11 @@ module "postgres" {
     },
   ]
 
-  custom_labels = {
-    test-id = "postgres-public-ip-example"
-  }
+  # Additional labels, e.g. to track which test run created the instances
+  custom_labels = merge(
+    {
+      test-id = "postgres-public-ip-example"
+    },
+    var.custom_labels,
+  )
 }
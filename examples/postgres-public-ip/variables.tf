@@ -65,3 +65,27 @@ variable "require_ssl" {
   type        = bool
   default     = false
 }
+
+variable "additional_databases" {
+  description = "A map of additional databases to create, keyed by database name. Each value is a map that may set the 'charset' and 'collation' of that database."
+  type        = map(map(string))
+  default     = {}
+}
+
+variable "additional_users" {
+  description = "A map of additional users to create, keyed by user name. Each value is a map that must set the 'password' and may set the 'host' of that user."
+  type        = map(map(string))
+  default     = {}
+}
+
+variable "iam_users" {
+  description = "A list of IAM principals that log in with IAM database authentication. Each entry is a map with the 'email' of the user or service account and an optional 'type'."
+  type        = list(map(string))
+  default     = []
+}
+
+variable "custom_labels" {
+  description = "A map of additional labels to apply to the instances. The key is the label name and the value is the label value."
+  type        = map(string)
+  default     = {}
+}
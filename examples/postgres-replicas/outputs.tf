@@ -45,6 +45,11 @@ output "read_replica_instance_names" {
   value       = module.postgres.read_replica_instance_names
 }
 
+output "read_replica_instance_names_by_key" {
+  description = "Map of read replica keys to the names of the read replica instances"
+  value       = module.postgres.read_replica_instance_names_by_key
+}
+
 output "read_replica_public_ips" {
   description = "List of public IPv4 addresses of the read replica instances"
   value       = module.postgres.read_replica_public_ip_addresses
@@ -55,6 +60,11 @@ output "read_replica_instances" {
   value       = module.postgres.read_replica_instances
 }
 
+output "read_replica_regions" {
+  description = "List of regions of the read replica instances"
+  value       = module.postgres.read_replica_regions
+}
+
 output "read_replica_proxy_connections" {
   description = "List of read replica instance paths for connecting with Cloud SQL Proxy. Read more at https://cloud.google.com/sql/docs/mysql/sql-proxy"
   value       = module.postgres.read_replica_proxy_connections
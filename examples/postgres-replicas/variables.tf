@@ -80,3 +80,60 @@ variable "name_override" {
   type        = string
   default     = null
 }
+
+variable "encryption_key_name" {
+  description = "The full path to a customer-managed Cloud KMS key to encrypt the master instance with. If null, Google-managed encryption is used."
+  type        = string
+  default     = null
+}
+
+variable "replica_encryption_key_names" {
+  description = "A map of region to the customer-managed Cloud KMS key to encrypt the replicas in that region with. Replicas in the master's region default to 'encryption_key_name'."
+  type        = map(string)
+  default     = {}
+}
+
+variable "query_insights_enabled" {
+  description = "Set to true to enable Query Insights on the master and replica instances."
+  type        = bool
+  default     = false
+}
+
+variable "query_string_length" {
+  description = "Maximum query length stored by Query Insights in bytes."
+  type        = number
+  default     = 1024
+}
+
+variable "record_application_tags" {
+  description = "Set to true to let Query Insights record the application tags of queries."
+  type        = bool
+  default     = false
+}
+
+variable "read_replica_regions" {
+  description = "A list of regions where read replicas should be created. If empty, all read replicas are created in 'region'. Otherwise, list size should match 'num_read_replicas'."
+  type        = list(string)
+  default     = []
+
+  # Example:
+  #  default = ["us-central1", "us-east1"]
+}
+
+variable "read_replica_configs" {
+  description = "A list of settings overrides for the read replicas, in the same order as 'read_replica_zones'. See the cloud-sql module for the supported settings."
+  type        = any
+  default     = []
+}
+
+variable "read_replicas" {
+  description = "A map of additional read replicas keyed by a stable name, which is appended to the instance name. See the cloud-sql module for the supported settings."
+  type        = any
+  default     = {}
+}
+
+variable "custom_labels" {
+  description = "A map of additional labels to apply to the instances. The key is the label name and the value is the label value."
+  type        = map(string)
+  default     = {}
+}
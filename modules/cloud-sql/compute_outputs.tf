@@ -65,6 +65,6 @@ data "template_file" "failover_certificate_sha1_fingerprint" {
 # ------------------------------------------------------------------------------
 
 data "template_file" "read_replica_proxy_connection" {
-  count    = var.num_read_replicas
-  template = "${var.project}:${var.region}:${google_sql_database_instance.read_replica.*.name[count.index]}"
+  for_each = local.read_replicas
+  template = "${var.project}:${each.value.region}:${google_sql_database_instance.read_replica[each.key].name}"
 }
@@ -27,6 +27,86 @@ locals {
   actual_binary_log_enabled     = local.is_postgres ? false : var.mysql_binary_log_enabled
   actual_availability_type      = local.is_postgres && var.enable_failover_replica ? "REGIONAL" : "ZONAL"
   actual_failover_replica_count = local.is_postgres ? 0 : var.enable_failover_replica ? 1 : 0
+
+  # Replicas have to be encrypted with a key in their own region. Replicas in the master's region fall back to the
+  # master's key.
+  replica_encryption_key_name = lookup(var.replica_encryption_key_names, var.region, var.encryption_key_name)
+
+  # IAM database authentication has to be enabled with an engine specific flag whenever IAM users are configured
+  iam_authentication_flag  = local.is_postgres ? "cloudsql.iam_authentication" : "cloudsql_iam_authentication"
+  iam_authentication_flags = length(var.iam_users) == 0 ? [] : [
+    {
+      name  = local.iam_authentication_flag
+      value = "on"
+    },
+  ]
+  actual_database_flags = concat(var.database_flags, local.iam_authentication_flags)
+
+  # Read replicas are keyed by a stable name, so adding or removing one doesn't affect the others. The replicas
+  # configured with 'num_read_replicas' and the related lists are keyed by their index as "read-<index>", which keeps
+  # the instance names they had before. Their configs are padded to one per replica, so replicas without a config use
+  # the defaults.
+  read_replica_configs = [
+    for index in range(var.num_read_replicas) :
+    concat(var.read_replica_configs, [for padding in range(var.num_read_replicas) : {}])[index]
+  ]
+  indexed_read_replicas = [
+    for index, config in local.read_replica_configs : {
+      key    = "read-${index}"
+      config = config
+      region = length(var.read_replica_regions) == 0 ? var.region : element(var.read_replica_regions, index)
+      zone   = lookup(config, "zone", null) == null ? element(var.read_replica_zones, index) : config.zone
+    }
+  ]
+  named_read_replicas = [
+    for key, config in var.read_replicas : {
+      key    = key
+      config = config
+      region = lookup(config, "region", var.region)
+      zone   = lookup(config, "zone", null)
+    }
+  ]
+
+  # The keys of the read replicas in the order of the list outputs: the replicas configured with 'num_read_replicas' by
+  # index, as before the replicas were keyed, followed by the replicas configured with 'read_replicas' by key
+  read_replica_keys = concat(
+    [for index in range(var.num_read_replicas) : "read-${index}"],
+    sort(keys(var.read_replicas)),
+  )
+
+  # Replicas can override the settings they would otherwise inherit from the master. If the master is encrypted,
+  # replicas in other regions need a key in 'replica_encryption_key_names', so indexing the map makes the plan fail
+  # early instead of creating an unencrypted replica.
+  read_replicas = {
+    for replica in concat(local.indexed_read_replicas, local.named_read_replicas) : replica.key => {
+      region              = replica.region
+      zone                = replica.zone
+      machine_type        = lookup(replica.config, "machine_type", var.machine_type)
+      disk_size           = lookup(replica.config, "disk_size", var.disk_size)
+      disk_type           = lookup(replica.config, "disk_type", var.disk_type)
+      custom_labels       = merge(var.custom_labels, lookup(replica.config, "custom_labels", {}))
+      database_flags      = concat([for flag in local.actual_database_flags : flag if !contains([for override in lookup(replica.config, "database_flags", []) : override.name], flag.name)], lookup(replica.config, "database_flags", []))
+      encryption_key_name = replica.region == var.region ? local.replica_encryption_key_name : var.encryption_key_name == null ? lookup(var.replica_encryption_key_names, replica.region, null) : var.replica_encryption_key_names[replica.region]
+    }
+  }
+
+  # The failover replica can override the same settings as the read replicas
+  failover_replica_zone           = lookup(var.failover_replica_config, "zone", var.mysql_failover_replica_zone)
+  failover_replica_database_flags = concat(
+    [for flag in local.actual_database_flags : flag if !contains([for override in lookup(var.failover_replica_config, "database_flags", []) : override.name], flag.name)],
+    lookup(var.failover_replica_config, "database_flags", []),
+  )
+
+  # Postgres IAM users are created with the full email of users and without the '.gserviceaccount.com' suffix for
+  # service accounts, which is also the name they log in with. MySQL IAM users are created with the full email, so the
+  # API can resolve the principal, and Cloud SQL shortens their login name to the part before the '@' itself.
+  iam_users = {
+    for user in var.iam_users : user.email => {
+      name       = local.is_postgres ? trimsuffix(user.email, ".gserviceaccount.com") : user.email
+      login_name = local.is_postgres ? trimsuffix(user.email, ".gserviceaccount.com") : split("@", user.email)[0]
+      type       = lookup(user, "type", replace(user.email, ".gserviceaccount.com", "") != user.email ? "CLOUD_IAM_SERVICE_ACCOUNT" : "CLOUD_IAM_USER")
+    }
+  }
 }
 
 # ------------------------------------------------------------------------------
@@ -48,6 +128,9 @@ resource "google_sql_database_instance" "master" {
   # Whether or not to allow Terraform to destroy the instance.
   deletion_protection = var.deletion_protection
 
+  # The customer-managed key to encrypt the instance with. Can't be changed after creation.
+  encryption_key_name = var.encryption_key_name
+
   settings {
     tier              = var.machine_type
     activation_policy = var.activation_policy
@@ -92,8 +175,15 @@ resource "google_sql_database_instance" "master" {
     disk_type         = var.disk_type
     availability_type = local.actual_availability_type
 
+    insights_config {
+      query_insights_enabled  = var.query_insights_enabled
+      query_string_length     = var.query_string_length
+      record_application_tags = var.record_application_tags
+      record_client_address   = var.record_client_address
+    }
+
     dynamic "database_flags" {
-      for_each = var.database_flags
+      for_each = local.actual_database_flags
       content {
         name  = database_flags.value.name
         value = database_flags.value.value
@@ -140,6 +230,46 @@ resource "google_sql_user" "default" {
   password = var.master_user_password
 }
 
+# ------------------------------------------------------------------------------
+# CREATE ADDITIONAL DATABASES AND USERS
+# ------------------------------------------------------------------------------
+
+resource "google_sql_database" "additional" {
+  for_each = var.additional_databases
+
+  depends_on = [google_sql_user.default]
+
+  name      = each.key
+  project   = var.project
+  instance  = google_sql_database_instance.master.name
+  charset   = lookup(each.value, "charset", null)
+  collation = lookup(each.value, "collation", null)
+}
+
+resource "google_sql_user" "additional" {
+  for_each = var.additional_users
+
+  depends_on = [google_sql_database.additional]
+
+  project  = var.project
+  name     = each.key
+  instance = google_sql_database_instance.master.name
+  # Postgres users don't have hosts, see the comment on google_sql_user.default above.
+  host     = local.is_postgres ? null : lookup(each.value, "host", "%")
+  password = each.value.password
+}
+
+resource "google_sql_user" "iam" {
+  for_each = local.iam_users
+
+  depends_on = [google_sql_user.additional]
+
+  project  = var.project
+  name     = each.value.name
+  instance = google_sql_database_instance.master.name
+  type     = each.value.type
+}
+
 # ------------------------------------------------------------------------------
 # SET MODULE DEPENDENCY RESOURCE
 # This works around a terraform limitation where we can not specify module dependencies natively.
@@ -165,6 +295,9 @@ resource "google_sql_database_instance" "failover_replica" {
     google_sql_database_instance.master,
     google_sql_database.default,
     google_sql_user.default,
+    google_sql_database.additional,
+    google_sql_user.additional,
+    google_sql_user.iam,
   ]
 
   provider         = google-beta
@@ -179,6 +312,8 @@ resource "google_sql_database_instance" "failover_replica" {
   # Whether or not to allow Terraform to destroy the instance.
   deletion_protection = var.deletion_protection
 
+  encryption_key_name = local.replica_encryption_key_name
+
   replica_configuration {
     # Specifies that the replica is the failover target.
     failover_target = true
@@ -187,7 +322,7 @@ resource "google_sql_database_instance" "failover_replica" {
   settings {
     crash_safe_replication = true
 
-    tier            = var.machine_type
+    tier            = lookup(var.failover_replica_config, "machine_type", var.machine_type)
     disk_autoresize = var.disk_autoresize
 
     ip_configuration {
@@ -205,25 +340,32 @@ resource "google_sql_database_instance" "failover_replica" {
     }
 
     dynamic "location_preference" {
-      for_each = var.mysql_failover_replica_zone == null ? [] : [var.mysql_failover_replica_zone]
+      for_each = local.failover_replica_zone == null ? [] : [local.failover_replica_zone]
 
       content {
         zone = location_preference.value
       }
     }
 
-    disk_size = var.disk_size
-    disk_type = var.disk_type
+    disk_size = lookup(var.failover_replica_config, "disk_size", var.disk_size)
+    disk_type = lookup(var.failover_replica_config, "disk_type", var.disk_type)
+
+    insights_config {
+      query_insights_enabled  = var.query_insights_enabled
+      query_string_length     = var.query_string_length
+      record_application_tags = var.record_application_tags
+      record_client_address   = var.record_client_address
+    }
 
     dynamic "database_flags" {
-      for_each = var.database_flags
+      for_each = local.failover_replica_database_flags
       content {
         name  = database_flags.value.name
         value = database_flags.value.value
       }
     }
 
-    user_labels = var.custom_labels
+    user_labels = merge(var.custom_labels, lookup(var.failover_replica_config, "custom_labels", {}))
   }
 
   # Default timeouts are 10 minutes, which in most cases should be enough.
@@ -241,19 +383,22 @@ resource "google_sql_database_instance" "failover_replica" {
 # ------------------------------------------------------------------------------
 
 resource "google_sql_database_instance" "read_replica" {
-  count = var.num_read_replicas
+  for_each = local.read_replicas
 
   depends_on = [
     google_sql_database_instance.master,
     google_sql_database_instance.failover_replica,
     google_sql_database.default,
     google_sql_user.default,
+    google_sql_database.additional,
+    google_sql_user.additional,
+    google_sql_user.iam,
   ]
 
   provider         = google-beta
-  name             = "${var.name}-read-${count.index}"
+  name             = "${var.name}-${each.key}"
   project          = var.project
-  region           = var.region
+  region           = each.value.region
   database_version = var.engine
 
   # The name of the instance that will act as the master in the replication setup.
@@ -262,13 +407,15 @@ resource "google_sql_database_instance" "read_replica" {
   # Whether or not to allow Terraform to destroy the instance.
   deletion_protection = var.deletion_protection
 
+  encryption_key_name = each.value.encryption_key_name
+
   replica_configuration {
     # Specifies that the replica is not the failover target.
     failover_target = false
   }
 
   settings {
-    tier            = var.machine_type
+    tier            = each.value.machine_type
     disk_autoresize = var.disk_autoresize
 
     ip_configuration {
@@ -285,22 +432,33 @@ resource "google_sql_database_instance" "read_replica" {
       require_ssl     = var.require_ssl
     }
 
-    location_preference {
-      zone = element(var.read_replica_zones, count.index)
+    dynamic "location_preference" {
+      for_each = each.value.zone == null ? [] : [each.value.zone]
+
+      content {
+        zone = location_preference.value
+      }
     }
 
-    disk_size = var.disk_size
-    disk_type = var.disk_type
+    disk_size = each.value.disk_size
+    disk_type = each.value.disk_type
+
+    insights_config {
+      query_insights_enabled  = var.query_insights_enabled
+      query_string_length     = var.query_string_length
+      record_application_tags = var.record_application_tags
+      record_client_address   = var.record_client_address
+    }
 
     dynamic "database_flags" {
-      for_each = var.database_flags
+      for_each = each.value.database_flags
       content {
         name  = database_flags.value.name
         value = database_flags.value.value
       }
     }
 
-    user_labels = var.custom_labels
+    user_labels = each.value.custom_labels
   }
 
   # Read replica creation is initiated concurrently, but the provider creates
@@ -325,6 +483,9 @@ data "template_file" "complete" {
     google_sql_database_instance.read_replica,
     google_sql_database.default,
     google_sql_user.default,
+    google_sql_database.additional,
+    google_sql_user.additional,
+    google_sql_user.iam,
   ]
 
   template = true
@@ -27,6 +27,11 @@ output "master_instance" {
   value       = google_sql_database_instance.master.self_link
 }
 
+output "master_encryption_key_name" {
+  description = "The customer-managed Cloud KMS key the master instance is encrypted with, if any"
+  value       = google_sql_database_instance.master.encryption_key_name
+}
+
 output "master_proxy_connection" {
   description = "Master instance path for connecting with Cloud SQL Proxy. Read more at https://cloud.google.com/sql/docs/mysql/sql-proxy"
   value       = "${var.project}:${var.region}:${google_sql_database_instance.master.name}"
@@ -75,6 +80,26 @@ output "db_name" {
   value       = google_sql_database.default.name
 }
 
+output "additional_dbs" {
+  description = "Map of additional database names to their self links"
+  value       = { for name, db in google_sql_database.additional : name => db.self_link }
+}
+
+output "additional_db_names" {
+  description = "List of names of the additional databases"
+  value       = [for db in google_sql_database.additional : db.name]
+}
+
+output "additional_user_names" {
+  description = "List of names of the additional users"
+  value       = [for user in google_sql_user.additional : user.name]
+}
+
+output "iam_user_names" {
+  description = "Map of IAM principal emails to the database user names to log in with"
+  value       = { for email, user in google_sql_user.iam : email => local.iam_users[email].login_name }
+}
+
 # ------------------------------------------------------------------------------
 # FAILOVER REPLICA OUTPUTS - ONLY APPLICABLE TO MYSQL
 # ------------------------------------------------------------------------------
@@ -142,39 +167,57 @@ output "failover_replica_ca_cert_sha1_fingerprint" {
 # READ REPLICA OUTPUTS
 # ------------------------------------------------------------------------------
 
+# The list outputs list the replicas configured with 'num_read_replicas' by index, followed by the replicas configured
+# with 'read_replicas' sorted by key.
+
+output "read_replica_keys" {
+  description = "List of keys of the read replica instances"
+  value       = local.read_replica_keys
+}
+
 output "read_replica_instance_names" {
   description = "List of names for the read replica instances"
-  value       = google_sql_database_instance.read_replica.*.name
+  value       = [for key in local.read_replica_keys : google_sql_database_instance.read_replica[key].name]
+}
+
+output "read_replica_instance_names_by_key" {
+  description = "Map of read replica keys to the names of the read replica instances"
+  value       = { for key, replica in google_sql_database_instance.read_replica : key => replica.name }
 }
 
 output "read_replica_ip_addresses" {
   description = "All IP addresses of the read replica instances JSON encoded, see https://www.terraform.io/docs/providers/google/r/sql_database_instance.html#ip_address-0-ip_address"
-  value       = jsonencode(google_sql_database_instance.read_replica.*.ip_address)
+  value       = jsonencode([for key in local.read_replica_keys : google_sql_database_instance.read_replica[key].ip_address])
 }
 
 output "read_replica_public_ip_addresses" {
   description = "List of public IPv4 addresses of the read replica instances."
-  value       = google_sql_database_instance.read_replica.*.public_ip_address
+  value       = [for key in local.read_replica_keys : google_sql_database_instance.read_replica[key].public_ip_address]
 }
 
 output "read_replica_private_ip_addresses" {
   description = "List of private IPv4 addresses of the read replica instances."
-  value       = google_sql_database_instance.read_replica.*.private_ip_address
+  value       = [for key in local.read_replica_keys : google_sql_database_instance.read_replica[key].private_ip_address]
 }
 
 output "read_replica_instances" {
   description = "List of self links to the read replica instances"
-  value       = google_sql_database_instance.read_replica.*.self_link
+  value       = [for key in local.read_replica_keys : google_sql_database_instance.read_replica[key].self_link]
+}
+
+output "read_replica_regions" {
+  description = "List of regions of the read replica instances"
+  value       = [for key in local.read_replica_keys : google_sql_database_instance.read_replica[key].region]
 }
 
 output "read_replica_proxy_connections" {
   description = "List of read replica instance paths for connecting with Cloud SQL Proxy. Read more at https://cloud.google.com/sql/docs/mysql/sql-proxy"
-  value       = data.template_file.read_replica_proxy_connection.*.rendered
+  value       = [for key in local.read_replica_keys : data.template_file.read_replica_proxy_connection[key].rendered]
 }
 
 output "read_replica_server_ca_certs" {
   description = "JSON encoded list of CA Certificates used to connect to the read replica instances via SSL"
-  value       = jsonencode(google_sql_database_instance.read_replica.*.server_ca_cert)
+  value       = jsonencode([for key in local.read_replica_keys : google_sql_database_instance.read_replica[key].server_ca_cert])
 }
 
 # ------------------------------------------------------------------------------
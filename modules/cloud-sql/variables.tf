@@ -111,6 +111,30 @@ variable "maintenance_track" {
   default     = "stable"
 }
 
+variable "query_insights_enabled" {
+  description = "Set to true to enable Query Insights, which collects query performance metrics, on the master and replica instances."
+  type        = bool
+  default     = false
+}
+
+variable "query_string_length" {
+  description = "Maximum query length stored by Query Insights in bytes, between 256 and 4500. Longer queries are truncated. Only applicable if 'query_insights_enabled' is true."
+  type        = number
+  default     = 1024
+}
+
+variable "record_application_tags" {
+  description = "Set to true to let Query Insights record the application tags of queries, see https://cloud.google.com/sql/docs/mysql/using-query-insights#filter-tags. Only applicable if 'query_insights_enabled' is true."
+  type        = bool
+  default     = false
+}
+
+variable "record_client_address" {
+  description = "Set to true to let Query Insights record the client IP addresses of queries. Only applicable if 'query_insights_enabled' is true."
+  type        = bool
+  default     = false
+}
+
 variable "db_charset" {
   description = "The charset for the default database."
   type        = string
@@ -123,6 +147,55 @@ variable "db_collation" {
   default     = null
 }
 
+variable "additional_databases" {
+  description = "A map of additional databases to create on the master instance, keyed by database name. Each value is a map that may set the 'charset' and 'collation' of that database."
+  type        = map(map(string))
+  default     = {}
+
+  # Example:
+  #
+  # additional_databases = {
+  #   reporting = {
+  #     charset   = "utf8mb4"
+  #     collation = "utf8mb4_general_ci"
+  #   }
+  #   audit = {}
+  # }
+}
+
+variable "additional_users" {
+  description = "A map of additional users to create on the master instance, keyed by user name. Each value is a map that must set the 'password' and may set the 'host' of that user, i.e. 'name'@'host' IDENTIFIED BY 'password'. The host defaults to '%' and is ignored for Postgres instances."
+  type        = map(map(string))
+  default     = {}
+
+  # Example:
+  #
+  # additional_users = {
+  #   app = {
+  #     password = "app-password"
+  #     host     = "10.0.0.%" # optional, MySQL only
+  #   }
+  # }
+}
+
+variable "iam_users" {
+  description = "A list of IAM principals that log in to the master instance with IAM database authentication. Each entry is a map with the 'email' of the user or service account and an optional 'type', either `CLOUD_IAM_USER` or `CLOUD_IAM_SERVICE_ACCOUNT`, which is derived from the email if omitted. Setting this enables the IAM authentication database flag."
+  type        = list(map(string))
+  default     = []
+
+  # Example:
+  #
+  # iam_users = [
+  #   {
+  #     email = "jane@example.com"
+  #   },
+  #   {
+  #     email = "app@my-project.iam.gserviceaccount.com"
+  #     type  = "CLOUD_IAM_SERVICE_ACCOUNT" # optional
+  #   },
+  # ]
+}
+
 variable "database_flags" {
   description = "List of Cloud SQL flags that are applied to the database server"
   type        = list(any)
@@ -191,6 +264,18 @@ variable "mysql_failover_replica_zone" {
   default     = null
 }
 
+variable "failover_replica_config" {
+  description = "Settings overrides for the failover replica. The object may set 'machine_type', 'disk_size', 'disk_type' and 'zone' to replace the value used for the failover replica, 'database_flags' to add flags or replace flags with the same name, and 'custom_labels' to add or replace labels. Only applicable to MySQL."
+  type        = any
+  default     = {}
+
+  # Example:
+  #
+  # failover_replica_config = {
+  #   zone = "us-central1-b"
+  # }
+}
+
 variable "require_ssl" {
   description = "True if the instance should require SSL/TLS for users connecting over IP. Note: SSL/TLS is needed to provide security when you connect to Cloud SQL using IP addresses. If you are connecting to your instance only by using the Cloud SQL Proxy or the Java Socket Library, you do not need to configure your instance to use SSL/TLS."
   type        = bool
@@ -203,14 +288,33 @@ variable "private_network" {
   default     = null
 }
 
+variable "read_replicas" {
+  description = "A map of read replicas to create, keyed by a short name that is appended to 'name' to form the instance name. Adding or removing a replica doesn't affect the others, unlike the replicas configured with the deprecated 'num_read_replicas'. Each value is an object that may set the 'region' and 'zone' of the replica, plus the settings overrides supported by 'read_replica_configs'. Keys must not clash with the 'read-<index>' keys of the replicas configured with 'num_read_replicas'."
+  type        = any
+  default     = {}
+
+  # Example:
+  #
+  # read_replicas = {
+  #   reporting = {
+  #     zone = "us-central1-b"
+  #   }
+  #   dr = {
+  #     region       = "us-east1"
+  #     zone         = "us-east1-c"
+  #     machine_type = "db-custom-4-15360"
+  #   }
+  # }
+}
+
 variable "num_read_replicas" {
-  description = "The number of read replicas to create. Cloud SQL will replicate all data from the master to these replicas, which you can use to horizontally scale read traffic."
+  description = "DEPRECATED: use 'read_replicas' instead. The number of read replicas to create. Cloud SQL will replicate all data from the master to these replicas, which you can use to horizontally scale read traffic. These replicas are keyed by their index as 'read-<index>', so removing any but the last one recreates the replicas after it. To migrate, configure the same replicas in 'read_replicas' with the same 'read-<index>' keys."
   type        = number
   default     = 0
 }
 
 variable "read_replica_zones" {
-  description = "A list of compute zones where read replicas should be created. List size should match 'num_read_replicas'"
+  description = "DEPRECATED: use 'read_replicas' instead. A list of compute zones where read replicas should be created. List size should match 'num_read_replicas'"
   type        = list(string)
   default     = []
 
@@ -218,6 +322,58 @@ variable "read_replica_zones" {
   #  default = ["us-central1-b", "us-central1-c"]
 }
 
+variable "read_replica_configs" {
+  description = "DEPRECATED: use 'read_replicas' instead. A list of settings overrides for the read replicas, in the same order as 'read_replica_zones'. Each entry is an object that may set 'machine_type', 'disk_size', 'disk_type' and 'zone' to replace the value used for that replica, 'database_flags' to add flags or replace flags with the same name, and 'custom_labels' to add or replace labels. Replicas without an entry use the same settings as the master."
+  type        = any
+  default     = []
+
+  # Example:
+  #
+  # read_replica_configs = [
+  #   {},
+  #   {
+  #     machine_type = "db-custom-4-15360"
+  #     disk_size    = 100
+  #     database_flags = [
+  #       {
+  #         name  = "long_query_time"
+  #         value = "10"
+  #       },
+  #     ]
+  #     custom_labels = {
+  #       workload = "analytics"
+  #     }
+  #   },
+  # ]
+}
+
+variable "read_replica_regions" {
+  description = "DEPRECATED: use 'read_replicas' instead. A list of regions where read replicas should be created, e.g. to keep a replica in another region for disaster recovery. If empty, all read replicas are created in 'region'. Otherwise, list size should match 'num_read_replicas' and each zone in 'read_replica_zones' has to be in the region of the same replica."
+  type        = list(string)
+  default     = []
+
+  # Example:
+  #  default = ["us-central1", "us-east1"]
+}
+
+variable "encryption_key_name" {
+  description = "The full path to a customer-managed Cloud KMS key to encrypt the master instance with, e.g. 'projects/my-project/locations/us-central1/keyRings/my-ring/cryptoKeys/my-key'. The key has to be in the same region as the instance and the Cloud SQL service account needs the Cloud KMS CryptoKey Encrypter/Decrypter role on it. If null, Google-managed encryption is used. Can't be changed after creation."
+  type        = string
+  default     = null
+}
+
+variable "replica_encryption_key_names" {
+  description = "A map of region to the customer-managed Cloud KMS key to encrypt the failover and read replicas in that region with. Replicas in the master's region use 'encryption_key_name' if their region is not in this map. If 'encryption_key_name' is set, every region in 'read_replica_regions' other than 'region' must be in this map."
+  type        = map(string)
+  default     = {}
+
+  # Example:
+  #
+  # replica_encryption_key_names = {
+  #   us-central1 = "projects/my-project/locations/us-central1/keyRings/my-ring/cryptoKeys/my-key"
+  # }
+}
+
 variable "custom_labels" {
   description = "A map of custom labels to apply to the instance. The key is the label name and the value is the label value."
   type        = map(string)
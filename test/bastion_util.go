@@ -0,0 +1,107 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/sshtunnel"
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/ssh"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+const BASTION_FIXTURE_DIR = "test/fixtures/bastion"
+const BASTION_SSH_USER = "terratest"
+const BASTION_KEY_SIZE = 2048
+
+const KEY_BASTION_DIR = "bastionDir"
+const KEY_BASTION_KEY_PAIR = "bastionKeyPair"
+
+const OUTPUT_PRIVATE_NETWORK = "private_network"
+const OUTPUT_BASTION_PUBLIC_IP = "public_ip"
+
+const MYSQL_PORT = 3306
+const POSTGRES_PORT = 5432
+
+// The bastion host accepts SSH connections a while after it's created, once it has booted
+const BASTION_CONNECT_RETRIES = 20
+const BASTION_CONNECT_SLEEP_BETWEEN_RETRIES = 10 * time.Second
+
+// deployBastion launches a bastion host in the private network of the deployed example, so the tests can reach the
+// private IP of the instance through an SSH tunnel. The options and the SSH key pair of the bastion host are saved in
+// the example dir for the later stages.
+func deployBastion(t *testing.T, exampleDir string) {
+	exampleOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+	projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+	region := test_structure.LoadString(t, exampleDir, KEY_REGION)
+	zone := gcp.GetRandomZoneForRegion(t, projectId, region)
+	network := terraform.Output(t, exampleOptions, OUTPUT_PRIVATE_NETWORK)
+
+	keyPair := ssh.GenerateRSAKeyPair(t, BASTION_KEY_SIZE)
+	test_structure.SaveTestData(t, test_structure.FormatTestDataPath(exampleDir, KEY_BASTION_KEY_PAIR), keyPair)
+
+	bastionDir := test_structure.CopyTerraformFolderToTemp(t, "../", BASTION_FIXTURE_DIR)
+	test_structure.SaveString(t, exampleDir, KEY_BASTION_DIR, bastionDir)
+
+	name := fmt.Sprintf("bastion-%s", strings.ToLower(random.UniqueId()))
+	terraformOptions := createTerratestOptionsForBastion(projectId, region, zone, bastionDir, name, network, strings.TrimSpace(keyPair.PublicKey))
+	test_structure.SaveTerraformOptions(t, bastionDir, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+}
+
+// destroyBastion destroys the bastion host of the example, if it was deployed. It has to run before the example is
+// destroyed, as the firewall rule of the bastion host blocks the deletion of the network.
+func destroyBastion(t *testing.T, exampleDir string) {
+	if !test_structure.IsTestDataPresent(t, test_structure.FormatTestDataPath(exampleDir, KEY_BASTION_DIR)) {
+		logger.Logf(t, "No bastion host was deployed for %s", exampleDir)
+		return
+	}
+	bastionDir := test_structure.LoadString(t, exampleDir, KEY_BASTION_DIR)
+	terraformOptions := test_structure.LoadTerraformOptions(t, bastionDir)
+	terraform.Destroy(t, terraformOptions)
+}
+
+// openBastionTunnel opens an SSH tunnel through the bastion host of the example to the given port on the private IP of
+// its master instance, retrying until the bastion host accepts connections. Close the tunnel when done.
+func openBastionTunnel(t *testing.T, exampleDir string, port int) *sshtunnel.Tunnel {
+	exampleOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+	privateIp := terraform.Output(t, exampleOptions, OUTPUT_MASTER_PRIVATE_IP)
+
+	bastionDir := test_structure.LoadString(t, exampleDir, KEY_BASTION_DIR)
+	bastionOptions := test_structure.LoadTerraformOptions(t, bastionDir)
+	bastionIp := terraform.Output(t, bastionOptions, OUTPUT_BASTION_PUBLIC_IP)
+
+	keyPair := ssh.KeyPair{}
+	test_structure.LoadTestData(t, test_structure.FormatTestDataPath(exampleDir, KEY_BASTION_KEY_PAIR), &keyPair)
+
+	targetAddress := net.JoinHostPort(privateIp, fmt.Sprint(port))
+	var tunnel *sshtunnel.Tunnel
+	description := fmt.Sprintf("Open a tunnel through bastion host %s to %s", bastionIp, targetAddress)
+	_, err := retry.DoWithRetryE(t, description, BASTION_CONNECT_RETRIES, BASTION_CONNECT_SLEEP_BETWEEN_RETRIES, func() (string, error) {
+		opened, err := sshtunnel.Open(sshtunnel.Options{
+			BastionAddress: bastionIp,
+			User:           BASTION_SSH_USER,
+			PrivateKey:     keyPair.PrivateKey,
+			TargetAddress:  targetAddress,
+			Logf:           func(format string, args ...interface{}) { logger.Logf(t, format, args...) },
+		})
+		if err != nil {
+			return "", err
+		}
+		tunnel = opened
+		return tunnel.LocalAddress(), nil
+	})
+	require.NoError(t, err, "Failed to open a tunnel through the bastion host")
+
+	logger.Logf(t, "Forwarding %s through bastion host %s to %s", tunnel.LocalAddress(), bastionIp, targetAddress)
+	return tunnel
+}
@@ -0,0 +1,336 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The plan tests only run `terraform plan`, so they don't create any resources and can use fixed regions and zones.
+const PLAN_REGION = "us-central1"
+const PLAN_MASTER_ZONE = "us-central1-a"
+const PLAN_FAILOVER_REPLICA_ZONE = "us-central1-b"
+const PLAN_READ_REPLICA_ZONE = "us-central1-c"
+const PLAN_INSTANCE_NAME = "mysql-replicas-plan"
+const PLAN_OVERRIDE_MACHINE_TYPE = "db-n1-standard-1"
+const PLAN_OVERRIDE_DISK_SIZE = 20
+const PLAN_DR_REGION = "us-east1"
+const PLAN_DR_READ_REPLICA_ZONE = "us-east1-b"
+
+// The estimated monthly cost of the planned instances must stay below this, so the examples don't get expensive by
+// accident, e.g. by overriding the machine type of every replica
+const PLAN_MONTHLY_BUDGET = 150
+
+func TestMySqlReplicasPlanEncryption(t *testing.T) {
+	t.Parallel()
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	encryptionKeyName := fmt.Sprintf(PLAN_ONLY_CMEK_KEY_NAME_FORMAT, projectId, PLAN_REGION)
+	require.NoError(t, validateEncryptionKeyNameE(encryptionKeyName, PLAN_REGION))
+
+	terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, PLAN_REGION, exampleDir, NAME_PREFIX_REPLICAS, PLAN_MASTER_ZONE, PLAN_FAILOVER_REPLICA_ZONE, 2, PLAN_READ_REPLICA_ZONE)
+	terraformOptions.Vars["read_replica_zones"] = []string{PLAN_READ_REPLICA_ZONE, PLAN_READ_REPLICA_ZONE}
+	terraformOptions.Vars["encryption_key_name"] = encryptionKeyName
+
+	plan := initAndPlanWithStruct(t, terraformOptions)
+
+	// Master, failover replica and both read replicas
+	assert.Len(t, getPlannedSqlInstanceAddresses(plan), 4)
+	assert.NoError(t, validatePlannedEncryptionKeysE(plan, encryptionKeyName))
+
+	// Replicas in the master's region don't need a key of their own, and a key for another region doesn't leave them
+	// without one
+	drEncryptionKeyName := fmt.Sprintf(PLAN_ONLY_CMEK_KEY_NAME_FORMAT, projectId, PLAN_DR_REGION)
+	terraformOptions.Vars["replica_encryption_key_names"] = map[string]string{PLAN_DR_REGION: drEncryptionKeyName}
+	plan = initAndPlanWithStruct(t, terraformOptions)
+	assert.NoError(t, validatePlannedEncryptionKeysE(plan, encryptionKeyName))
+}
+
+func TestReplicasPlanQueryInsights(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                 string
+		exampleName          string
+		failoverReplicaZone  string
+		expectedNumInstances int
+	}{
+		// Master, failover replica and both read replicas
+		{"MySQL", EXAMPLE_NAME_REPLICAS, PLAN_FAILOVER_REPLICA_ZONE, 4},
+		// Postgres uses a regional master instead of a failover replica
+		{"Postgres", EXAMPLE_NAME_POSTGRES_REPLICAS, "", 3},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+			exampleDir := filepath.Join(_examplesDir, testCase.exampleName)
+
+			projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+			terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, PLAN_REGION, exampleDir, testCase.exampleName, PLAN_MASTER_ZONE, testCase.failoverReplicaZone, 2, PLAN_READ_REPLICA_ZONE)
+			terraformOptions.Vars["read_replica_zones"] = []string{PLAN_READ_REPLICA_ZONE, PLAN_READ_REPLICA_ZONE}
+			setQueryInsightsVars(terraformOptions)
+
+			plan := initAndPlanWithStruct(t, terraformOptions)
+
+			assert.Len(t, getPlannedSqlInstanceAddresses(plan), testCase.expectedNumInstances)
+			assert.NoError(t, validatePlannedInsightsConfigsE(plan, QUERY_INSIGHTS_CONFIG))
+		})
+	}
+}
+
+func TestMySqlReplicasPlanCrossRegion(t *testing.T) {
+	t.Parallel()
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, PLAN_REGION, exampleDir, NAME_PREFIX_CROSS_REGION_REPLICAS, PLAN_MASTER_ZONE, PLAN_FAILOVER_REPLICA_ZONE, 2, PLAN_READ_REPLICA_ZONE)
+	terraformOptions.Vars["read_replica_zones"] = []string{PLAN_READ_REPLICA_ZONE, PLAN_DR_READ_REPLICA_ZONE}
+	terraformOptions.Vars["read_replica_regions"] = []string{PLAN_REGION, PLAN_DR_REGION}
+
+	plan := initAndPlanWithStruct(t, terraformOptions)
+
+	expectedRegions := map[string]string{
+		"module.mysql.google_sql_database_instance.master":                 PLAN_REGION,
+		"module.mysql.google_sql_database_instance.failover_replica[0]":    PLAN_REGION,
+		`module.mysql.google_sql_database_instance.read_replica["read-0"]`: PLAN_REGION,
+		`module.mysql.google_sql_database_instance.read_replica["read-1"]`: PLAN_DR_REGION,
+	}
+	for address, expectedRegion := range expectedRegions {
+		region, err := getPlannedAttributeE(plan, address, "region")
+		require.NoError(t, err)
+		assert.Equal(t, expectedRegion, region, "Unexpected region of %s", address)
+	}
+
+	// An encrypted master needs a key for every other replica region, as keys are regional
+	terraformOptions.Vars["encryption_key_name"] = fmt.Sprintf(PLAN_ONLY_CMEK_KEY_NAME_FORMAT, projectId, PLAN_REGION)
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	require.Error(t, err, "Expected the plan to fail without a key for %s", PLAN_DR_REGION)
+	// Make sure the plan failed on the missing key, not e.g. on missing credentials
+	assert.Contains(t, err.Error(), "Invalid index")
+	assert.Contains(t, err.Error(), "var.replica_encryption_key_names[replica.region]")
+	assert.Contains(t, err.Error(), fmt.Sprintf(`replica.region is "%s"`, PLAN_DR_REGION))
+
+	drEncryptionKeyName := fmt.Sprintf(PLAN_ONLY_CMEK_KEY_NAME_FORMAT, projectId, PLAN_DR_REGION)
+	terraformOptions.Vars["replica_encryption_key_names"] = map[string]string{PLAN_DR_REGION: drEncryptionKeyName}
+	plan = initAndPlanWithStruct(t, terraformOptions)
+
+	drEncryptionKey, err := getPlannedAttributeE(plan, `module.mysql.google_sql_database_instance.read_replica["read-1"]`, "encryption_key_name")
+	require.NoError(t, err)
+	assert.Equal(t, drEncryptionKeyName, drEncryptionKey)
+}
+
+func TestMySqlReplicasPlanReplicaOverrides(t *testing.T) {
+	t.Parallel()
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, PLAN_REGION, exampleDir, NAME_PREFIX_REPLICAS, PLAN_MASTER_ZONE, PLAN_FAILOVER_REPLICA_ZONE, 2, PLAN_READ_REPLICA_ZONE)
+	terraformOptions.Vars["read_replica_zones"] = []string{PLAN_READ_REPLICA_ZONE, PLAN_READ_REPLICA_ZONE}
+
+	// Only the second read replica is overridden, the first one gets an empty config and the failover replica only a
+	// different machine type
+	terraformOptions.Vars["read_replica_configs"] = []map[string]interface{}{
+		{},
+		{
+			"machine_type": PLAN_OVERRIDE_MACHINE_TYPE,
+			"disk_size":    PLAN_OVERRIDE_DISK_SIZE,
+			"disk_type":    "PD_HDD",
+			"zone":         PLAN_MASTER_ZONE,
+			"database_flags": []map[string]string{
+				{"name": "auto_increment_increment", "value": "3"},
+				{"name": "long_query_time", "value": "10"},
+			},
+			"custom_labels": map[string]string{"workload": "analytics"},
+		},
+	}
+	terraformOptions.Vars["failover_replica_config"] = map[string]interface{}{"machine_type": PLAN_OVERRIDE_MACHINE_TYPE}
+
+	plan := initAndPlanWithStruct(t, terraformOptions)
+
+	master := "module.mysql.google_sql_database_instance.master"
+	failoverReplica := "module.mysql.google_sql_database_instance.failover_replica[0]"
+	defaultReplica := `module.mysql.google_sql_database_instance.read_replica["read-0"]`
+	overriddenReplica := `module.mysql.google_sql_database_instance.read_replica["read-1"]`
+
+	masterSettings := getPlannedSettings(t, plan, master)
+	defaultReplicaSettings := getPlannedSettings(t, plan, defaultReplica)
+	overriddenReplicaSettings := getPlannedSettings(t, plan, overriddenReplica)
+
+	// Defaults flow through to the replica without overrides
+	for _, setting := range []string{"tier", "disk_size", "disk_type", "user_labels"} {
+		assert.Equal(t, masterSettings[setting], defaultReplicaSettings[setting], "Unexpected %s of %s", setting, defaultReplica)
+	}
+	assert.Equal(t, getPlannedDatabaseFlags(t, plan, master), getPlannedDatabaseFlags(t, plan, defaultReplica))
+	assert.Equal(t, PLAN_READ_REPLICA_ZONE, getPlannedZone(t, defaultReplicaSettings))
+
+	// The failover replica only gets a different machine type
+	failoverReplicaSettings := getPlannedSettings(t, plan, failoverReplica)
+	assert.Equal(t, PLAN_OVERRIDE_MACHINE_TYPE, failoverReplicaSettings["tier"])
+	assert.Equal(t, masterSettings["disk_size"], failoverReplicaSettings["disk_size"])
+	assert.Equal(t, getPlannedDatabaseFlags(t, plan, master), getPlannedDatabaseFlags(t, plan, failoverReplica))
+
+	// The overridden replica
+	assert.Equal(t, PLAN_OVERRIDE_MACHINE_TYPE, overriddenReplicaSettings["tier"])
+	assert.Equal(t, float64(PLAN_OVERRIDE_DISK_SIZE), overriddenReplicaSettings["disk_size"])
+	assert.Equal(t, "PD_HDD", overriddenReplicaSettings["disk_type"])
+	assert.Equal(t, PLAN_MASTER_ZONE, getPlannedZone(t, overriddenReplicaSettings))
+	expectedLabels := map[string]interface{}{"test-id": "mysql-replicas-example", "workload": "analytics"}
+	for key, value := range getRunLabels(t, terraformOptions) {
+		expectedLabels[key] = value
+	}
+	assert.Equal(t, expectedLabels, overriddenReplicaSettings["user_labels"])
+	assert.Equal(t, map[string]string{"auto_increment_increment": "3", "auto_increment_offset": "7", "long_query_time": "10"}, getPlannedDatabaseFlags(t, plan, overriddenReplica))
+
+	// The master keeps its own settings
+	assert.Equal(t, "db-f1-micro", masterSettings["tier"])
+	assert.Equal(t, map[string]string{"auto_increment_increment": "7", "auto_increment_offset": "7"}, getPlannedDatabaseFlags(t, plan, master))
+
+	// The overridden machine types cost more than the default one
+	estimate := validatePlanWithinBudget(t, plan, PLAN_MONTHLY_BUDGET)
+	costs := map[string]float64{}
+	for _, instance := range estimate.Instances {
+		costs[instance.Address] = instance.TotalMonthly
+	}
+	assert.Len(t, costs, 4)
+	assert.Greater(t, costs[failoverReplica], costs[master])
+	assert.Greater(t, costs[overriddenReplica], costs[defaultReplica])
+}
+
+func getPlannedSettings(t *testing.T, plan *terraform.PlanStruct, address string) map[string]interface{} {
+	settings, err := getPlannedSettingsE(plan, address)
+	require.NoError(t, err)
+	return settings
+}
+
+func getPlannedDatabaseFlags(t *testing.T, plan *terraform.PlanStruct, address string) map[string]string {
+	flags, err := getPlannedDatabaseFlagsE(plan, address)
+	require.NoError(t, err)
+	return flags
+}
+
+func getPlannedZone(t *testing.T, settings map[string]interface{}) interface{} {
+	locationPreference, err := getSingleNestedBlockE(settings["location_preference"])
+	require.NoError(t, err)
+	return locationPreference["zone"]
+}
+
+func TestMySqlReplicasPlanStableIdentities(t *testing.T) {
+	t.Parallel()
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, PLAN_REGION, exampleDir, NAME_PREFIX_REPLICAS, PLAN_MASTER_ZONE, PLAN_FAILOVER_REPLICA_ZONE, 1, PLAN_READ_REPLICA_ZONE)
+	terraformOptions.Vars["name_override"] = PLAN_INSTANCE_NAME
+	terraformOptions.Vars["read_replicas"] = map[string]interface{}{
+		"analytics": map[string]interface{}{"zone": PLAN_MASTER_ZONE},
+		"dr":        map[string]interface{}{"region": PLAN_DR_REGION},
+	}
+
+	plan := initAndPlanWithStruct(t, terraformOptions)
+
+	// The replica configured with num_read_replicas keeps the name it had when replicas were created with count
+	expectedNames := map[string]string{
+		`module.mysql.google_sql_database_instance.read_replica["read-0"]`:    PLAN_INSTANCE_NAME + "-read-0",
+		`module.mysql.google_sql_database_instance.read_replica["analytics"]`: PLAN_INSTANCE_NAME + "-analytics",
+		`module.mysql.google_sql_database_instance.read_replica["dr"]`:        PLAN_INSTANCE_NAME + "-dr",
+	}
+	for address, expectedName := range expectedNames {
+		name, err := getPlannedAttributeE(plan, address, "name")
+		require.NoError(t, err)
+		assert.Equal(t, expectedName, name)
+	}
+
+	region, err := getPlannedAttributeE(plan, `module.mysql.google_sql_database_instance.read_replica["dr"]`, "region")
+	require.NoError(t, err)
+	assert.Equal(t, PLAN_DR_REGION, region)
+
+	// Master, failover replica and the three read replicas
+	assert.Len(t, getPlannedSqlInstanceAddresses(plan), 5)
+}
+
+func TestPublicIpPlanIamUsers(t *testing.T) {
+	t.Parallel()
+
+	const userEmail = "jane@example.com"
+	const serviceAccountEmail = "ci@my-project.iam.gserviceaccount.com"
+
+	testCases := []struct {
+		name               string
+		exampleName        string
+		namePrefix         string
+		moduleName         string
+		expectedNames      map[string]string
+		expectedLoginNames map[string]string
+	}{
+		// MySQL users are created with the full email, Cloud SQL shortens their login name itself
+		{
+			"MySQL",
+			EXAMPLE_NAME_PUBLIC,
+			NAME_PREFIX_PUBLIC,
+			"mysql",
+			map[string]string{userEmail: userEmail, serviceAccountEmail: serviceAccountEmail},
+			map[string]string{userEmail: "jane", serviceAccountEmail: "ci"},
+		},
+		{
+			"Postgres",
+			EXAMPLE_NAME_POSTGRES_PUBLIC,
+			NAME_PREFIX_POSTGRES_PUBLIC,
+			"postgres",
+			map[string]string{userEmail: userEmail, serviceAccountEmail: "ci@my-project.iam"},
+			map[string]string{userEmail: userEmail, serviceAccountEmail: "ci@my-project.iam"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+			exampleDir := filepath.Join(_examplesDir, testCase.exampleName)
+
+			projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+			terraformOptions := createTerratestOptionsForCloudSql(t, projectId, PLAN_REGION, exampleDir, testCase.namePrefix)
+			terraformOptions.Vars["iam_users"] = []map[string]string{{"email": userEmail}, {"email": serviceAccountEmail}}
+
+			plan := initAndPlanWithStruct(t, terraformOptions)
+
+			for email, expectedName := range testCase.expectedNames {
+				address := fmt.Sprintf(`module.%s.google_sql_user.iam["%s"]`, testCase.moduleName, email)
+				name, err := getPlannedAttributeE(plan, address, "name")
+				require.NoError(t, err)
+				assert.Equal(t, expectedName, name, address)
+				assert.Equal(t, testCase.expectedLoginNames[email], getIamDatabaseUserName(testCase.moduleName == "postgres", email))
+			}
+
+			require.Contains(t, plan.RawPlan.PlannedValues.Outputs, OUTPUT_IAM_USER_NAMES)
+			iamUserNames := plan.RawPlan.PlannedValues.Outputs[OUTPUT_IAM_USER_NAMES].Value
+			assert.Equal(t, map[string]interface{}{userEmail: testCase.expectedLoginNames[userEmail], serviceAccountEmail: testCase.expectedLoginNames[serviceAccountEmail]}, iamUserNames)
+		})
+	}
+}
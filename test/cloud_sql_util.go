@@ -0,0 +1,202 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+const ACTIVATION_POLICY_ALWAYS = "ALWAYS"
+const ACTIVATION_POLICY_NEVER = "NEVER"
+
+const INSTANCE_STATE_RUNNABLE = "RUNNABLE"
+
+// The type of the public IP address of an instance in the Admin API
+const IP_ADDRESS_TYPE_PRIMARY = "PRIMARY"
+
+// The number of times initAndApplyWithInstanceName generates a new instance name when Cloud SQL rejects the name
+const INSTANCE_NAME_TAKEN_MAX_RETRIES = 3
+
+// Cloud SQL rejects the name of an existing or recently deleted instance with this error
+var instanceNameTakenErrorRegexp = regexp.MustCompile(`Error 409: .*instanceAlreadyExists`)
+
+// newSqlAdminAPI creates a Cloud SQL Admin API client authenticated with the default Google credentials.
+func newSqlAdminAPI(t *testing.T) cloudsql.AdminAPI {
+	api, err := cloudsql.NewAdminAPI(context.Background())
+	require.NoError(t, err, "Failed to create Cloud SQL Admin API client")
+	return api
+}
+
+// setInstanceNameOverride sets the name_override of the given example options to a name generated from its
+// name_prefix, which is valid and not taken by an existing or recently deleted instance, also for the failover replica
+// and the read replicas configured in the options. Call it before saving the options, so later stages keep the name.
+func setInstanceNameOverride(t *testing.T, terraformOptions *terraform.Options) {
+	projectId := terraformOptions.Vars["project"].(string)
+	namePrefix := terraformOptions.Vars["name_prefix"].(string)
+
+	numReadReplicas, _ := terraformOptions.Vars["num_read_replicas"].(int)
+	readReplicaKeys := []string{}
+	if readReplicas, exists := terraformOptions.Vars["read_replicas"].(map[string]interface{}); exists {
+		for key := range readReplicas {
+			readReplicaKeys = append(readReplicaKeys, key)
+		}
+	}
+
+	name, err := cloudsql.GenerateInstanceName(newSqlAdminAPI(t), cloudsql.InstanceNameOptions{
+		Project:     projectId,
+		Prefix:      namePrefix,
+		ReplicaKeys: cloudsql.GetReplicaKeys(numReadReplicas, readReplicaKeys...),
+	})
+	require.NoError(t, err, "Failed to generate an instance name for %s", namePrefix)
+
+	logger.Logf(t, "Using instance name %s", name)
+	terraformOptions.Vars["name_override"] = name
+}
+
+// initAndApplyWithInstanceName runs `terraform init` and `terraform apply` with the given example options. If Cloud SQL
+// rejects the name set by setInstanceNameOverride as taken, which its check can miss when the Admin API doesn't return
+// the operations of a deleted instance, it generates a new name, saves the options in the given example dir and
+// applies again.
+func initAndApplyWithInstanceName(t *testing.T, exampleDir string, terraformOptions *terraform.Options) {
+	for retries := 0; ; retries++ {
+		output, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err == nil {
+			return
+		}
+		if retries >= INSTANCE_NAME_TAKEN_MAX_RETRIES || !isInstanceNameTakenOutput(output) {
+			require.NoError(t, err, "Failed to apply %s", terraformOptions.TerraformDir)
+		}
+
+		logger.Logf(t, "Instance name %s is taken, generating a new one", terraformOptions.Vars["name_override"])
+		setInstanceNameOverride(t, terraformOptions)
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+	}
+}
+
+// isInstanceNameTakenOutput returns whether the given Terraform output contains the error Cloud SQL returns for the
+// name of an existing or recently deleted instance.
+func isInstanceNameTakenOutput(output string) bool {
+	return instanceNameTakenErrorRegexp.MatchString(output)
+}
+
+// getSqlInstance fetches the given instance from the Admin API, failing the test if it doesn't exist.
+func getSqlInstance(t *testing.T, api cloudsql.AdminAPI, projectId string, instanceName string) *sqladmin.DatabaseInstance {
+	instance, err := api.GetInstance(projectId, instanceName)
+	require.NoError(t, err, "Failed to get Cloud SQL instance %s", instanceName)
+	return instance
+}
+
+// getInstancePublicIp returns the public IP address the Admin API reports for the given instance, failing the test if
+// it has none.
+func getInstancePublicIp(t *testing.T, api cloudsql.AdminAPI, projectId string, instanceName string) string {
+	publicIp, err := getInstancePublicIpE(api, projectId, instanceName)
+	require.NoError(t, err, "Failed to get the public IP of instance %s", instanceName)
+	return publicIp
+}
+
+// getInstancePublicIpE returns the public IP address the Admin API reports for the given instance, or an error if it
+// has none.
+func getInstancePublicIpE(api cloudsql.AdminAPI, projectId string, instanceName string) (string, error) {
+	instance, err := api.GetInstance(projectId, instanceName)
+	if err != nil {
+		return "", err
+	}
+	for _, ipAddress := range instance.IpAddresses {
+		if ipAddress.Type == IP_ADDRESS_TYPE_PRIMARY {
+			return ipAddress.IpAddress, nil
+		}
+	}
+	return "", fmt.Errorf("instance %s has no public IP address", instanceName)
+}
+
+// validateInstanceInsightsConfig fails the test if the Admin API doesn't report the expected Query Insights configuration
+// for the given instance.
+func validateInstanceInsightsConfig(t *testing.T, api cloudsql.AdminAPI, projectId string, instanceName string, expected cloudsql.InsightsConfig) {
+	err := validateInstanceInsightsConfigE(api, projectId, instanceName, expected)
+	require.NoError(t, err, "Instance %s doesn't have the expected Query Insights configuration", instanceName)
+}
+
+// validateInstanceInsightsConfigE returns an error if the Admin API doesn't report the expected Query Insights
+// configuration for the given instance.
+func validateInstanceInsightsConfigE(api cloudsql.AdminAPI, projectId string, instanceName string, expected cloudsql.InsightsConfig) error {
+	actual, err := api.GetInsightsConfig(projectId, instanceName)
+	if err != nil {
+		return err
+	}
+	if *actual != expected {
+		return fmt.Errorf("instance %s has Query Insights configuration %+v, expected %+v", instanceName, *actual, expected)
+	}
+	return nil
+}
+
+// parseProxyConnectionE splits a Cloud SQL Proxy connection name of the form project:region:instance into its parts.
+func parseProxyConnectionE(connection string) (string, string, string, error) {
+	parts := strings.Split(connection, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("%q is not a connection name of the form project:region:instance", connection)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// validateProxyConnectionRegion fails the test if the region in the given connection name doesn't match the region the
+// Admin API reports for the instance.
+func validateProxyConnectionRegion(t *testing.T, api cloudsql.AdminAPI, connection string) {
+	err := validateProxyConnectionRegionE(api, connection)
+	require.NoError(t, err, "Invalid proxy connection %s", connection)
+}
+
+// validateProxyConnectionRegionE returns an error if the region in the given connection name doesn't match the region
+// the Admin API reports for the instance.
+func validateProxyConnectionRegionE(api cloudsql.AdminAPI, connection string) error {
+	projectId, region, instanceName, err := parseProxyConnectionE(connection)
+	if err != nil {
+		return err
+	}
+
+	instance, err := api.GetInstance(projectId, instanceName)
+	if err != nil {
+		return err
+	}
+	if instance.Region != region {
+		return fmt.Errorf("connection %s has region %s, but instance %s lives in %s", connection, region, instanceName, instance.Region)
+	}
+	return nil
+}
+
+// validateInstanceActivationPolicy fails the test if the Admin API doesn't report the given activation policy for the
+// instance, or if an instance that should be running is not runnable.
+func validateInstanceActivationPolicy(t *testing.T, api cloudsql.AdminAPI, projectId string, instanceName string, expectedPolicy string) {
+	err := validateInstanceActivationPolicyE(api, projectId, instanceName, expectedPolicy)
+	require.NoError(t, err, "Instance %s doesn't have the expected activation policy", instanceName)
+}
+
+// validateInstanceActivationPolicyE returns an error if the Admin API doesn't report the given activation policy for the
+// instance, or if an instance that should be running is not runnable. Stopped instances are only recognizable by their
+// activation policy, as the API keeps reporting them as runnable.
+func validateInstanceActivationPolicyE(api cloudsql.AdminAPI, projectId string, instanceName string, expectedPolicy string) error {
+	instance, err := api.GetInstance(projectId, instanceName)
+	if err != nil {
+		return err
+	}
+
+	if instance.Settings == nil || instance.Settings.ActivationPolicy != expectedPolicy {
+		actualPolicy := ""
+		if instance.Settings != nil {
+			actualPolicy = instance.Settings.ActivationPolicy
+		}
+		return fmt.Errorf("instance %s has activation policy %q, expected %q", instanceName, actualPolicy, expectedPolicy)
+	}
+	if expectedPolicy == ACTIVATION_POLICY_ALWAYS && instance.State != INSTANCE_STATE_RUNNABLE {
+		return fmt.Errorf("instance %s is %s, expected %s", instanceName, instance.State, INSTANCE_STATE_RUNNABLE)
+	}
+	return nil
+}
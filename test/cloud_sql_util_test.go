@@ -0,0 +1,103 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/stretchr/testify/assert"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+func TestValidateInstanceInsightsConfigE(t *testing.T) {
+	t.Parallel()
+
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "insights"},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "plain"},
+	)
+	api.PutInsightsConfig("my-project", "insights", QUERY_INSIGHTS_CONFIG)
+
+	assert.NoError(t, validateInstanceInsightsConfigE(api, "my-project", "insights", QUERY_INSIGHTS_CONFIG))
+	assert.Error(t, validateInstanceInsightsConfigE(api, "my-project", "plain", QUERY_INSIGHTS_CONFIG))
+	assert.NoError(t, validateInstanceInsightsConfigE(api, "my-project", "plain", cloudsql.InsightsConfig{}))
+	assert.Error(t, validateInstanceInsightsConfigE(api, "my-project", "missing", cloudsql.InsightsConfig{}))
+}
+
+func TestParseProxyConnectionE(t *testing.T) {
+	t.Parallel()
+
+	projectId, region, instanceName, err := parseProxyConnectionE("my-project:us-east1:mysql-replicas-read-0")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-project", projectId)
+	assert.Equal(t, "us-east1", region)
+	assert.Equal(t, "mysql-replicas-read-0", instanceName)
+
+	for _, connection := range []string{"", "my-project:mysql-replicas", "my-project::mysql-replicas", "a:b:c:d"} {
+		_, _, _, err := parseProxyConnectionE(connection)
+		assert.Error(t, err, "Expected %q to be rejected", connection)
+	}
+}
+
+func TestValidateProxyConnectionRegionE(t *testing.T) {
+	t.Parallel()
+
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "replicas-read-0", Region: "us-central1"},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "replicas-read-1", Region: "us-east1"},
+	)
+
+	assert.NoError(t, validateProxyConnectionRegionE(api, "my-project:us-central1:replicas-read-0"))
+	assert.NoError(t, validateProxyConnectionRegionE(api, "my-project:us-east1:replicas-read-1"))
+	assert.Error(t, validateProxyConnectionRegionE(api, "my-project:us-central1:replicas-read-1"))
+	assert.Error(t, validateProxyConnectionRegionE(api, "my-project:us-central1:replicas-read-2"))
+	assert.Error(t, validateProxyConnectionRegionE(api, "replicas-read-0"))
+}
+
+func TestGetInstancePublicIpE(t *testing.T) {
+	t.Parallel()
+
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "public", IpAddresses: []*sqladmin.IpMapping{
+			{Type: "PRIVATE", IpAddress: "10.0.0.3"},
+			{Type: IP_ADDRESS_TYPE_PRIMARY, IpAddress: "35.1.2.3"},
+		}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "private", IpAddresses: []*sqladmin.IpMapping{
+			{Type: "PRIVATE", IpAddress: "10.0.0.4"},
+		}},
+	)
+
+	publicIp, err := getInstancePublicIpE(api, "my-project", "public")
+	assert.NoError(t, err)
+	assert.Equal(t, "35.1.2.3", publicIp)
+
+	_, err = getInstancePublicIpE(api, "my-project", "private")
+	assert.Error(t, err)
+	_, err = getInstancePublicIpE(api, "my-project", "missing")
+	assert.Error(t, err)
+}
+
+func TestValidateInstanceActivationPolicyE(t *testing.T) {
+	t.Parallel()
+
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "running", State: INSTANCE_STATE_RUNNABLE, Settings: &sqladmin.Settings{ActivationPolicy: ACTIVATION_POLICY_ALWAYS}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "stopped", State: INSTANCE_STATE_RUNNABLE, Settings: &sqladmin.Settings{ActivationPolicy: ACTIVATION_POLICY_NEVER}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "starting", State: "PENDING_CREATE", Settings: &sqladmin.Settings{ActivationPolicy: ACTIVATION_POLICY_ALWAYS}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "unknown", State: INSTANCE_STATE_RUNNABLE},
+	)
+
+	assert.NoError(t, validateInstanceActivationPolicyE(api, "my-project", "running", ACTIVATION_POLICY_ALWAYS))
+	assert.NoError(t, validateInstanceActivationPolicyE(api, "my-project", "stopped", ACTIVATION_POLICY_NEVER))
+	assert.Error(t, validateInstanceActivationPolicyE(api, "my-project", "running", ACTIVATION_POLICY_NEVER))
+	assert.Error(t, validateInstanceActivationPolicyE(api, "my-project", "stopped", ACTIVATION_POLICY_ALWAYS))
+	assert.Error(t, validateInstanceActivationPolicyE(api, "my-project", "starting", ACTIVATION_POLICY_ALWAYS))
+	assert.Error(t, validateInstanceActivationPolicyE(api, "my-project", "unknown", ACTIVATION_POLICY_ALWAYS))
+}
+
+func TestIsInstanceNameTakenOutput(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isInstanceNameTakenOutput("google_sql_database_instance.master: Creating...\n\nError: Error, failed to create instance mysql-public-abc123: googleapi: Error 409: The Cloud SQL instance already exists. When you delete an instance, you can't reuse the name of the deleted instance until one week from the deletion date., instanceAlreadyExists\n\n  on main.tf line 28"))
+	assert.False(t, isInstanceNameTakenOutput("Error: Error, failed to create instance mysql-public-abc123: googleapi: Error 409: Operation failed because another operation was already in progress., operationInProgress"))
+	assert.False(t, isInstanceNameTakenOutput("Apply complete! Resources: 3 added, 0 changed, 0 destroyed."))
+}
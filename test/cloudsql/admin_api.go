@@ -0,0 +1,176 @@
+// Package cloudsql contains a thin wrapper around the Cloud SQL Admin API, plus an in-memory fake of it, so that the
+// tests and tools in this repo can inspect the instances deployed by the cloud-sql module without talking to GCP in
+// unit tests.
+package cloudsql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// AdminAPI is the subset of the Cloud SQL Admin API used by the tests and tools. It is implemented by the client
+// returned from NewAdminAPI and by FakeAdminAPI.
+type AdminAPI interface {
+	// GetInstance returns the instance with the given name, or an error for which IsNotFound returns true.
+	GetInstance(project string, instance string) (*sqladmin.DatabaseInstance, error)
+
+	// GetInsightsConfig returns the Query Insights configuration of the instance with the given name.
+	GetInsightsConfig(project string, instance string) (*InsightsConfig, error)
+
+	// PromoteReplica starts promoting the read replica with the given name to a standalone instance. Use
+	// WaitForOperation to wait for the returned operation to finish.
+	PromoteReplica(project string, instance string) (*sqladmin.Operation, error)
+
+	// GetOperation returns the operation with the given name, or an error for which IsNotFound returns true.
+	GetOperation(project string, operation string) (*sqladmin.Operation, error)
+
+	// ListDatabases returns the databases of the instance with the given name, including the system databases.
+	ListDatabases(project string, instance string) ([]*sqladmin.Database, error)
+
+	// ListUsers returns the users of the instance with the given name.
+	ListUsers(project string, instance string) ([]*User, error)
+
+	// ListOperations returns the operations on the instance with the given name, most recent first. The operations of
+	// a deleted instance are kept for a while after the deletion.
+	ListOperations(project string, instance string) ([]*sqladmin.Operation, error)
+}
+
+// InsightsConfig is the Query Insights configuration of an instance. The version of the sqladmin client used here
+// predates Query Insights, so this is decoded from the raw instance resource instead.
+type InsightsConfig struct {
+	QueryInsightsEnabled  bool  `json:"queryInsightsEnabled"`
+	QueryStringLength     int64 `json:"queryStringLength"`
+	RecordApplicationTags bool  `json:"recordApplicationTags"`
+	RecordClientAddress   bool  `json:"recordClientAddress"`
+}
+
+// User is a database user of an instance. The version of the sqladmin client used here predates IAM database
+// authentication, so this is decoded from the raw user resource to get the type of the user.
+type User struct {
+	Name string `json:"name"`
+	Host string `json:"host,omitempty"`
+	// Type is empty for built-in users, or CLOUD_IAM_USER or CLOUD_IAM_SERVICE_ACCOUNT for IAM users
+	Type string `json:"type,omitempty"`
+}
+
+type adminAPI struct {
+	ctx     context.Context
+	client  *http.Client
+	service *sqladmin.Service
+}
+
+// NewAdminAPI creates a client for the Cloud SQL Admin API. By default, it authenticates with the Google application
+// default credentials, which can be overridden with the given options.
+func NewAdminAPI(ctx context.Context, opts ...option.ClientOption) (AdminAPI, error) {
+	opts = append([]option.ClientOption{option.WithScopes(sqladmin.CloudPlatformScope)}, opts...)
+	client, endpoint, err := htransport.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Share the authenticated client, so the raw requests below use the same credentials as the generated client
+	service, err := sqladmin.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+	if endpoint != "" {
+		service.BasePath = endpoint
+	}
+
+	return &adminAPI{ctx: ctx, client: client, service: service}, nil
+}
+
+func (api *adminAPI) GetInstance(project string, instance string) (*sqladmin.DatabaseInstance, error) {
+	return api.service.Instances.Get(project, instance).Context(api.ctx).Do()
+}
+
+func (api *adminAPI) PromoteReplica(project string, instance string) (*sqladmin.Operation, error) {
+	return api.service.Instances.PromoteReplica(project, instance).Context(api.ctx).Do()
+}
+
+func (api *adminAPI) GetOperation(project string, operation string) (*sqladmin.Operation, error) {
+	return api.service.Operations.Get(project, operation).Context(api.ctx).Do()
+}
+
+func (api *adminAPI) GetInsightsConfig(project string, instance string) (*InsightsConfig, error) {
+	var raw struct {
+		Settings struct {
+			InsightsConfig *InsightsConfig `json:"insightsConfig"`
+		} `json:"settings"`
+	}
+	if err := api.getRaw(fmt.Sprintf("sql/v1beta4/projects/%s/instances/%s", project, instance), &raw); err != nil {
+		return nil, err
+	}
+
+	// The API omits the configuration entirely if Query Insights was never configured
+	if raw.Settings.InsightsConfig == nil {
+		return &InsightsConfig{}, nil
+	}
+	return raw.Settings.InsightsConfig, nil
+}
+
+func (api *adminAPI) ListDatabases(project string, instance string) ([]*sqladmin.Database, error) {
+	response, err := api.service.Databases.List(project, instance).Context(api.ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return response.Items, nil
+}
+
+func (api *adminAPI) ListUsers(project string, instance string) ([]*User, error) {
+	var raw struct {
+		Items []*User `json:"items"`
+	}
+	if err := api.getRaw(fmt.Sprintf("sql/v1beta4/projects/%s/instances/%s/users", project, instance), &raw); err != nil {
+		return nil, err
+	}
+	if raw.Items == nil {
+		return []*User{}, nil
+	}
+	return raw.Items, nil
+}
+
+func (api *adminAPI) ListOperations(project string, instance string) ([]*sqladmin.Operation, error) {
+	response, err := api.service.Operations.List(project).Instance(instance).Context(api.ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return response.Items, nil
+}
+
+// getRaw fetches the resource at the given path relative to the API base path and decodes it into the given value.
+func (api *adminAPI) getRaw(path string, value interface{}) error {
+	request, err := http.NewRequestWithContext(api.ctx, http.MethodGet, api.service.BasePath+path, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := api.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if err := googleapi.CheckResponse(response); err != nil {
+		return err
+	}
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// IsNotFound returns true if the given error was returned because an instance or operation doesn't exist.
+func IsNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func notFoundError(format string, args ...interface{}) error {
+	return &googleapi.Error{Code: http.StatusNotFound, Message: fmt.Sprintf(format, args...)}
+}
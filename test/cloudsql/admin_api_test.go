@@ -0,0 +1,111 @@
+package cloudsql
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func newTestAdminAPI(t *testing.T, handler http.HandlerFunc) AdminAPI {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	api, err := NewAdminAPI(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	return api
+}
+
+func TestGetInsightsConfig(t *testing.T) {
+	t.Parallel()
+
+	api := newTestAdminAPI(t, func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/sql/v1beta4/projects/my-project/instances/insights":
+			writer.Write([]byte(`{"name": "insights", "settings": {"insightsConfig": {"queryInsightsEnabled": true, "queryStringLength": 2048, "recordApplicationTags": true}}}`))
+		case "/sql/v1beta4/projects/my-project/instances/plain":
+			writer.Write([]byte(`{"name": "plain", "settings": {}}`))
+		default:
+			http.NotFound(writer, request)
+		}
+	})
+
+	config, err := api.GetInsightsConfig("my-project", "insights")
+	require.NoError(t, err)
+	assert.Equal(t, InsightsConfig{QueryInsightsEnabled: true, QueryStringLength: 2048, RecordApplicationTags: true}, *config)
+
+	config, err = api.GetInsightsConfig("my-project", "plain")
+	require.NoError(t, err)
+	assert.Equal(t, InsightsConfig{}, *config)
+
+	_, err = api.GetInsightsConfig("my-project", "missing")
+	assert.True(t, IsNotFound(err))
+
+	instance, err := api.GetInstance("my-project", "insights")
+	require.NoError(t, err)
+	assert.Equal(t, "insights", instance.Name)
+}
+
+func TestListDatabasesAndUsers(t *testing.T) {
+	t.Parallel()
+
+	api := newTestAdminAPI(t, func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/sql/v1beta4/projects/my-project/instances/mysql/databases":
+			writer.Write([]byte(`{"items": [{"name": "app", "charset": "utf8mb4", "collation": "utf8mb4_general_ci"}, {"name": "mysql"}]}`))
+		case "/sql/v1beta4/projects/my-project/instances/mysql/users":
+			writer.Write([]byte(`{"items": [{"name": "admin", "host": "%"}, {"name": "jane", "host": "%", "type": "CLOUD_IAM_USER"}]}`))
+		case "/sql/v1beta4/projects/my-project/instances/empty/users":
+			writer.Write([]byte(`{}`))
+		default:
+			http.NotFound(writer, request)
+		}
+	})
+
+	databases, err := api.ListDatabases("my-project", "mysql")
+	require.NoError(t, err)
+	require.Len(t, databases, 2)
+	assert.Equal(t, "app", databases[0].Name)
+	assert.Equal(t, "utf8mb4_general_ci", databases[0].Collation)
+
+	users, err := api.ListUsers("my-project", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, []*User{{Name: "admin", Host: "%"}, {Name: "jane", Host: "%", Type: "CLOUD_IAM_USER"}}, users)
+
+	users, err = api.ListUsers("my-project", "empty")
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	_, err = api.ListUsers("my-project", "missing")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestListOperations(t *testing.T) {
+	t.Parallel()
+
+	api := newTestAdminAPI(t, func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/sql/v1beta4/projects/my-project/operations" {
+			http.NotFound(writer, request)
+			return
+		}
+		switch request.URL.Query().Get("instance") {
+		case "deleted":
+			writer.Write([]byte(`{"items": [{"name": "operation-2", "operationType": "DELETE", "targetId": "deleted", "insertTime": "2026-10-14T12:00:00Z"}, {"name": "operation-1", "operationType": "CREATE", "targetId": "deleted"}]}`))
+		default:
+			http.NotFound(writer, request)
+		}
+	})
+
+	operations, err := api.ListOperations("my-project", "deleted")
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+	assert.Equal(t, "DELETE", operations[0].OperationType)
+	assert.Equal(t, "2026-10-14T12:00:00Z", operations[0].InsertTime)
+
+	_, err = api.ListOperations("my-project", "missing")
+	assert.True(t, IsNotFound(err))
+}
@@ -0,0 +1,305 @@
+package cloudsql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+const SQL_INSTANCE_RESOURCE_TYPE = "google_sql_database_instance"
+
+// The fields compared by DetectDrift. Database flags and labels are compared one by one, e.g. as
+// database_flags[long_query_time].
+const DRIFT_FIELD_INSTANCE = "instance"
+const DRIFT_FIELD_TIER = "tier"
+const DRIFT_FIELD_DATABASE_FLAGS = "database_flags"
+const DRIFT_FIELD_AUTHORIZED_NETWORKS = "authorized_networks"
+const DRIFT_FIELD_BACKUP_ENABLED = "backup_configuration.enabled"
+const DRIFT_FIELD_BACKUP_START_TIME = "backup_configuration.start_time"
+const DRIFT_FIELD_BACKUP_BINARY_LOG_ENABLED = "backup_configuration.binary_log_enabled"
+const DRIFT_FIELD_USER_LABELS = "user_labels"
+
+// Values reported for instances, flags and labels that exist on only one side
+const DRIFT_VALUE_EXISTS = "exists"
+const DRIFT_VALUE_NOT_FOUND = "not found"
+const DRIFT_VALUE_UNSET = ""
+
+// StateInstance holds the settings of a Cloud SQL instance as recorded in the Terraform state that can drift, e.g.
+// because someone changed them in the console.
+type StateInstance struct {
+	Address                string
+	Project                string
+	Name                   string
+	Tier                   string
+	DatabaseFlags          map[string]string
+	AuthorizedNetworks     []string
+	BackupEnabled          bool
+	BackupStartTime        string
+	BackupBinaryLogEnabled bool
+	UserLabels             map[string]string
+}
+
+// Drift is a single difference between the Terraform state and the live instance.
+type Drift struct {
+	Address  string `json:"address"`
+	Instance string `json:"instance"`
+	Field    string `json:"field"`
+	State    string `json:"state"`
+	Actual   string `json:"actual"`
+}
+
+// DriftReport is the JSON output of the drift detection.
+type DriftReport struct {
+	Drifted bool    `json:"drifted"`
+	Drifts  []Drift `json:"drifts"`
+}
+
+// ParseState parses the output of `terraform show -json`.
+func ParseState(reader io.Reader) (*tfjson.State, error) {
+	state := &tfjson.State{}
+	if err := json.NewDecoder(reader).Decode(state); err != nil {
+		return nil, fmt.Errorf("failed to parse the Terraform state: %v", err)
+	}
+	return state, nil
+}
+
+// GetStateInstances returns all Cloud SQL instances in the given state, including those in child modules, sorted by
+// address.
+func GetStateInstances(state *tfjson.State) ([]StateInstance, error) {
+	instances := []StateInstance{}
+	if state.Values == nil || state.Values.RootModule == nil {
+		return instances, nil
+	}
+
+	modules := []*tfjson.StateModule{state.Values.RootModule}
+	for len(modules) > 0 {
+		module := modules[0]
+		modules = append(modules[1:], module.ChildModules...)
+
+		for _, resource := range module.Resources {
+			if resource.Type != SQL_INSTANCE_RESOURCE_TYPE || resource.Mode != tfjson.ManagedResourceMode {
+				continue
+			}
+			instance, err := newStateInstance(resource)
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, instance)
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Address < instances[j].Address })
+	return instances, nil
+}
+
+func newStateInstance(resource *tfjson.StateResource) (StateInstance, error) {
+	attributes := resource.AttributeValues
+	instance := StateInstance{
+		Address:            resource.Address,
+		Project:            getString(attributes, "project"),
+		Name:               getString(attributes, "name"),
+		DatabaseFlags:      map[string]string{},
+		AuthorizedNetworks: []string{},
+		UserLabels:         map[string]string{},
+	}
+	if instance.Name == "" {
+		return instance, fmt.Errorf("%s has no name in the state", resource.Address)
+	}
+
+	settings := getSingleBlock(attributes, "settings")
+	instance.Tier = getString(settings, "tier")
+
+	for _, flag := range getBlocks(settings, "database_flags") {
+		instance.DatabaseFlags[getString(flag, "name")] = getString(flag, "value")
+	}
+	for _, network := range getBlocks(getSingleBlock(settings, "ip_configuration"), "authorized_networks") {
+		instance.AuthorizedNetworks = append(instance.AuthorizedNetworks, getString(network, "value"))
+	}
+	sort.Strings(instance.AuthorizedNetworks)
+
+	backup := getSingleBlock(settings, "backup_configuration")
+	instance.BackupEnabled = getBool(backup, "enabled")
+	instance.BackupStartTime = getString(backup, "start_time")
+	instance.BackupBinaryLogEnabled = getBool(backup, "binary_log_enabled")
+
+	if labels, ok := settings["user_labels"].(map[string]interface{}); ok {
+		for key, value := range labels {
+			instance.UserLabels[key] = fmt.Sprint(value)
+		}
+	}
+	return instance, nil
+}
+
+// DetectDrift compares each of the given instances with the live instance returned by the Admin API and returns all
+// differences, sorted by address and field.
+func DetectDrift(api AdminAPI, instances []StateInstance) ([]Drift, error) {
+	drifts := []Drift{}
+	for _, instance := range instances {
+		liveInstance, err := api.GetInstance(instance.Project, instance.Name)
+		if IsNotFound(err) {
+			drifts = append(drifts, newDrift(instance, DRIFT_FIELD_INSTANCE, DRIFT_VALUE_EXISTS, DRIFT_VALUE_NOT_FOUND))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, compareInstance(instance, liveInstance)...)
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].Address != drifts[j].Address {
+			return drifts[i].Address < drifts[j].Address
+		}
+		return drifts[i].Field < drifts[j].Field
+	})
+	return drifts, nil
+}
+
+func compareInstance(instance StateInstance, liveInstance *sqladmin.DatabaseInstance) []Drift {
+	settings := liveInstance.Settings
+	if settings == nil {
+		settings = &sqladmin.Settings{}
+	}
+
+	drifts := []Drift{}
+	addIfDifferent := func(field string, stateValue string, actualValue string) {
+		if stateValue != actualValue {
+			drifts = append(drifts, newDrift(instance, field, stateValue, actualValue))
+		}
+	}
+
+	addIfDifferent(DRIFT_FIELD_TIER, instance.Tier, settings.Tier)
+
+	actualFlags := map[string]string{}
+	for _, flag := range settings.DatabaseFlags {
+		actualFlags[flag.Name] = flag.Value
+	}
+	for _, name := range unionKeys(instance.DatabaseFlags, actualFlags) {
+		addIfDifferent(fmt.Sprintf("%s[%s]", DRIFT_FIELD_DATABASE_FLAGS, name), instance.DatabaseFlags[name], actualFlags[name])
+	}
+
+	actualNetworks := []string{}
+	if settings.IpConfiguration != nil {
+		for _, network := range settings.IpConfiguration.AuthorizedNetworks {
+			actualNetworks = append(actualNetworks, network.Value)
+		}
+	}
+	sort.Strings(actualNetworks)
+	addIfDifferent(DRIFT_FIELD_AUTHORIZED_NETWORKS, strings.Join(instance.AuthorizedNetworks, ","), strings.Join(actualNetworks, ","))
+
+	actualBackup := settings.BackupConfiguration
+	if actualBackup == nil {
+		actualBackup = &sqladmin.BackupConfiguration{}
+	}
+	addIfDifferent(DRIFT_FIELD_BACKUP_ENABLED, fmt.Sprint(instance.BackupEnabled), fmt.Sprint(actualBackup.Enabled))
+	addIfDifferent(DRIFT_FIELD_BACKUP_BINARY_LOG_ENABLED, fmt.Sprint(instance.BackupBinaryLogEnabled), fmt.Sprint(actualBackup.BinaryLogEnabled))
+	// The start time is only meaningful if backups are enabled
+	if instance.BackupEnabled || actualBackup.Enabled {
+		addIfDifferent(DRIFT_FIELD_BACKUP_START_TIME, instance.BackupStartTime, actualBackup.StartTime)
+	}
+
+	for _, key := range unionKeys(instance.UserLabels, settings.UserLabels) {
+		addIfDifferent(fmt.Sprintf("%s[%s]", DRIFT_FIELD_USER_LABELS, key), instance.UserLabels[key], settings.UserLabels[key])
+	}
+	return drifts
+}
+
+// WriteDriftHuman writes the given drifts as a human readable summary.
+func WriteDriftHuman(writer io.Writer, drifts []Drift) error {
+	if len(drifts) == 0 {
+		_, err := fmt.Fprintln(writer, "No drift detected.")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(writer, "Detected %d difference(s) between the Terraform state and the live instances:\n", len(drifts)); err != nil {
+		return err
+	}
+	address := ""
+	for _, drift := range drifts {
+		if drift.Address != address {
+			address = drift.Address
+			if _, err := fmt.Fprintf(writer, "\n%s (%s)\n", drift.Address, drift.Instance); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(writer, "  %s: state %s, actual %s\n", drift.Field, formatDriftValue(drift.State), formatDriftValue(drift.Actual)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDriftJSON writes the given drifts as an indented DriftReport.
+func WriteDriftJSON(writer io.Writer, drifts []Drift) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(DriftReport{Drifted: len(drifts) > 0, Drifts: drifts})
+}
+
+func formatDriftValue(value string) string {
+	if value == DRIFT_VALUE_UNSET {
+		return "(unset)"
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+func newDrift(instance StateInstance, field string, stateValue string, actualValue string) Drift {
+	return Drift{
+		Address:  instance.Address,
+		Instance: instance.Name,
+		Field:    field,
+		State:    stateValue,
+		Actual:   actualValue,
+	}
+}
+
+// unionKeys returns the sorted keys that are in either of the given maps.
+func unionKeys(first map[string]string, second map[string]string) []string {
+	keys := []string{}
+	for key := range first {
+		keys = append(keys, key)
+	}
+	for key := range second {
+		if _, exists := first[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// getSingleBlock returns the only element of the given nested block, which the JSON state stores as a list, or an empty
+// map if the block isn't set.
+func getSingleBlock(attributes map[string]interface{}, name string) map[string]interface{} {
+	blocks := getBlocks(attributes, name)
+	if len(blocks) == 0 {
+		return map[string]interface{}{}
+	}
+	return blocks[0]
+}
+
+func getBlocks(attributes map[string]interface{}, name string) []map[string]interface{} {
+	blocks := []map[string]interface{}{}
+	list, _ := attributes[name].([]interface{})
+	for _, element := range list {
+		if block, ok := element.(map[string]interface{}); ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+func getString(attributes map[string]interface{}, name string) string {
+	value, _ := attributes[name].(string)
+	return value
+}
+
+func getBool(attributes map[string]interface{}, name string) bool {
+	value, _ := attributes[name].(bool)
+	return value
+}
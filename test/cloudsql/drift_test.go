@@ -0,0 +1,209 @@
+package cloudsql
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// TEST_STATE_JSON is a trimmed down `terraform show -json` output of the mysql-replicas example
+const TEST_STATE_JSON = `{
+  "format_version": "0.2",
+  "terraform_version": "1.0.11",
+  "values": {
+    "root_module": {
+      "resources": [
+        {"address": "random_id.name", "mode": "managed", "type": "random_id", "name": "name", "values": {"hex": "ab12"}}
+      ],
+      "child_modules": [
+        {
+          "address": "module.mysql",
+          "resources": [
+            {
+              "address": "module.mysql.google_sql_database_instance.master",
+              "mode": "managed",
+              "type": "google_sql_database_instance",
+              "name": "master",
+              "values": {
+                "project": "my-project",
+                "name": "mysql-replicas-ab12",
+                "settings": [
+                  {
+                    "tier": "db-f1-micro",
+                    "database_flags": [
+                      {"name": "auto_increment_increment", "value": "5"},
+                      {"name": "auto_increment_offset", "value": "5"}
+                    ],
+                    "ip_configuration": [
+                      {"authorized_networks": [{"name": "allow-all-inbound", "value": "0.0.0.0/0"}]}
+                    ],
+                    "backup_configuration": [
+                      {"enabled": true, "start_time": "04:00", "binary_log_enabled": true}
+                    ],
+                    "user_labels": {"test-id": "mysql-replicas-example"}
+                  }
+                ]
+              }
+            },
+            {
+              "address": "module.mysql.google_sql_database_instance.read_replica[\"read-0\"]",
+              "mode": "managed",
+              "type": "google_sql_database_instance",
+              "name": "read_replica",
+              "index": "read-0",
+              "values": {
+                "project": "my-project",
+                "name": "mysql-replicas-ab12-read-0",
+                "settings": [{"tier": "db-f1-micro", "user_labels": {}}]
+              }
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func newTestStateInstances(t *testing.T) []StateInstance {
+	state, err := ParseState(strings.NewReader(TEST_STATE_JSON))
+	require.NoError(t, err)
+	instances, err := GetStateInstances(state)
+	require.NoError(t, err)
+	return instances
+}
+
+// newTestLiveMaster returns the master of TEST_STATE_JSON as returned by the Admin API if it didn't drift
+func newTestLiveMaster() *sqladmin.DatabaseInstance {
+	return &sqladmin.DatabaseInstance{
+		Project: "my-project",
+		Name:    "mysql-replicas-ab12",
+		Settings: &sqladmin.Settings{
+			Tier: "db-f1-micro",
+			DatabaseFlags: []*sqladmin.DatabaseFlags{
+				{Name: "auto_increment_offset", Value: "5"},
+				{Name: "auto_increment_increment", Value: "5"},
+			},
+			IpConfiguration: &sqladmin.IpConfiguration{
+				AuthorizedNetworks: []*sqladmin.AclEntry{{Name: "allow-all-inbound", Value: "0.0.0.0/0"}},
+			},
+			BackupConfiguration: &sqladmin.BackupConfiguration{Enabled: true, StartTime: "04:00", BinaryLogEnabled: true},
+			UserLabels:          map[string]string{"test-id": "mysql-replicas-example"},
+		},
+	}
+}
+
+func newTestLiveReplica() *sqladmin.DatabaseInstance {
+	return &sqladmin.DatabaseInstance{
+		Project:  "my-project",
+		Name:     "mysql-replicas-ab12-read-0",
+		Settings: &sqladmin.Settings{Tier: "db-f1-micro"},
+	}
+}
+
+func TestGetStateInstances(t *testing.T) {
+	t.Parallel()
+
+	instances := newTestStateInstances(t)
+	require.Len(t, instances, 2)
+
+	assert.Equal(
+		t,
+		StateInstance{
+			Address:                "module.mysql.google_sql_database_instance.master",
+			Project:                "my-project",
+			Name:                   "mysql-replicas-ab12",
+			Tier:                   "db-f1-micro",
+			DatabaseFlags:          map[string]string{"auto_increment_increment": "5", "auto_increment_offset": "5"},
+			AuthorizedNetworks:     []string{"0.0.0.0/0"},
+			BackupEnabled:          true,
+			BackupStartTime:        "04:00",
+			BackupBinaryLogEnabled: true,
+			UserLabels:             map[string]string{"test-id": "mysql-replicas-example"},
+		},
+		instances[0],
+	)
+	assert.Equal(t, `module.mysql.google_sql_database_instance.read_replica["read-0"]`, instances[1].Address)
+	assert.Equal(t, "mysql-replicas-ab12-read-0", instances[1].Name)
+}
+
+func TestDetectDriftNoDrift(t *testing.T) {
+	t.Parallel()
+
+	api := NewFakeAdminAPI(newTestLiveMaster(), newTestLiveReplica())
+	drifts, err := DetectDrift(api, newTestStateInstances(t))
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestDetectDrift(t *testing.T) {
+	t.Parallel()
+
+	// Someone changed the master in the console and deleted the read replica
+	master := newTestLiveMaster()
+	master.Settings.Tier = "db-g1-small"
+	master.Settings.DatabaseFlags = []*sqladmin.DatabaseFlags{
+		{Name: "auto_increment_increment", Value: "5"},
+		{Name: "long_query_time", Value: "1"},
+	}
+	master.Settings.IpConfiguration.AuthorizedNetworks = append(master.Settings.IpConfiguration.AuthorizedNetworks, &sqladmin.AclEntry{Value: "10.0.0.0/8"})
+	master.Settings.BackupConfiguration.StartTime = "02:00"
+	master.Settings.UserLabels["owner"] = "someone"
+
+	drifts, err := DetectDrift(NewFakeAdminAPI(master), newTestStateInstances(t))
+	require.NoError(t, err)
+
+	const masterAddress = "module.mysql.google_sql_database_instance.master"
+	newMasterDrift := func(field string, stateValue string, actualValue string) Drift {
+		return Drift{Address: masterAddress, Instance: "mysql-replicas-ab12", Field: field, State: stateValue, Actual: actualValue}
+	}
+	assert.Equal(
+		t,
+		[]Drift{
+			newMasterDrift(DRIFT_FIELD_AUTHORIZED_NETWORKS, "0.0.0.0/0", "0.0.0.0/0,10.0.0.0/8"),
+			newMasterDrift(DRIFT_FIELD_BACKUP_START_TIME, "04:00", "02:00"),
+			newMasterDrift("database_flags[auto_increment_offset]", "5", DRIFT_VALUE_UNSET),
+			newMasterDrift("database_flags[long_query_time]", DRIFT_VALUE_UNSET, "1"),
+			newMasterDrift(DRIFT_FIELD_TIER, "db-f1-micro", "db-g1-small"),
+			newMasterDrift("user_labels[owner]", DRIFT_VALUE_UNSET, "someone"),
+			{
+				Address:  `module.mysql.google_sql_database_instance.read_replica["read-0"]`,
+				Instance: "mysql-replicas-ab12-read-0",
+				Field:    DRIFT_FIELD_INSTANCE,
+				State:    DRIFT_VALUE_EXISTS,
+				Actual:   DRIFT_VALUE_NOT_FOUND,
+			},
+		},
+		drifts,
+	)
+}
+
+func TestWriteDrift(t *testing.T) {
+	t.Parallel()
+
+	drifts := []Drift{
+		{Address: "module.mysql.google_sql_database_instance.master", Instance: "mysql", Field: DRIFT_FIELD_TIER, State: "db-f1-micro", Actual: "db-g1-small"},
+		{Address: "module.mysql.google_sql_database_instance.master", Instance: "mysql", Field: "user_labels[owner]", State: DRIFT_VALUE_UNSET, Actual: "someone"},
+	}
+
+	var human bytes.Buffer
+	require.NoError(t, WriteDriftHuman(&human, drifts))
+	assert.Contains(t, human.String(), "Detected 2 difference(s)")
+	assert.Contains(t, human.String(), "module.mysql.google_sql_database_instance.master (mysql)")
+	assert.Contains(t, human.String(), `tier: state "db-f1-micro", actual "db-g1-small"`)
+	assert.Contains(t, human.String(), `user_labels[owner]: state (unset), actual "someone"`)
+
+	human.Reset()
+	require.NoError(t, WriteDriftHuman(&human, []Drift{}))
+	assert.Equal(t, "No drift detected.\n", human.String())
+
+	var output bytes.Buffer
+	require.NoError(t, WriteDriftJSON(&output, drifts))
+	report := DriftReport{}
+	require.NoError(t, json.Unmarshal(output.Bytes(), &report))
+	assert.Equal(t, DriftReport{Drifted: true, Drifts: drifts}, report)
+}
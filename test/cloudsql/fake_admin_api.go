@@ -0,0 +1,266 @@
+package cloudsql
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"google.golang.org/api/googleapi"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// FakeAdminAPI is an in-memory implementation of AdminAPI for unit tests. Instances are stored by project and name and
+// are deep copied on the way in and out, so callers can't accidentally modify the stored state.
+type FakeAdminAPI struct {
+	mutex           sync.Mutex
+	instances       map[string]*sqladmin.DatabaseInstance
+	insightsConfigs map[string]InsightsConfig
+	operations      map[string]*sqladmin.Operation
+	databases       map[string][]*sqladmin.Database
+	users           map[string][]*User
+	projectErrors   map[string]error
+}
+
+// NewFakeAdminAPI creates a fake Admin API that serves the given instances. Each instance needs its Project and Name
+// set.
+func NewFakeAdminAPI(instances ...*sqladmin.DatabaseInstance) *FakeAdminAPI {
+	fake := &FakeAdminAPI{
+		instances:       map[string]*sqladmin.DatabaseInstance{},
+		insightsConfigs: map[string]InsightsConfig{},
+		operations:      map[string]*sqladmin.Operation{},
+		databases:       map[string][]*sqladmin.Database{},
+		users:           map[string][]*User{},
+		projectErrors:   map[string]error{},
+	}
+	for _, instance := range instances {
+		fake.PutInstance(instance)
+	}
+	return fake
+}
+
+// PutProjectError makes all requests for the given project fail with the given error, e.g. to simulate missing
+// permissions.
+func (fake *FakeAdminAPI) PutProjectError(project string, err error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	fake.projectErrors[project] = err
+}
+
+// PutInstance adds or replaces an instance.
+func (fake *FakeAdminAPI) PutInstance(instance *sqladmin.DatabaseInstance) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	fake.instances[instanceKey(instance.Project, instance.Name)] = copyInstance(instance)
+}
+
+func (fake *FakeAdminAPI) GetInstance(project string, instance string) (*sqladmin.DatabaseInstance, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err, exists := fake.projectErrors[project]; exists {
+		return nil, err
+	}
+
+	stored, exists := fake.instances[instanceKey(project, instance)]
+	if !exists {
+		return nil, notFoundError("instance %s does not exist in project %s", instance, project)
+	}
+	return copyInstance(stored), nil
+}
+
+// PutInsightsConfig sets the Query Insights configuration of an existing instance.
+func (fake *FakeAdminAPI) PutInsightsConfig(project string, instance string, config InsightsConfig) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	fake.insightsConfigs[instanceKey(project, instance)] = config
+}
+
+func (fake *FakeAdminAPI) GetInsightsConfig(project string, instance string) (*InsightsConfig, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err, exists := fake.projectErrors[project]; exists {
+		return nil, err
+	}
+
+	key := instanceKey(project, instance)
+	if _, exists := fake.instances[key]; !exists {
+		return nil, notFoundError("instance %s does not exist in project %s", instance, project)
+	}
+
+	config := fake.insightsConfigs[key]
+	return &config, nil
+}
+
+// PromoteReplica turns the given read replica into a standalone instance right away and returns a finished operation.
+func (fake *FakeAdminAPI) PromoteReplica(project string, instance string) (*sqladmin.Operation, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err, exists := fake.projectErrors[project]; exists {
+		return nil, err
+	}
+
+	stored, exists := fake.instances[instanceKey(project, instance)]
+	if !exists {
+		return nil, notFoundError("instance %s does not exist in project %s", instance, project)
+	}
+	if stored.MasterInstanceName == "" {
+		return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("instance %s is not a read replica", instance)}
+	}
+
+	stored.MasterInstanceName = ""
+	stored.ReplicaConfiguration = nil
+	stored.InstanceType = "CLOUD_SQL_INSTANCE"
+
+	return fake.putOperation(project, instance, "PROMOTE_REPLICA"), nil
+}
+
+// PutOperation adds or replaces an operation, e.g. to simulate a long running or failed operation.
+func (fake *FakeAdminAPI) PutOperation(project string, operation *sqladmin.Operation) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	fake.operations[instanceKey(project, operation.Name)] = copyOperation(operation)
+}
+
+func (fake *FakeAdminAPI) GetOperation(project string, operation string) (*sqladmin.Operation, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err, exists := fake.projectErrors[project]; exists {
+		return nil, err
+	}
+
+	stored, exists := fake.operations[instanceKey(project, operation)]
+	if !exists {
+		return nil, notFoundError("operation %s does not exist in project %s", operation, project)
+	}
+	return copyOperation(stored), nil
+}
+
+// PutDatabase adds a database to the instance the database's Project and Instance refer to.
+func (fake *FakeAdminAPI) PutDatabase(database *sqladmin.Database) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	key := instanceKey(database.Project, database.Instance)
+	var copied sqladmin.Database
+	copyJSON(database, &copied)
+	fake.databases[key] = append(fake.databases[key], &copied)
+}
+
+func (fake *FakeAdminAPI) ListDatabases(project string, instance string) ([]*sqladmin.Database, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err, exists := fake.projectErrors[project]; exists {
+		return nil, err
+	}
+
+	key := instanceKey(project, instance)
+	if _, exists := fake.instances[key]; !exists {
+		return nil, notFoundError("instance %s does not exist in project %s", instance, project)
+	}
+
+	databases := []*sqladmin.Database{}
+	for _, database := range fake.databases[key] {
+		var copied sqladmin.Database
+		copyJSON(database, &copied)
+		databases = append(databases, &copied)
+	}
+	return databases, nil
+}
+
+// PutUser adds a user to the given instance.
+func (fake *FakeAdminAPI) PutUser(project string, instance string, user User) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	key := instanceKey(project, instance)
+	fake.users[key] = append(fake.users[key], &user)
+}
+
+func (fake *FakeAdminAPI) ListUsers(project string, instance string) ([]*User, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err, exists := fake.projectErrors[project]; exists {
+		return nil, err
+	}
+
+	key := instanceKey(project, instance)
+	if _, exists := fake.instances[key]; !exists {
+		return nil, notFoundError("instance %s does not exist in project %s", instance, project)
+	}
+
+	users := []*User{}
+	for _, user := range fake.users[key] {
+		copied := *user
+		users = append(users, &copied)
+	}
+	return users, nil
+}
+
+// ListOperations returns the operations targeting the given instance, sorted by insert time, most recent first. Use
+// PutOperation to simulate the operations of a deleted instance.
+func (fake *FakeAdminAPI) ListOperations(project string, instance string) ([]*sqladmin.Operation, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err, exists := fake.projectErrors[project]; exists {
+		return nil, err
+	}
+
+	operations := []*sqladmin.Operation{}
+	for _, operation := range fake.operations {
+		if operation.TargetProject == project && operation.TargetId == instance {
+			operations = append(operations, copyOperation(operation))
+		}
+	}
+	if _, exists := fake.instances[instanceKey(project, instance)]; !exists && len(operations) == 0 {
+		return nil, notFoundError("instance %s does not exist in project %s", instance, project)
+	}
+
+	sort.Slice(operations, func(i, j int) bool { return operations[i].InsertTime > operations[j].InsertTime })
+	return operations, nil
+}
+
+// putOperation stores a finished operation of the given type on the given instance. The caller must hold the mutex.
+func (fake *FakeAdminAPI) putOperation(project string, instance string, operationType string) *sqladmin.Operation {
+	operation := &sqladmin.Operation{
+		Name:          fmt.Sprintf("operation-%d", len(fake.operations)+1),
+		OperationType: operationType,
+		TargetId:      instance,
+		TargetProject: project,
+		Status:        OPERATION_STATUS_DONE,
+	}
+	fake.operations[instanceKey(project, operation.Name)] = operation
+	return copyOperation(operation)
+}
+
+func instanceKey(project string, instance string) string {
+	return project + "/" + instance
+}
+
+// copyInstance deep copies an instance by round tripping it through JSON, the same way the real API client decodes it.
+func copyInstance(instance *sqladmin.DatabaseInstance) *sqladmin.DatabaseInstance {
+	var copied sqladmin.DatabaseInstance
+	copyJSON(instance, &copied)
+	return &copied
+}
+
+// copyOperation deep copies an operation the same way as copyInstance.
+func copyOperation(operation *sqladmin.Operation) *sqladmin.Operation {
+	var copied sqladmin.Operation
+	copyJSON(operation, &copied)
+	return &copied
+}
+
+func copyJSON(value interface{}, copied interface{}) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(content, copied); err != nil {
+		panic(err)
+	}
+}
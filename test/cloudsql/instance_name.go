@@ -0,0 +1,195 @@
+package cloudsql
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Cloud SQL limits the length of "<project ID>:<instance name>"
+const MAX_PROJECT_AND_INSTANCE_NAME_LENGTH = 98
+
+// After an instance is deleted, its name can't be reused for up to a week
+const INSTANCE_NAME_REUSE_PERIOD = 7 * 24 * time.Hour
+
+const OPERATION_TYPE_DELETE = "DELETE"
+
+// The reason of the 403 error Cloud SQL returns for instances that don't exist, so the names of instances can't be probed
+const INSTANCE_NOT_AUTHORIZED_REASON = "notAuthorized"
+
+// The failover replica of the cloud-sql module is named "<name>-failover" and the read replicas "<name>-<key>", where
+// the replicas configured with num_read_replicas are keyed as "read-<index>"
+const FAILOVER_REPLICA_KEY = "failover"
+const INDEXED_READ_REPLICA_KEY_FORMAT = "read-%d"
+
+// The generated names end in this many random bytes in hex, which is enough to make collisions between concurrent test
+// runs unlikely
+const INSTANCE_NAME_RANDOM_BYTES = 4
+const DEFAULT_INSTANCE_NAME_ATTEMPTS = 5
+
+var instanceNameRegexp = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// InstanceNameOptions configures GenerateInstanceName.
+type InstanceNameOptions struct {
+	Project string
+	Prefix  string
+	// The keys of the replicas of the instance, named "<name>-<key>". Use GetReplicaKeys for the replicas of the
+	// cloud-sql module.
+	ReplicaKeys []string
+	// Defaults to DEFAULT_INSTANCE_NAME_ATTEMPTS
+	MaxAttempts int
+	// Defaults to crypto/rand
+	Random io.Reader
+	// Defaults to the current time
+	Now time.Time
+}
+
+// InstanceNameTakenError is returned by CheckInstanceNameAvailable if the instance or one of its replicas can't be
+// created with the given name.
+type InstanceNameTakenError struct {
+	Name   string
+	Reason string
+}
+
+func (err InstanceNameTakenError) Error() string {
+	return fmt.Sprintf("the instance name %s is taken: %s", err.Name, err.Reason)
+}
+
+// GetReplicaKeys returns the keys of the failover replica, the given number of replicas configured with
+// num_read_replicas and the given keyed read replicas of the cloud-sql module.
+func GetReplicaKeys(numReadReplicas int, readReplicaKeys ...string) []string {
+	keys := []string{FAILOVER_REPLICA_KEY}
+	for index := 0; index < numReadReplicas; index++ {
+		keys = append(keys, fmt.Sprintf(INDEXED_READ_REPLICA_KEY_FORMAT, index))
+	}
+	return append(keys, readReplicaKeys...)
+}
+
+// GetInstanceNames returns the names of the instance and of its replicas with the given keys.
+func GetInstanceNames(name string, replicaKeys []string) []string {
+	names := []string{name}
+	for _, key := range replicaKeys {
+		names = append(names, name+"-"+key)
+	}
+	return names
+}
+
+// ValidateInstanceName checks that the given name and the names of its replicas are valid Cloud SQL instance names:
+// lowercase letters, numbers and hyphens, starting with a letter and not ending with a hyphen, and short enough to fit
+// into MAX_PROJECT_AND_INSTANCE_NAME_LENGTH with the project ID.
+func ValidateInstanceName(project string, name string, replicaKeys []string) error {
+	for _, instanceName := range GetInstanceNames(name, replicaKeys) {
+		if !instanceNameRegexp.MatchString(instanceName) {
+			return fmt.Errorf("%s is not a valid instance name: use lowercase letters, numbers and hyphens, start with a letter and don't end with a hyphen", instanceName)
+		}
+		if length := len(project) + 1 + len(instanceName); length > MAX_PROJECT_AND_INSTANCE_NAME_LENGTH {
+			return fmt.Errorf("%s:%s is %d characters long, the limit is %d", project, instanceName, length, MAX_PROJECT_AND_INSTANCE_NAME_LENGTH)
+		}
+	}
+	return nil
+}
+
+// CheckInstanceNameAvailable returns an InstanceNameTakenError if an instance with the given name or the name of one
+// of its replicas exists, or was deleted within INSTANCE_NAME_REUSE_PERIOD before now. The check for deleted instances
+// is best effort: it relies on the operations the Admin API still lists for them, so a name it reports as available
+// can still be rejected when the instance is created.
+func CheckInstanceNameAvailable(api AdminAPI, project string, name string, replicaKeys []string, now time.Time) error {
+	for _, instanceName := range GetInstanceNames(name, replicaKeys) {
+		_, err := api.GetInstance(project, instanceName)
+		if err == nil {
+			return InstanceNameTakenError{Name: instanceName, Reason: "the instance exists"}
+		}
+		if !isMissingInstance(err) {
+			return err
+		}
+
+		operations, err := api.ListOperations(project, instanceName)
+		if isMissingInstance(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, operation := range operations {
+			if operation.OperationType != OPERATION_TYPE_DELETE {
+				continue
+			}
+			deletedAt, err := time.Parse(time.RFC3339, operation.InsertTime)
+			if err != nil {
+				return fmt.Errorf("failed to parse the time of operation %s: %v", operation.Name, err)
+			}
+			if now.Sub(deletedAt) < INSTANCE_NAME_REUSE_PERIOD {
+				return InstanceNameTakenError{Name: instanceName, Reason: fmt.Sprintf("the instance was deleted at %s", operation.InsertTime)}
+			}
+		}
+	}
+	return nil
+}
+
+// GenerateInstanceName generates a name of the form "<prefix>-<random hex>" that is valid and available for the
+// instance and its replicas, trying up to MaxAttempts random names.
+func GenerateInstanceName(api AdminAPI, options InstanceNameOptions) (string, error) {
+	maxAttempts := options.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DEFAULT_INSTANCE_NAME_ATTEMPTS
+	}
+	random := options.Random
+	if random == nil {
+		random = rand.Reader
+	}
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		suffix := make([]byte, INSTANCE_NAME_RANDOM_BYTES)
+		if _, err := io.ReadFull(random, suffix); err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("%s-%s", options.Prefix, hex.EncodeToString(suffix))
+
+		// The random suffix is always valid, so an invalid name won't get better with another attempt
+		if err := ValidateInstanceName(options.Project, name, options.ReplicaKeys); err != nil {
+			return "", err
+		}
+
+		err := CheckInstanceNameAvailable(api, options.Project, name, options.ReplicaKeys, now)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.As(err, &InstanceNameTakenError{}) {
+			return "", err
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("failed to find an available instance name after %d attempts: %v", maxAttempts, lastErr)
+}
+
+// isMissingInstance returns true if the given error was returned because an instance doesn't exist. Depending on the
+// API version, Cloud SQL answers requests for instances that never existed with a 404 or with a 403 with the reason
+// INSTANCE_NOT_AUTHORIZED_REASON. Other 403 errors, e.g. a missing IAM permission or a disabled API, are not.
+func isMissingInstance(err error) bool {
+	if IsNotFound(err) {
+		return true
+	}
+
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == INSTANCE_NOT_AUTHORIZED_REASON {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package cloudsql
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+var instanceNameTestNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func TestGetReplicaKeys(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"failover"}, GetReplicaKeys(0))
+	assert.Equal(t, []string{"failover", "read-0", "read-1", "analytics"}, GetReplicaKeys(2, "analytics"))
+	assert.Equal(t, []string{"mysql-abc", "mysql-abc-failover", "mysql-abc-read-0"}, GetInstanceNames("mysql-abc", GetReplicaKeys(1)))
+}
+
+func TestValidateInstanceName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		instanceName string
+		replicaKeys  []string
+		expectedErr  string
+	}{
+		{"Valid", "mysql-replicas-0a1b2c3d", GetReplicaKeys(1), ""},
+		{"Uppercase", "MySql-replicas", nil, "not a valid instance name"},
+		{"LeadingDigit", "1mysql", nil, "not a valid instance name"},
+		{"TrailingHyphen", "mysql-", nil, "not a valid instance name"},
+		{"Underscore", "mysql_replicas", nil, "not a valid instance name"},
+		{"InvalidReplicaKey", "mysql", []string{"Read"}, "mysql-Read is not a valid instance name"},
+		// "test-project:" + 77 characters fit, but not with the "-failover" suffix
+		{"TooLongWithFailover", strings.Repeat("a", 77), GetReplicaKeys(0), "-failover is 99 characters long"},
+		{"TooLongWithReadReplica", strings.Repeat("a", 80), []string{"read-0"}, "-read-0 is 100 characters long"},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateInstanceName("test-project", testCase.instanceName, testCase.replicaKeys)
+			if testCase.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCheckInstanceNameAvailable(t *testing.T) {
+	t.Parallel()
+
+	api := NewFakeAdminAPI(&sqladmin.DatabaseInstance{Project: "test-project", Name: "live"})
+	api.PutOperation("test-project", &sqladmin.Operation{
+		Name:          "recent-delete",
+		OperationType: OPERATION_TYPE_DELETE,
+		TargetProject: "test-project",
+		TargetId:      "recent-failover",
+		InsertTime:    instanceNameTestNow.Add(-2 * 24 * time.Hour).Format(time.RFC3339),
+	})
+	api.PutOperation("test-project", &sqladmin.Operation{
+		Name:          "old-delete",
+		OperationType: OPERATION_TYPE_DELETE,
+		TargetProject: "test-project",
+		TargetId:      "old",
+		InsertTime:    instanceNameTestNow.Add(-8 * 24 * time.Hour).Format(time.RFC3339),
+	})
+
+	assert.NoError(t, CheckInstanceNameAvailable(api, "test-project", "free", GetReplicaKeys(1), instanceNameTestNow))
+	assert.NoError(t, CheckInstanceNameAvailable(api, "test-project", "old", nil, instanceNameTestNow))
+
+	err := CheckInstanceNameAvailable(api, "test-project", "live", nil, instanceNameTestNow)
+	assert.Equal(t, InstanceNameTakenError{Name: "live", Reason: "the instance exists"}, err)
+
+	err = CheckInstanceNameAvailable(api, "test-project", "recent", GetReplicaKeys(0), instanceNameTestNow)
+	require.IsType(t, InstanceNameTakenError{}, err)
+	assert.Equal(t, "recent-failover", err.(InstanceNameTakenError).Name)
+
+	// Without the failover replica, the name is available
+	assert.NoError(t, CheckInstanceNameAvailable(api, "test-project", "recent", nil, instanceNameTestNow))
+}
+
+func TestGenerateInstanceName(t *testing.T) {
+	t.Parallel()
+
+	api := NewFakeAdminAPI(&sqladmin.DatabaseInstance{Project: "test-project", Name: "mysql-00000000"})
+	api.PutOperation("test-project", &sqladmin.Operation{
+		Name:          "delete",
+		OperationType: OPERATION_TYPE_DELETE,
+		TargetProject: "test-project",
+		TargetId:      "mysql-01010101-read-0",
+		InsertTime:    instanceNameTestNow.Add(-time.Hour).Format(time.RFC3339),
+	})
+
+	// The first two names are taken by a live instance and a recently deleted read replica
+	random := bytes.NewReader([]byte{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2})
+	name, err := GenerateInstanceName(api, InstanceNameOptions{
+		Project:     "test-project",
+		Prefix:      "mysql",
+		ReplicaKeys: GetReplicaKeys(1),
+		Random:      random,
+		Now:         instanceNameTestNow,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "mysql-02020202", name)
+
+	_, err = GenerateInstanceName(api, InstanceNameOptions{
+		Project:     "test-project",
+		Prefix:      "mysql",
+		MaxAttempts: 1,
+		Random:      bytes.NewReader([]byte{0, 0, 0, 0}),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 1 attempts")
+
+	_, err = GenerateInstanceName(api, InstanceNameOptions{Project: "test-project", Prefix: "MySql"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a valid instance name")
+}
+
+func TestIsMissingInstance(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isMissingInstance(&googleapi.Error{Code: http.StatusNotFound}))
+	assert.True(t, isMissingInstance(&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: INSTANCE_NOT_AUTHORIZED_REASON}}}))
+	assert.False(t, isMissingInstance(&googleapi.Error{Code: http.StatusForbidden}))
+	assert.False(t, isMissingInstance(&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}))
+	assert.False(t, isMissingInstance(&googleapi.Error{Code: http.StatusInternalServerError}))
+}
+
+func TestGenerateInstanceNameFailsWithoutPermission(t *testing.T) {
+	t.Parallel()
+
+	permissionDenied := &googleapi.Error{
+		Code:    http.StatusForbidden,
+		Message: "The caller does not have permission",
+		Errors:  []googleapi.ErrorItem{{Reason: "forbidden", Message: "The caller does not have permission"}},
+	}
+	api := NewFakeAdminAPI()
+	api.PutProjectError("test-project", permissionDenied)
+
+	err := CheckInstanceNameAvailable(api, "test-project", "free", GetReplicaKeys(1), instanceNameTestNow)
+	assert.Equal(t, permissionDenied, err)
+
+	_, err = GenerateInstanceName(api, InstanceNameOptions{Project: "test-project", Prefix: "mysql", Now: instanceNameTestNow})
+	assert.Equal(t, permissionDenied, err)
+
+	// Other projects are still accessible
+	assert.NoError(t, CheckInstanceNameAvailable(api, "other-project", "free", nil, instanceNameTestNow))
+}
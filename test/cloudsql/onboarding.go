@@ -0,0 +1,671 @@
+package cloudsql
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// The addresses of the resources in modules/cloud-sql that existing instances, databases and users are imported to
+const ONBOARDING_MASTER_ADDRESS = "google_sql_database_instance.master"
+const ONBOARDING_FAILOVER_REPLICA_ADDRESS = "google_sql_database_instance.failover_replica[0]"
+const ONBOARDING_READ_REPLICA_ADDRESS = "google_sql_database_instance.read_replica"
+const ONBOARDING_DATABASE_ADDRESS = "google_sql_database.default"
+const ONBOARDING_ADDITIONAL_DATABASE_ADDRESS = "google_sql_database.additional"
+const ONBOARDING_USER_ADDRESS = "google_sql_user.default"
+const ONBOARDING_ADDITIONAL_USER_ADDRESS = "google_sql_user.additional"
+const ONBOARDING_IAM_USER_ADDRESS = "google_sql_user.iam"
+
+// The Admin API never returns passwords, so the generated tfvars use this placeholder for the passwords of additional
+// users. The password of the master user is left out, so it can be set with TF_VAR_master_user_password.
+const ONBOARDING_PASSWORD_PLACEHOLDER = "REPLACE_ME"
+
+const IAM_USER_TYPE_USER = "CLOUD_IAM_USER"
+const IAM_USER_TYPE_SERVICE_ACCOUNT = "CLOUD_IAM_SERVICE_ACCOUNT"
+
+const SERVICE_ACCOUNT_EMAIL_SUFFIX = ".gserviceaccount.com"
+
+// Cloud SQL creates these databases and users itself, so they are not managed by the module
+var mySqlSystemDatabases = []string{"information_schema", "mysql", "performance_schema", "sys"}
+var postgresSystemDatabases = []string{"cloudsqladmin", "postgres", "template0", "template1"}
+var systemUserRegexp = regexp.MustCompile(`^(root|postgres|mysql\..*|cloudsql.*)$`)
+
+var hclIdentifierRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// OnboardingOptions configures which instance GenerateOnboarding reads and how it maps it to the module.
+type OnboardingOptions struct {
+	Project  string
+	Instance string
+
+	// The database and user to use as db_name and master_user_name. If empty, the first database and user in
+	// alphabetical order are used, and all others become additional databases and users.
+	DatabaseName   string
+	MasterUserName string
+
+	// The address of the module in the configuration the resources are imported to, e.g. module.mysql. If empty, the
+	// module is expected to be the root module.
+	ModuleAddress string
+}
+
+// ImportAddress is a resource of the module and the ID to import the existing resource with.
+type ImportAddress struct {
+	Address string `json:"address"`
+	ID      string `json:"id"`
+}
+
+// Onboarding holds the module inputs and the imports that bring an existing instance under Terraform. Warnings
+// describe settings the module can't express, which Terraform will therefore plan to change.
+type Onboarding struct {
+	Vars     map[string]interface{} `json:"vars"`
+	Imports  []ImportAddress        `json:"imports"`
+	Warnings []string               `json:"warnings"`
+}
+
+type onboardingBuilder struct {
+	api        AdminAPI
+	options    OnboardingOptions
+	master     *sqladmin.DatabaseInstance
+	isPostgres bool
+	onboarding *Onboarding
+}
+
+// GenerateOnboarding reads the given master instance, its replicas, databases and users from the Admin API and returns
+// the inputs for modules/cloud-sql that match them, along with the resource addresses to import them to.
+func GenerateOnboarding(api AdminAPI, options OnboardingOptions) (*Onboarding, error) {
+	master, err := api.GetInstance(options.Project, options.Instance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance %s: %v", options.Instance, err)
+	}
+	if master.MasterInstanceName != "" {
+		return nil, fmt.Errorf("instance %s is a replica of %s, onboard the master instead", options.Instance, master.MasterInstanceName)
+	}
+	if master.Settings == nil {
+		master.Settings = &sqladmin.Settings{}
+	}
+
+	builder := &onboardingBuilder{
+		api:        api,
+		options:    options,
+		master:     master,
+		isPostgres: strings.HasPrefix(master.DatabaseVersion, "POSTGRES"),
+		onboarding: &Onboarding{Vars: map[string]interface{}{}, Imports: []ImportAddress{}, Warnings: []string{}},
+	}
+	builder.addImport(ONBOARDING_MASTER_ADDRESS, fmt.Sprintf("projects/%s/instances/%s", options.Project, master.Name))
+
+	// The users go first, as the IAM authentication flag is only set through database_flags if there are no IAM users
+	hasIamUsers, err := builder.addUsers()
+	if err != nil {
+		return nil, err
+	}
+	if err := builder.addDatabases(); err != nil {
+		return nil, err
+	}
+	builder.addInstanceVars(hasIamUsers)
+	if err := builder.addInsightsVars(); err != nil {
+		return nil, err
+	}
+	if err := builder.addReplicas(); err != nil {
+		return nil, err
+	}
+	return builder.onboarding, nil
+}
+
+func (builder *onboardingBuilder) addImport(address string, id string) {
+	if builder.options.ModuleAddress != "" {
+		address = builder.options.ModuleAddress + "." + address
+	}
+	builder.onboarding.Imports = append(builder.onboarding.Imports, ImportAddress{Address: address, ID: id})
+}
+
+func (builder *onboardingBuilder) addWarning(format string, args ...interface{}) {
+	builder.onboarding.Warnings = append(builder.onboarding.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (builder *onboardingBuilder) addInstanceVars(hasIamUsers bool) {
+	master := builder.master
+	settings := master.Settings
+	vars := builder.onboarding.Vars
+
+	vars["project"] = builder.options.Project
+	vars["region"] = master.Region
+	vars["name"] = master.Name
+	vars["engine"] = master.DatabaseVersion
+	vars["machine_type"] = settings.Tier
+	vars["activation_policy"] = settings.ActivationPolicy
+	vars["disk_autoresize"] = settings.StorageAutoResize == nil || *settings.StorageAutoResize
+	vars["disk_size"] = settings.DataDiskSizeGb
+	vars["disk_type"] = settings.DataDiskType
+
+	if settings.LocationPreference != nil && settings.LocationPreference.Zone != "" {
+		vars["master_zone"] = settings.LocationPreference.Zone
+	}
+
+	ipConfiguration := settings.IpConfiguration
+	if ipConfiguration == nil {
+		ipConfiguration = &sqladmin.IpConfiguration{}
+	}
+	vars["enable_public_internet_access"] = ipConfiguration.Ipv4Enabled
+	vars["require_ssl"] = ipConfiguration.RequireSsl
+	if ipConfiguration.PrivateNetwork != "" {
+		vars["private_network"] = ipConfiguration.PrivateNetwork
+	}
+	// The replicas read the name of each network, so it is always set
+	authorizedNetworks := []interface{}{}
+	for _, network := range ipConfiguration.AuthorizedNetworks {
+		authorizedNetworks = append(authorizedNetworks, map[string]interface{}{"name": network.Name, "value": network.Value})
+	}
+	vars["authorized_networks"] = authorizedNetworks
+
+	backup := settings.BackupConfiguration
+	if backup == nil {
+		backup = &sqladmin.BackupConfiguration{}
+	}
+	vars["backup_enabled"] = backup.Enabled
+	if backup.StartTime != "" {
+		vars["backup_start_time"] = backup.StartTime
+	}
+	if builder.isPostgres {
+		vars["postgres_point_in_time_recovery_enabled"] = backup.PointInTimeRecoveryEnabled
+	} else {
+		vars["mysql_binary_log_enabled"] = backup.BinaryLogEnabled
+	}
+
+	if window := settings.MaintenanceWindow; window != nil && window.Day != 0 {
+		vars["maintenance_window_day"] = window.Day
+		vars["maintenance_window_hour"] = window.Hour
+		if window.UpdateTrack != "" {
+			vars["maintenance_track"] = window.UpdateTrack
+		}
+	} else {
+		builder.addWarning("Instance %s has no maintenance window, the module will set the default maintenance window", master.Name)
+	}
+
+	// The module adds the IAM authentication flag itself whenever IAM users are configured
+	iamAuthenticationFlag := "cloudsql_iam_authentication"
+	if builder.isPostgres {
+		iamAuthenticationFlag = "cloudsql.iam_authentication"
+	}
+	databaseFlags := []interface{}{}
+	for _, flag := range settings.DatabaseFlags {
+		if hasIamUsers && flag.Name == iamAuthenticationFlag {
+			continue
+		}
+		databaseFlags = append(databaseFlags, map[string]interface{}{"name": flag.Name, "value": flag.Value})
+	}
+	vars["database_flags"] = databaseFlags
+
+	labels := map[string]interface{}{}
+	for key, value := range settings.UserLabels {
+		labels[key] = value
+	}
+	vars["custom_labels"] = labels
+
+	if keyName := getEncryptionKeyName(master); keyName != "" {
+		vars["encryption_key_name"] = keyName
+	}
+}
+
+func (builder *onboardingBuilder) addInsightsVars() error {
+	config, err := builder.api.GetInsightsConfig(builder.options.Project, builder.master.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get the Query Insights configuration of instance %s: %v", builder.master.Name, err)
+	}
+
+	vars := builder.onboarding.Vars
+	vars["query_insights_enabled"] = config.QueryInsightsEnabled
+	if config.QueryStringLength != 0 {
+		vars["query_string_length"] = config.QueryStringLength
+	}
+	vars["record_application_tags"] = config.RecordApplicationTags
+	vars["record_client_address"] = config.RecordClientAddress
+	return nil
+}
+
+// addReplicas maps the failover replica and the read replicas of the master. The module derives the names of the
+// replicas from the name of the master, and Cloud SQL can't rename instances, so replicas with other names can't be
+// imported.
+func (builder *onboardingBuilder) addReplicas() error {
+	master := builder.master
+	vars := builder.onboarding.Vars
+	failoverName := master.Name + "-failover"
+	replicaEncryptionKeyNames := map[string]interface{}{}
+
+	enableFailoverReplica := builder.isPostgres && master.Settings.AvailabilityType == "REGIONAL"
+	if !builder.isPostgres && master.Settings.AvailabilityType == "REGIONAL" {
+		builder.addWarning("Instance %s is a regional MySQL instance, which the module doesn't support, use a failover replica instead", master.Name)
+	}
+
+	if master.FailoverReplica != nil && master.FailoverReplica.Name != "" {
+		if master.FailoverReplica.Name != failoverName {
+			builder.addWarning("Failover replica %s can't be imported, as the module names it %s", master.FailoverReplica.Name, failoverName)
+		} else {
+			replica, err := builder.getReplica(failoverName)
+			if err != nil {
+				return err
+			}
+			enableFailoverReplica = true
+			if zone := getZone(replica); zone != "" {
+				vars["mysql_failover_replica_zone"] = zone
+			}
+			if config := builder.getReplicaOverrides(replica); len(config) > 0 {
+				vars["failover_replica_config"] = config
+			}
+			builder.addReplicaEncryptionKeyName(replicaEncryptionKeyNames, replica)
+			builder.addImport(ONBOARDING_FAILOVER_REPLICA_ADDRESS, fmt.Sprintf("projects/%s/instances/%s", builder.options.Project, failoverName))
+		}
+	}
+	vars["enable_failover_replica"] = enableFailoverReplica
+
+	replicaNames := append([]string{}, master.ReplicaNames...)
+	sort.Strings(replicaNames)
+
+	readReplicas := map[string]interface{}{}
+	for _, replicaName := range replicaNames {
+		if replicaName == failoverName || (master.FailoverReplica != nil && replicaName == master.FailoverReplica.Name) {
+			continue
+		}
+		if !strings.HasPrefix(replicaName, master.Name+"-") {
+			builder.addWarning("Read replica %s can't be imported, as the module names read replicas %s-<key>", replicaName, master.Name)
+			continue
+		}
+
+		replica, err := builder.getReplica(replicaName)
+		if err != nil {
+			return err
+		}
+		key := strings.TrimPrefix(replicaName, master.Name+"-")
+		config := builder.getReplicaOverrides(replica)
+		if replica.Region != master.Region {
+			config["region"] = replica.Region
+		}
+		if zone := getZone(replica); zone != "" {
+			config["zone"] = zone
+		}
+		readReplicas[key] = config
+		builder.addReplicaEncryptionKeyName(replicaEncryptionKeyNames, replica)
+		builder.addImport(fmt.Sprintf("%s[%q]", ONBOARDING_READ_REPLICA_ADDRESS, key), fmt.Sprintf("projects/%s/instances/%s", builder.options.Project, replicaName))
+	}
+	if len(readReplicas) > 0 {
+		vars["read_replicas"] = readReplicas
+	}
+	if len(replicaEncryptionKeyNames) > 0 {
+		vars["replica_encryption_key_names"] = replicaEncryptionKeyNames
+	}
+	return nil
+}
+
+func (builder *onboardingBuilder) getReplica(name string) (*sqladmin.DatabaseInstance, error) {
+	replica, err := builder.api.GetInstance(builder.options.Project, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replica %s: %v", name, err)
+	}
+	if replica.Settings == nil {
+		replica.Settings = &sqladmin.Settings{}
+	}
+	return replica, nil
+}
+
+// getReplicaOverrides returns the settings overrides of the given replica, in the format of 'read_replica_configs' and
+// 'failover_replica_config'. Replicas inherit all flags and labels of the master, so flags and labels the replica
+// lacks can't be expressed.
+func (builder *onboardingBuilder) getReplicaOverrides(replica *sqladmin.DatabaseInstance) map[string]interface{} {
+	masterSettings := builder.master.Settings
+	settings := replica.Settings
+	config := map[string]interface{}{}
+
+	if settings.Tier != masterSettings.Tier {
+		config["machine_type"] = settings.Tier
+	}
+	if settings.DataDiskSizeGb != masterSettings.DataDiskSizeGb {
+		config["disk_size"] = settings.DataDiskSizeGb
+	}
+	if settings.DataDiskType != masterSettings.DataDiskType {
+		config["disk_type"] = settings.DataDiskType
+	}
+
+	masterFlags := map[string]string{}
+	for _, flag := range masterSettings.DatabaseFlags {
+		masterFlags[flag.Name] = flag.Value
+	}
+	flags := map[string]string{}
+	for _, flag := range settings.DatabaseFlags {
+		flags[flag.Name] = flag.Value
+	}
+	flagOverrides := []interface{}{}
+	for _, name := range unionKeys(masterFlags, flags) {
+		value, exists := flags[name]
+		if !exists {
+			builder.addWarning("Replica %s doesn't have the database flag %s of the master, which the module will add", replica.Name, name)
+		} else if masterValue, masterExists := masterFlags[name]; !masterExists || masterValue != value {
+			flagOverrides = append(flagOverrides, map[string]interface{}{"name": name, "value": value})
+		}
+	}
+	if len(flagOverrides) > 0 {
+		config["database_flags"] = flagOverrides
+	}
+
+	labelOverrides := map[string]interface{}{}
+	for _, key := range unionKeys(masterSettings.UserLabels, settings.UserLabels) {
+		value, exists := settings.UserLabels[key]
+		if !exists {
+			builder.addWarning("Replica %s doesn't have the label %s of the master, which the module will add", replica.Name, key)
+		} else if masterValue, masterExists := masterSettings.UserLabels[key]; !masterExists || masterValue != value {
+			labelOverrides[key] = value
+		}
+	}
+	if len(labelOverrides) > 0 {
+		config["custom_labels"] = labelOverrides
+	}
+	return config
+}
+
+// addReplicaEncryptionKeyName adds the key of the given replica to the given 'replica_encryption_key_names', unless the
+// module uses the key of the master for it anyway.
+func (builder *onboardingBuilder) addReplicaEncryptionKeyName(keyNames map[string]interface{}, replica *sqladmin.DatabaseInstance) {
+	keyName := getEncryptionKeyName(replica)
+	if keyName == "" {
+		if getEncryptionKeyName(builder.master) != "" {
+			builder.addWarning("Replica %s is not encrypted with a customer-managed key like the master, which the module doesn't support", replica.Name)
+		}
+		return
+	}
+	if replica.Region == builder.master.Region && keyName == getEncryptionKeyName(builder.master) {
+		return
+	}
+	if existing, exists := keyNames[replica.Region]; exists && existing != keyName {
+		builder.addWarning("Replica %s is encrypted with %s, but the module uses %s for all replicas in %s", replica.Name, keyName, existing, replica.Region)
+		return
+	}
+	keyNames[replica.Region] = keyName
+}
+
+func (builder *onboardingBuilder) addDatabases() error {
+	master := builder.master
+	databases, err := builder.api.ListDatabases(builder.options.Project, master.Name)
+	if err != nil {
+		return fmt.Errorf("failed to list the databases of instance %s: %v", master.Name, err)
+	}
+
+	systemDatabases := mySqlSystemDatabases
+	if builder.isPostgres {
+		systemDatabases = postgresSystemDatabases
+	}
+	databasesByName := map[string]*sqladmin.Database{}
+	names := []string{}
+	for _, database := range databases {
+		if containsString(systemDatabases, database.Name) {
+			continue
+		}
+		databasesByName[database.Name] = database
+		names = append(names, database.Name)
+	}
+	sort.Strings(names)
+
+	defaultName := builder.options.DatabaseName
+	if defaultName == "" {
+		if len(names) == 0 {
+			return fmt.Errorf("instance %s has no databases, but the module needs one for db_name", master.Name)
+		}
+		defaultName = names[0]
+	}
+	defaultDatabase, exists := databasesByName[defaultName]
+	if !exists {
+		return fmt.Errorf("instance %s has no database %s", master.Name, defaultName)
+	}
+
+	vars := builder.onboarding.Vars
+	vars["db_name"] = defaultName
+	if defaultDatabase.Charset != "" {
+		vars["db_charset"] = defaultDatabase.Charset
+	}
+	if defaultDatabase.Collation != "" {
+		vars["db_collation"] = defaultDatabase.Collation
+	}
+	builder.addImport(ONBOARDING_DATABASE_ADDRESS, getDatabaseImportId(builder.options.Project, master.Name, defaultName))
+
+	additionalDatabases := map[string]interface{}{}
+	for _, name := range names {
+		if name == defaultName {
+			continue
+		}
+		config := map[string]interface{}{}
+		if databasesByName[name].Charset != "" {
+			config["charset"] = databasesByName[name].Charset
+		}
+		if databasesByName[name].Collation != "" {
+			config["collation"] = databasesByName[name].Collation
+		}
+		additionalDatabases[name] = config
+		builder.addImport(fmt.Sprintf("%s[%q]", ONBOARDING_ADDITIONAL_DATABASE_ADDRESS, name), getDatabaseImportId(builder.options.Project, master.Name, name))
+	}
+	if len(additionalDatabases) > 0 {
+		vars["additional_databases"] = additionalDatabases
+	}
+	return nil
+}
+
+// addUsers maps the built-in users to the master user and the additional users, and the IAM users to 'iam_users'. It
+// returns whether there are IAM users.
+func (builder *onboardingBuilder) addUsers() (bool, error) {
+	master := builder.master
+	users, err := builder.api.ListUsers(builder.options.Project, master.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to list the users of instance %s: %v", master.Name, err)
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		if users[i].Name != users[j].Name {
+			return users[i].Name < users[j].Name
+		}
+		return users[i].Host < users[j].Host
+	})
+
+	// The master user may be one of the users Cloud SQL creates, like root, but only if it is chosen explicitly
+	builtInUsers := []*User{}
+	iamUsers := []*User{}
+	var masterUser *User
+	for _, user := range users {
+		switch {
+		case user.Type == IAM_USER_TYPE_USER || user.Type == IAM_USER_TYPE_SERVICE_ACCOUNT:
+			iamUsers = append(iamUsers, user)
+		case builder.options.MasterUserName != "" && user.Name == builder.options.MasterUserName && masterUser == nil:
+			masterUser = user
+		case !systemUserRegexp.MatchString(user.Name):
+			builtInUsers = append(builtInUsers, user)
+		}
+	}
+	if masterUser == nil {
+		if builder.options.MasterUserName != "" {
+			return false, fmt.Errorf("instance %s has no user %s", master.Name, builder.options.MasterUserName)
+		}
+		if len(builtInUsers) == 0 {
+			return false, fmt.Errorf("instance %s has no users, but the module needs one for master_user_name", master.Name)
+		}
+		masterUser, builtInUsers = builtInUsers[0], builtInUsers[1:]
+	}
+
+	vars := builder.onboarding.Vars
+	vars["master_user_name"] = masterUser.Name
+	if !builder.isPostgres {
+		vars["master_user_host"] = masterUser.Host
+	}
+	builder.addImport(ONBOARDING_USER_ADDRESS, builder.getUserImportId(masterUser))
+
+	additionalUsers := map[string]interface{}{}
+	for _, user := range builtInUsers {
+		if _, exists := additionalUsers[user.Name]; exists || user.Name == masterUser.Name {
+			builder.addWarning("User %s@%s can't be imported, as the module only supports one host per user name", user.Name, user.Host)
+			continue
+		}
+		config := map[string]interface{}{"password": ONBOARDING_PASSWORD_PLACEHOLDER}
+		if !builder.isPostgres {
+			config["host"] = user.Host
+		}
+		additionalUsers[user.Name] = config
+		builder.addImport(fmt.Sprintf("%s[%q]", ONBOARDING_ADDITIONAL_USER_ADDRESS, user.Name), builder.getUserImportId(user))
+	}
+	if len(additionalUsers) > 0 {
+		vars["additional_users"] = additionalUsers
+	}
+
+	// Postgres uses the email of IAM users as their name, so it can be recovered. MySQL drops the domain of the email.
+	iamUserVars := []interface{}{}
+	for _, user := range iamUsers {
+		if !builder.isPostgres {
+			builder.addWarning("IAM user %s can't be imported, as the module needs its email, add it to iam_users manually", user.Name)
+			continue
+		}
+		email := user.Name
+		if user.Type == IAM_USER_TYPE_SERVICE_ACCOUNT {
+			email += SERVICE_ACCOUNT_EMAIL_SUFFIX
+		}
+		iamUserVars = append(iamUserVars, map[string]interface{}{"email": email, "type": user.Type})
+		builder.addImport(fmt.Sprintf("%s[%q]", ONBOARDING_IAM_USER_ADDRESS, email), builder.getUserImportId(user))
+	}
+	if len(iamUserVars) > 0 {
+		vars["iam_users"] = iamUserVars
+	}
+	return len(iamUserVars) > 0, nil
+}
+
+// getUserImportId returns the import ID of the given user, which includes the host for MySQL users only.
+func (builder *onboardingBuilder) getUserImportId(user *User) string {
+	if builder.isPostgres {
+		return fmt.Sprintf("%s/%s/%s", builder.options.Project, builder.master.Name, user.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", builder.options.Project, builder.master.Name, user.Host, user.Name)
+}
+
+func getDatabaseImportId(project string, instance string, database string) string {
+	return fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, database)
+}
+
+func getEncryptionKeyName(instance *sqladmin.DatabaseInstance) string {
+	if instance.DiskEncryptionConfiguration == nil {
+		return ""
+	}
+	return instance.DiskEncryptionConfiguration.KmsKeyName
+}
+
+func getZone(instance *sqladmin.DatabaseInstance) string {
+	if instance.Settings == nil || instance.Settings.LocationPreference == nil {
+		return ""
+	}
+	return instance.Settings.LocationPreference.Zone
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTfvars writes the module inputs of the given onboarding as a tfvars file, sorted by name.
+func WriteTfvars(writer io.Writer, onboarding *Onboarding) error {
+	var builder strings.Builder
+	builder.WriteString("# Generated by cloud-sql-onboard. The Admin API doesn't return passwords, so set the master user password\n")
+	builder.WriteString("# with TF_VAR_master_user_password and replace the placeholder passwords of the additional users before\n")
+	builder.WriteString("# applying, or the passwords of those users will be changed.\n")
+
+	names := []string{}
+	for name := range onboarding.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		builder.WriteString("\n")
+		builder.WriteString(name)
+		builder.WriteString(" = ")
+		writeHclValue(&builder, onboarding.Vars[name], "")
+		builder.WriteString("\n")
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// WriteImportScript writes a shell script that runs `terraform import` for each resource of the given onboarding.
+func WriteImportScript(writer io.Writer, onboarding *Onboarding) error {
+	var builder strings.Builder
+	builder.WriteString("#!/usr/bin/env bash\n")
+	builder.WriteString("# Generated by cloud-sql-onboard. Run this in the Terraform working directory after `terraform init`.\n")
+	builder.WriteString("set -e\n\n")
+	for _, resource := range onboarding.Imports {
+		builder.WriteString(fmt.Sprintf("terraform import %s %s\n", shellQuote(resource.Address), shellQuote(resource.ID)))
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func writeHclValue(builder *strings.Builder, value interface{}, indent string) {
+	switch typed := value.(type) {
+	case string:
+		builder.WriteString(quoteHclString(typed))
+	case bool:
+		builder.WriteString(strconv.FormatBool(typed))
+	case int64:
+		builder.WriteString(strconv.FormatInt(typed, 10))
+	case []interface{}:
+		if len(typed) == 0 {
+			builder.WriteString("[]")
+			return
+		}
+		builder.WriteString("[\n")
+		for _, element := range typed {
+			builder.WriteString(indent + "  ")
+			writeHclValue(builder, element, indent+"  ")
+			builder.WriteString(",\n")
+		}
+		builder.WriteString(indent + "]")
+	case map[string]interface{}:
+		if len(typed) == 0 {
+			builder.WriteString("{}")
+			return
+		}
+		keys := []string{}
+		width := 0
+		for key := range typed {
+			keys = append(keys, key)
+			if len(quoteHclKey(key)) > width {
+				width = len(quoteHclKey(key))
+			}
+		}
+		sort.Strings(keys)
+
+		builder.WriteString("{\n")
+		for _, key := range keys {
+			builder.WriteString(fmt.Sprintf("%s  %-*s = ", indent, width, quoteHclKey(key)))
+			writeHclValue(builder, typed[key], indent+"  ")
+			builder.WriteString("\n")
+		}
+		builder.WriteString(indent + "}")
+	default:
+		builder.WriteString(quoteHclString(fmt.Sprint(typed)))
+	}
+}
+
+func quoteHclKey(key string) string {
+	if hclIdentifierRegexp.MatchString(key) {
+		return key
+	}
+	return quoteHclString(key)
+}
+
+// quoteHclString quotes the given string and escapes template sequences, so it is used literally.
+func quoteHclString(value string) string {
+	quoted := strconv.Quote(value)
+	quoted = strings.Replace(quoted, "${", "$${", -1)
+	return strings.Replace(quoted, "%{", "%%{", -1)
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,270 @@
+package cloudsql
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+const TEST_ONBOARDING_KEY_NAME = "projects/my-project/locations/us-central1/keyRings/ring/cryptoKeys/key"
+const TEST_ONBOARDING_DR_KEY_NAME = "projects/my-project/locations/us-east1/keyRings/ring/cryptoKeys/key"
+
+func newTestOnboardingInstance(name string, region string, zone string, keyName string) *sqladmin.DatabaseInstance {
+	autoResize := true
+	return &sqladmin.DatabaseInstance{
+		Project:                     "my-project",
+		Name:                        name,
+		Region:                      region,
+		DatabaseVersion:             "MYSQL_5_7",
+		DiskEncryptionConfiguration: &sqladmin.DiskEncryptionConfiguration{KmsKeyName: keyName},
+		Settings: &sqladmin.Settings{
+			Tier:               "db-f1-micro",
+			ActivationPolicy:   "ALWAYS",
+			AvailabilityType:   "ZONAL",
+			StorageAutoResize:  &autoResize,
+			DataDiskSizeGb:     10,
+			DataDiskType:       "PD_SSD",
+			LocationPreference: &sqladmin.LocationPreference{Zone: zone},
+			IpConfiguration: &sqladmin.IpConfiguration{
+				Ipv4Enabled:        true,
+				AuthorizedNetworks: []*sqladmin.AclEntry{{Name: "allow-all-inbound", Value: "0.0.0.0/0"}},
+			},
+			BackupConfiguration: &sqladmin.BackupConfiguration{Enabled: true, StartTime: "04:00", BinaryLogEnabled: true},
+			MaintenanceWindow:   &sqladmin.MaintenanceWindow{Day: 7, Hour: 3, UpdateTrack: "stable"},
+			DatabaseFlags:       []*sqladmin.DatabaseFlags{{Name: "auto_increment_increment", Value: "7"}},
+			UserLabels:          map[string]string{"team": "data"},
+		},
+	}
+}
+
+// newTestOnboardingAPI returns a MySQL master with a failover replica, a read replica in the same region, one in
+// another region with a bigger machine type, and one that doesn't follow the naming scheme of the module
+func newTestOnboardingAPI() *FakeAdminAPI {
+	master := newTestOnboardingInstance("orders", "us-central1", "us-central1-a", TEST_ONBOARDING_KEY_NAME)
+	master.FailoverReplica = &sqladmin.DatabaseInstanceFailoverReplica{Name: "orders-failover"}
+	master.ReplicaNames = []string{"orders-read-0", "orders-failover", "orders-dr", "legacy-replica"}
+
+	failover := newTestOnboardingInstance("orders-failover", "us-central1", "us-central1-b", TEST_ONBOARDING_KEY_NAME)
+	readReplica := newTestOnboardingInstance("orders-read-0", "us-central1", "us-central1-c", TEST_ONBOARDING_KEY_NAME)
+
+	drReplica := newTestOnboardingInstance("orders-dr", "us-east1", "us-east1-b", TEST_ONBOARDING_DR_KEY_NAME)
+	drReplica.Settings.Tier = "db-g1-small"
+	drReplica.Settings.DatabaseFlags = append(drReplica.Settings.DatabaseFlags, &sqladmin.DatabaseFlags{Name: "long_query_time", Value: "10"})
+	drReplica.Settings.UserLabels = map[string]string{}
+
+	for _, replica := range []*sqladmin.DatabaseInstance{failover, readReplica, drReplica} {
+		replica.MasterInstanceName = "orders"
+	}
+
+	api := NewFakeAdminAPI(master, failover, readReplica, drReplica)
+	api.PutInsightsConfig("my-project", "orders", InsightsConfig{QueryInsightsEnabled: true, QueryStringLength: 2048})
+	for _, database := range []string{"orders_app", "mysql", "orders", "sys"} {
+		api.PutDatabase(&sqladmin.Database{Project: "my-project", Instance: "orders", Name: database, Charset: "utf8"})
+	}
+	api.PutUser("my-project", "orders", User{Name: "root", Host: "%"})
+	api.PutUser("my-project", "orders", User{Name: "reporting", Host: "10.0.0.%"})
+	api.PutUser("my-project", "orders", User{Name: "admin", Host: "%"})
+	api.PutUser("my-project", "orders", User{Name: "admin", Host: "localhost"})
+	api.PutUser("my-project", "orders", User{Name: "jane", Host: "%", Type: IAM_USER_TYPE_USER})
+	return api
+}
+
+func TestGenerateOnboardingMySql(t *testing.T) {
+	t.Parallel()
+
+	onboarding, err := GenerateOnboarding(newTestOnboardingAPI(), OnboardingOptions{Project: "my-project", Instance: "orders", DatabaseName: "orders", ModuleAddress: "module.orders"})
+	require.NoError(t, err)
+
+	vars := onboarding.Vars
+	assert.Equal(t, "orders", vars["name"])
+	assert.Equal(t, "MYSQL_5_7", vars["engine"])
+	assert.Equal(t, "us-central1-a", vars["master_zone"])
+	assert.Equal(t, int64(10), vars["disk_size"])
+	assert.Equal(t, true, vars["mysql_binary_log_enabled"])
+	assert.NotContains(t, vars, "postgres_point_in_time_recovery_enabled")
+	assert.Equal(t, int64(3), vars["maintenance_window_hour"])
+	assert.Equal(t, int64(2048), vars["query_string_length"])
+	assert.Equal(t, TEST_ONBOARDING_KEY_NAME, vars["encryption_key_name"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "allow-all-inbound", "value": "0.0.0.0/0"}}, vars["authorized_networks"])
+
+	// DATABASES AND USERS
+	assert.Equal(t, "orders", vars["db_name"])
+	assert.Equal(t, map[string]interface{}{"orders_app": map[string]interface{}{"charset": "utf8"}}, vars["additional_databases"])
+	assert.Equal(t, "admin", vars["master_user_name"])
+	assert.Equal(t, "%", vars["master_user_host"])
+	assert.Equal(t, map[string]interface{}{"reporting": map[string]interface{}{"host": "10.0.0.%", "password": ONBOARDING_PASSWORD_PLACEHOLDER}}, vars["additional_users"])
+	assert.NotContains(t, vars, "iam_users")
+
+	// REPLICAS
+	assert.Equal(t, true, vars["enable_failover_replica"])
+	assert.Equal(t, "us-central1-b", vars["mysql_failover_replica_zone"])
+	assert.NotContains(t, vars, "failover_replica_config")
+	assert.Equal(t, map[string]interface{}{
+		"read-0": map[string]interface{}{"zone": "us-central1-c"},
+		"dr": map[string]interface{}{
+			"region":         "us-east1",
+			"zone":           "us-east1-b",
+			"machine_type":   "db-g1-small",
+			"database_flags": []interface{}{map[string]interface{}{"name": "long_query_time", "value": "10"}},
+		},
+	}, vars["read_replicas"])
+	assert.Equal(t, map[string]interface{}{"us-east1": TEST_ONBOARDING_DR_KEY_NAME}, vars["replica_encryption_key_names"])
+
+	assert.Equal(t, []ImportAddress{
+		{`module.orders.google_sql_database_instance.master`, "projects/my-project/instances/orders"},
+		{`module.orders.google_sql_user.default`, "my-project/orders/%/admin"},
+		{`module.orders.google_sql_user.additional["reporting"]`, "my-project/orders/10.0.0.%/reporting"},
+		{`module.orders.google_sql_database.default`, "projects/my-project/instances/orders/databases/orders"},
+		{`module.orders.google_sql_database.additional["orders_app"]`, "projects/my-project/instances/orders/databases/orders_app"},
+		{`module.orders.google_sql_database_instance.failover_replica[0]`, "projects/my-project/instances/orders-failover"},
+		{`module.orders.google_sql_database_instance.read_replica["dr"]`, "projects/my-project/instances/orders-dr"},
+		{`module.orders.google_sql_database_instance.read_replica["read-0"]`, "projects/my-project/instances/orders-read-0"},
+	}, onboarding.Imports)
+
+	assert.Equal(t, []string{
+		"User admin@localhost can't be imported, as the module only supports one host per user name",
+		"IAM user jane can't be imported, as the module needs its email, add it to iam_users manually",
+		"Read replica legacy-replica can't be imported, as the module names read replicas orders-<key>",
+		"Replica orders-dr doesn't have the label team of the master, which the module will add",
+	}, onboarding.Warnings)
+}
+
+func TestGenerateOnboardingPostgres(t *testing.T) {
+	t.Parallel()
+
+	master := newTestOnboardingInstance("billing", "us-central1", "", "")
+	master.DatabaseVersion = "POSTGRES_14"
+	master.Settings.AvailabilityType = "REGIONAL"
+	master.Settings.BackupConfiguration.BinaryLogEnabled = false
+	master.Settings.BackupConfiguration.PointInTimeRecoveryEnabled = true
+	master.Settings.MaintenanceWindow = nil
+	master.Settings.DatabaseFlags = []*sqladmin.DatabaseFlags{{Name: "cloudsql.iam_authentication", Value: "on"}}
+
+	api := NewFakeAdminAPI(master)
+	api.PutDatabase(&sqladmin.Database{Project: "my-project", Instance: "billing", Name: "postgres"})
+	api.PutDatabase(&sqladmin.Database{Project: "my-project", Instance: "billing", Name: "billing", Charset: "UTF8", Collation: "en_US.UTF8"})
+	api.PutUser("my-project", "billing", User{Name: "postgres"})
+	api.PutUser("my-project", "billing", User{Name: "billing"})
+	api.PutUser("my-project", "billing", User{Name: "jane@example.com", Type: IAM_USER_TYPE_USER})
+	api.PutUser("my-project", "billing", User{Name: "app@my-project.iam", Type: IAM_USER_TYPE_SERVICE_ACCOUNT})
+
+	onboarding, err := GenerateOnboarding(api, OnboardingOptions{Project: "my-project", Instance: "billing"})
+	require.NoError(t, err)
+
+	vars := onboarding.Vars
+	assert.Equal(t, true, vars["enable_failover_replica"])
+	assert.Equal(t, true, vars["postgres_point_in_time_recovery_enabled"])
+	assert.NotContains(t, vars, "mysql_binary_log_enabled")
+	assert.NotContains(t, vars, "master_zone")
+	assert.NotContains(t, vars, "master_user_host")
+	assert.NotContains(t, vars, "encryption_key_name")
+	assert.NotContains(t, vars, "maintenance_window_day")
+	assert.Equal(t, "en_US.UTF8", vars["db_collation"])
+	assert.Equal(t, "billing", vars["master_user_name"])
+	assert.Equal(t, []interface{}{}, vars["database_flags"], "The module adds the IAM authentication flag itself")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"email": "app@my-project.iam.gserviceaccount.com", "type": IAM_USER_TYPE_SERVICE_ACCOUNT},
+		map[string]interface{}{"email": "jane@example.com", "type": IAM_USER_TYPE_USER},
+	}, vars["iam_users"])
+
+	assert.Equal(t, []ImportAddress{
+		{`google_sql_database_instance.master`, "projects/my-project/instances/billing"},
+		{`google_sql_user.default`, "my-project/billing/billing"},
+		{`google_sql_user.iam["app@my-project.iam.gserviceaccount.com"]`, "my-project/billing/app@my-project.iam"},
+		{`google_sql_user.iam["jane@example.com"]`, "my-project/billing/jane@example.com"},
+		{`google_sql_database.default`, "projects/my-project/instances/billing/databases/billing"},
+	}, onboarding.Imports)
+	assert.Equal(t, []string{"Instance billing has no maintenance window, the module will set the default maintenance window"}, onboarding.Warnings)
+}
+
+func TestGenerateOnboardingErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		options OnboardingOptions
+	}{
+		{"MissingInstance", OnboardingOptions{Project: "my-project", Instance: "missing"}},
+		{"Replica", OnboardingOptions{Project: "my-project", Instance: "orders-read-0"}},
+		{"MissingDatabase", OnboardingOptions{Project: "my-project", Instance: "orders", DatabaseName: "missing"}},
+		{"MissingMasterUser", OnboardingOptions{Project: "my-project", Instance: "orders", MasterUserName: "missing"}},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := GenerateOnboarding(newTestOnboardingAPI(), testCase.options)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWriteTfvars(t *testing.T) {
+	t.Parallel()
+
+	onboarding := &Onboarding{Vars: map[string]interface{}{
+		"name":                "orders",
+		"disk_size":           int64(10),
+		"require_ssl":         false,
+		"database_flags":      []interface{}{},
+		"additional_users":    map[string]interface{}{"app": map[string]interface{}{"host": "%", "password": "${secret}"}},
+		"custom_labels":       map[string]interface{}{"team": "data", "cost.center": "42"},
+		"authorized_networks": []interface{}{map[string]interface{}{"name": "office", "value": "203.0.113.0/24"}},
+	}}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteTfvars(&buffer, onboarding))
+	assert.Contains(t, buffer.String(), `
+additional_users = {
+  app = {
+    host     = "%"
+    password = "$${secret}"
+  }
+}
+
+authorized_networks = [
+  {
+    name  = "office"
+    value = "203.0.113.0/24"
+  },
+]
+
+custom_labels = {
+  "cost.center" = "42"
+  team          = "data"
+}
+
+database_flags = []
+
+disk_size = 10
+
+name = "orders"
+
+require_ssl = false
+`)
+}
+
+func TestWriteImportScript(t *testing.T) {
+	t.Parallel()
+
+	onboarding := &Onboarding{Imports: []ImportAddress{
+		{`google_sql_database_instance.read_replica["read-0"]`, "projects/my-project/instances/orders-read-0"},
+		{`google_sql_user.default`, "my-project/orders/%/o'brien"},
+	}}
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteImportScript(&buffer, onboarding))
+	assert.Contains(t, buffer.String(), `set -e
+
+terraform import 'google_sql_database_instance.read_replica["read-0"]' 'projects/my-project/instances/orders-read-0'
+terraform import 'google_sql_user.default' 'my-project/orders/%/o'\''brien'
+`)
+}
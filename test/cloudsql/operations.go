@@ -0,0 +1,45 @@
+package cloudsql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// OPERATION_STATUS_DONE is the status of an operation that finished, successfully or not.
+const OPERATION_STATUS_DONE = "DONE"
+
+// WaitForOperation polls the given operation every pollInterval until it is done, and returns an error if it failed or
+// didn't finish within the given timeout.
+func WaitForOperation(api AdminAPI, project string, operation *sqladmin.Operation, timeout time.Duration, pollInterval time.Duration) (*sqladmin.Operation, error) {
+	deadline := time.Now().Add(timeout)
+
+	for operation.Status != OPERATION_STATUS_DONE {
+		if time.Now().After(deadline) {
+			return operation, fmt.Errorf("operation %s on %s is still %s after %s", operation.Name, operation.TargetId, operation.Status, timeout)
+		}
+		time.Sleep(pollInterval)
+
+		var err error
+		if operation, err = api.GetOperation(project, operation.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return operation, OperationError(operation)
+}
+
+// OperationError returns an error describing the errors of the given finished operation, or nil if it succeeded.
+func OperationError(operation *sqladmin.Operation) error {
+	if operation.Error == nil || len(operation.Error.Errors) == 0 {
+		return nil
+	}
+
+	messages := []string{}
+	for _, operationError := range operation.Error.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", operationError.Code, operationError.Message))
+	}
+	return fmt.Errorf("operation %s on %s failed: %s", operation.Name, operation.TargetId, strings.Join(messages, ", "))
+}
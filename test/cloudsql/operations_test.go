@@ -0,0 +1,59 @@
+package cloudsql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+func TestPromoteReplicaAndWaitForOperation(t *testing.T) {
+	t.Parallel()
+
+	api := NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "master", InstanceType: "CLOUD_SQL_INSTANCE"},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "master-read-0", InstanceType: "READ_REPLICA_INSTANCE", MasterInstanceName: "my-project:master"},
+	)
+
+	operation, err := api.PromoteReplica("my-project", "master-read-0")
+	require.NoError(t, err)
+
+	operation, err = WaitForOperation(api, "my-project", operation, time.Second, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "PROMOTE_REPLICA", operation.OperationType)
+
+	instance, err := api.GetInstance("my-project", "master-read-0")
+	require.NoError(t, err)
+	assert.Empty(t, instance.MasterInstanceName)
+	assert.Equal(t, "CLOUD_SQL_INSTANCE", instance.InstanceType)
+
+	_, err = api.PromoteReplica("my-project", "master")
+	assert.Error(t, err, "Expected promoting a standalone instance to fail")
+
+	_, err = api.PromoteReplica("my-project", "missing")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestWaitForOperationFailures(t *testing.T) {
+	t.Parallel()
+
+	api := NewFakeAdminAPI()
+	api.PutOperation("my-project", &sqladmin.Operation{Name: "running", Status: "RUNNING"})
+	api.PutOperation("my-project", &sqladmin.Operation{
+		Name:     "failed",
+		TargetId: "master-read-0",
+		Status:   OPERATION_STATUS_DONE,
+		Error:    &sqladmin.OperationErrors{Errors: []*sqladmin.OperationError{{Code: "INTERNAL_ERROR", Message: "boom"}}},
+	})
+
+	_, err := WaitForOperation(api, "my-project", &sqladmin.Operation{Name: "running", Status: "PENDING"}, 10*time.Millisecond, time.Millisecond)
+	assert.Error(t, err, "Expected a timeout")
+
+	_, err = WaitForOperation(api, "my-project", &sqladmin.Operation{Name: "failed", Status: "RUNNING"}, time.Second, time.Millisecond)
+	assert.EqualError(t, err, "operation failed on master-read-0 failed: INTERNAL_ERROR: boom")
+
+	_, err = WaitForOperation(api, "my-project", &sqladmin.Operation{Name: "missing", Status: "RUNNING"}, time.Second, time.Millisecond)
+	assert.True(t, IsNotFound(err))
+}
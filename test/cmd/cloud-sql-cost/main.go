@@ -0,0 +1,106 @@
+// Command cloud-sql-cost estimates the monthly cost of the Cloud SQL instances in a Terraform plan, so reviewers can see
+// the cost of changing the machine type, disk size, number of read replicas or high availability. It prices each
+// instance with the rate table bundled with the cost package, or the one passed with -rates.
+//
+// Usage:
+//
+//	terraform plan -out tfplan && terraform show -json tfplan > plan.json
+//	cloud-sql-cost -plan plan.json [-rates <rate table>] [-budget <monthly budget>] [-output human|json]
+//
+// The command exits with 0 if the estimate is within the budget, 2 if it exceeds the budget and 1 on errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cost"
+)
+
+const OUTPUT_HUMAN = "human"
+const OUTPUT_JSON = "json"
+
+const EXIT_CODE_WITHIN_BUDGET = 0
+const EXIT_CODE_ERROR = 1
+const EXIT_CODE_OVER_BUDGET = 2
+
+func main() {
+	planPath := flag.String("plan", "-", "A file with the output of `terraform show -json <plan file>`, or - for stdin")
+	ratesPath := flag.String("rates", "", "A rate table to use instead of the bundled one")
+	budget := flag.Float64("budget", 0, "The monthly budget, the command exits with 2 if the estimate exceeds it. 0 disables the check")
+	output := flag.String("output", OUTPUT_HUMAN, "The output format, either human or json")
+	flag.Parse()
+
+	os.Exit(run(*planPath, *ratesPath, *budget, *output, os.Stdout, os.Stderr))
+}
+
+func run(planPath string, ratesPath string, budget float64, output string, stdout io.Writer, stderr io.Writer) int {
+	if output != OUTPUT_HUMAN && output != OUTPUT_JSON {
+		fmt.Fprintf(stderr, "Unknown output format %q, expected %s or %s\n", output, OUTPUT_HUMAN, OUTPUT_JSON)
+		return EXIT_CODE_ERROR
+	}
+
+	rates := cost.DefaultRateTable()
+	if ratesPath != "" {
+		ratesFile, err := os.Open(ratesPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to read the rate table: %v\n", err)
+			return EXIT_CODE_ERROR
+		}
+		defer ratesFile.Close()
+
+		rates, err = cost.LoadRateTable(ratesFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return EXIT_CODE_ERROR
+		}
+	}
+
+	planReader := io.Reader(os.Stdin)
+	if planPath != "-" {
+		planFile, err := os.Open(planPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to read the plan: %v\n", err)
+			return EXIT_CODE_ERROR
+		}
+		defer planFile.Close()
+		planReader = planFile
+	}
+
+	return estimate(planReader, rates, budget, output, stdout, stderr)
+}
+
+// estimate writes the estimate of the plan read from the given reader and returns the exit code.
+func estimate(planReader io.Reader, rates *cost.RateTable, budget float64, output string, stdout io.Writer, stderr io.Writer) int {
+	plan, err := cost.ParsePlan(planReader)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_CODE_ERROR
+	}
+
+	estimate, err := cost.EstimatePlan(plan, rates)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_CODE_ERROR
+	}
+
+	if output == OUTPUT_JSON {
+		err = cost.WriteEstimateJSON(stdout, estimate)
+	} else {
+		err = cost.WriteEstimateHuman(stdout, estimate)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to write the estimate: %v\n", err)
+		return EXIT_CODE_ERROR
+	}
+
+	if budget > 0 {
+		if err := cost.CheckBudget(estimate, budget); err != nil {
+			fmt.Fprintln(stderr, err)
+			return EXIT_CODE_OVER_BUDGET
+		}
+	}
+	return EXIT_CODE_WITHIN_BUDGET
+}
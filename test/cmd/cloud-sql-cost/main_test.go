@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cost"
+	"github.com/stretchr/testify/assert"
+)
+
+const TEST_PLAN_JSON = `{
+  "format_version": "0.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "google_sql_database_instance.master",
+          "mode": "managed",
+          "type": "google_sql_database_instance",
+          "name": "master",
+          "values": {"name": "mysql", "region": "us-central1", "settings": [{"tier": "db-f1-micro", "disk_size": 10, "disk_type": "PD_SSD"}]}
+        }
+      ]
+    }
+  }
+}`
+
+func TestEstimate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		budget           float64
+		output           string
+		expectedExitCode int
+		expectedOutput   string
+	}{
+		{"NoBudget", 0, OUTPUT_HUMAN, EXIT_CODE_WITHIN_BUDGET, "Estimated total: 9.37 USD per month"},
+		{"WithinBudget", 10, OUTPUT_JSON, EXIT_CODE_WITHIN_BUDGET, `"total_monthly": 9.365`},
+		{"OverBudget", 5, OUTPUT_HUMAN, EXIT_CODE_OVER_BUDGET, "google_sql_database_instance.master"},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			exitCode := estimate(strings.NewReader(TEST_PLAN_JSON), cost.DefaultRateTable(), testCase.budget, testCase.output, &stdout, &stderr)
+			assert.Equal(t, testCase.expectedExitCode, exitCode, stderr.String())
+			assert.Contains(t, stdout.String(), testCase.expectedOutput)
+		})
+	}
+}
+
+func TestRunUnknownOutput(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, EXIT_CODE_ERROR, run("-", "", 0, "yaml", &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "yaml")
+}
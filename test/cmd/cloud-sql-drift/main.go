@@ -0,0 +1,119 @@
+// Command cloud-sql-drift compares the Cloud SQL instances in a Terraform state with the live instances reported by the
+// Cloud SQL Admin API, and reports settings that were changed outside of Terraform, e.g. in the console. It compares the
+// machine type, database flags, authorized networks, backup configuration and labels.
+//
+// Usage:
+//
+//	cloud-sql-drift [-dir <terraform dir> | -state <file>] [-output human|json]
+//
+// The command exits with 0 if there is no drift, 2 if there is drift and 1 on errors, like `terraform plan
+// -detailed-exitcode`.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+)
+
+const OUTPUT_HUMAN = "human"
+const OUTPUT_JSON = "json"
+
+const EXIT_CODE_NO_DRIFT = 0
+const EXIT_CODE_ERROR = 1
+const EXIT_CODE_DRIFT = 2
+
+func main() {
+	dir := flag.String("dir", ".", "The Terraform working directory to read the state from with `terraform show -json`")
+	statePath := flag.String("state", "", "A file with the output of `terraform show -json` to read instead of running Terraform, or - for stdin")
+	output := flag.String("output", OUTPUT_HUMAN, "The output format, either human or json")
+	flag.Parse()
+
+	os.Exit(run(*dir, *statePath, *output, os.Stdout, os.Stderr))
+}
+
+func run(dir string, statePath string, output string, stdout io.Writer, stderr io.Writer) int {
+	if output != OUTPUT_HUMAN && output != OUTPUT_JSON {
+		fmt.Fprintf(stderr, "Unknown output format %q, expected %s or %s\n", output, OUTPUT_HUMAN, OUTPUT_JSON)
+		return EXIT_CODE_ERROR
+	}
+
+	stateReader, err := openState(dir, statePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read the Terraform state: %v\n", err)
+		return EXIT_CODE_ERROR
+	}
+	defer stateReader.Close()
+
+	api, err := cloudsql.NewAdminAPI(context.Background())
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create Cloud SQL Admin API client: %v\n", err)
+		return EXIT_CODE_ERROR
+	}
+
+	return detectDrift(api, stateReader, output, stdout, stderr)
+}
+
+// detectDrift reports the drift between the state read from the given reader and the instances returned by the given
+// API, and returns the exit code.
+func detectDrift(api cloudsql.AdminAPI, stateReader io.Reader, output string, stdout io.Writer, stderr io.Writer) int {
+	state, err := cloudsql.ParseState(stateReader)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_CODE_ERROR
+	}
+
+	instances, err := cloudsql.GetStateInstances(state)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_CODE_ERROR
+	}
+
+	drifts, err := cloudsql.DetectDrift(api, instances)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to compare the instances with the Admin API: %v\n", err)
+		return EXIT_CODE_ERROR
+	}
+
+	if output == OUTPUT_JSON {
+		err = cloudsql.WriteDriftJSON(stdout, drifts)
+	} else {
+		err = cloudsql.WriteDriftHuman(stdout, drifts)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to write the report: %v\n", err)
+		return EXIT_CODE_ERROR
+	}
+
+	if len(drifts) > 0 {
+		return EXIT_CODE_DRIFT
+	}
+	return EXIT_CODE_NO_DRIFT
+}
+
+// openState returns a reader for the JSON state, either from the given file, from stdin, or by running `terraform show
+// -json` in the given directory.
+func openState(dir string, statePath string) (io.ReadCloser, error) {
+	switch statePath {
+	case "-":
+		return os.Stdin, nil
+	case "":
+		cmd := exec.Command("terraform", "show", "-json")
+		cmd.Dir = dir
+		cmd.Stderr = os.Stderr
+		stateJSON, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("terraform show -json failed in %s: %v", dir, err)
+		}
+		return ioutil.NopCloser(bytes.NewReader(stateJSON)), nil
+	default:
+		return os.Open(statePath)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+const TEST_STATE_JSON = `{
+  "format_version": "0.2",
+  "values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "google_sql_database_instance.master",
+          "mode": "managed",
+          "type": "google_sql_database_instance",
+          "name": "master",
+          "values": {"project": "my-project", "name": "mysql", "settings": [{"tier": "db-f1-micro"}]}
+        }
+      ]
+    }
+  }
+}`
+
+func TestDetectDrift(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		liveTier         string
+		output           string
+		expectedExitCode int
+		expectedOutput   string
+	}{
+		{"NoDriftHuman", "db-f1-micro", OUTPUT_HUMAN, EXIT_CODE_NO_DRIFT, "No drift detected."},
+		{"DriftHuman", "db-g1-small", OUTPUT_HUMAN, EXIT_CODE_DRIFT, `tier: state "db-f1-micro", actual "db-g1-small"`},
+		{"DriftJSON", "db-g1-small", OUTPUT_JSON, EXIT_CODE_DRIFT, `"drifted": true`},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			api := cloudsql.NewFakeAdminAPI(&sqladmin.DatabaseInstance{Project: "my-project", Name: "mysql", Settings: &sqladmin.Settings{Tier: testCase.liveTier}})
+
+			var stdout, stderr bytes.Buffer
+			exitCode := detectDrift(api, strings.NewReader(TEST_STATE_JSON), testCase.output, &stdout, &stderr)
+			assert.Equal(t, testCase.expectedExitCode, exitCode, stderr.String())
+			assert.Contains(t, stdout.String(), testCase.expectedOutput)
+
+			if testCase.output == OUTPUT_JSON {
+				report := cloudsql.DriftReport{}
+				require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+				assert.Len(t, report.Drifts, 1)
+			}
+		})
+	}
+}
+
+func TestDetectDriftInvalidState(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	exitCode := detectDrift(cloudsql.NewFakeAdminAPI(), strings.NewReader(`{"format_version": "9.9"}`), OUTPUT_HUMAN, &stdout, &stderr)
+	assert.Equal(t, EXIT_CODE_ERROR, exitCode)
+	assert.NotEmpty(t, stderr.String())
+}
+
+func TestRunUnknownOutput(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, EXIT_CODE_ERROR, run(".", "-", "yaml", &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "yaml")
+}
@@ -0,0 +1,107 @@
+// Command cloud-sql-onboard brings an existing Cloud SQL instance under modules/cloud-sql. It reads the instance, its
+// failover and read replicas, databases and users from the Cloud SQL Admin API, and writes a tfvars file with the module
+// inputs that match them, plus a script that imports them with `terraform import`.
+//
+// Usage:
+//
+//	cloud-sql-onboard -project <project> -instance <master instance> [-db-name <name>] [-master-user <name>]
+//	  [-module <module address>] [-tfvars <file>] [-imports <file>]
+//
+// Settings the module can't express are printed as warnings, as Terraform will plan to change them after the import.
+// The command exits with 0 on success and 1 on errors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+)
+
+const EXIT_CODE_SUCCESS = 0
+const EXIT_CODE_ERROR = 1
+
+func main() {
+	options := cloudsql.OnboardingOptions{}
+	flag.StringVar(&options.Project, "project", "", "The project of the instance")
+	flag.StringVar(&options.Instance, "instance", "", "The name of the master instance")
+	flag.StringVar(&options.DatabaseName, "db-name", "", "The database to use as db_name, defaults to the first database in alphabetical order")
+	flag.StringVar(&options.MasterUserName, "master-user", "", "The user to use as master_user_name, defaults to the first user in alphabetical order")
+	flag.StringVar(&options.ModuleAddress, "module", "", "The address of the module to import to, e.g. module.mysql, if it isn't the root module")
+	tfvarsPath := flag.String("tfvars", "terraform.tfvars", "The file to write the module inputs to, or - for stdout")
+	importsPath := flag.String("imports", "import.sh", "The file to write the import script to, or - for stdout")
+	flag.Parse()
+
+	if options.Project == "" || options.Instance == "" {
+		fmt.Fprintln(os.Stderr, "Both -project and -instance are required")
+		os.Exit(EXIT_CODE_ERROR)
+	}
+
+	api, err := cloudsql.NewAdminAPI(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create Cloud SQL Admin API client: %v\n", err)
+		os.Exit(EXIT_CODE_ERROR)
+	}
+
+	tfvars, err := createOutput(*tfvarsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(EXIT_CODE_ERROR)
+	}
+	defer tfvars.Close()
+
+	imports, err := createOutput(*importsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(EXIT_CODE_ERROR)
+	}
+	defer imports.Close()
+
+	exitCode := onboard(api, options, tfvars, imports, os.Stderr)
+	if exitCode == EXIT_CODE_SUCCESS && *importsPath != "-" {
+		if err := os.Chmod(*importsPath, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to make %s executable: %v\n", *importsPath, err)
+			exitCode = EXIT_CODE_ERROR
+		}
+	}
+	os.Exit(exitCode)
+}
+
+// onboard generates the onboarding of the instance described by the given options, writes the tfvars and the import
+// script, and returns the exit code. Warnings are written to stderr.
+func onboard(api cloudsql.AdminAPI, options cloudsql.OnboardingOptions, tfvars io.Writer, imports io.Writer, stderr io.Writer) int {
+	onboarding, err := cloudsql.GenerateOnboarding(api, options)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_CODE_ERROR
+	}
+
+	if err := cloudsql.WriteTfvars(tfvars, onboarding); err != nil {
+		fmt.Fprintf(stderr, "Failed to write the tfvars: %v\n", err)
+		return EXIT_CODE_ERROR
+	}
+	if err := cloudsql.WriteImportScript(imports, onboarding); err != nil {
+		fmt.Fprintf(stderr, "Failed to write the import script: %v\n", err)
+		return EXIT_CODE_ERROR
+	}
+
+	for _, warning := range onboarding.Warnings {
+		fmt.Fprintf(stderr, "WARNING: %s\n", warning)
+	}
+	return EXIT_CODE_SUCCESS
+}
+
+// createOutput creates the given file, or returns stdout for -.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", path, err)
+	}
+	return file, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/stretchr/testify/assert"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+func newTestAPI() *cloudsql.FakeAdminAPI {
+	api := cloudsql.NewFakeAdminAPI(&sqladmin.DatabaseInstance{
+		Project:         "my-project",
+		Name:            "orders",
+		Region:          "us-central1",
+		DatabaseVersion: "MYSQL_8_0",
+		ReplicaNames:    []string{"legacy-replica"},
+		Settings:        &sqladmin.Settings{Tier: "db-f1-micro"},
+	})
+	api.PutDatabase(&sqladmin.Database{Project: "my-project", Instance: "orders", Name: "orders"})
+	api.PutUser("my-project", "orders", cloudsql.User{Name: "admin", Host: "%"})
+	return api
+}
+
+func TestOnboard(t *testing.T) {
+	t.Parallel()
+
+	var tfvars, imports, stderr bytes.Buffer
+	exitCode := onboard(newTestAPI(), cloudsql.OnboardingOptions{Project: "my-project", Instance: "orders", ModuleAddress: "module.orders"}, &tfvars, &imports, &stderr)
+	assert.Equal(t, EXIT_CODE_SUCCESS, exitCode, stderr.String())
+
+	assert.Contains(t, tfvars.String(), `machine_type = "db-f1-micro"`)
+	assert.Contains(t, imports.String(), `terraform import 'module.orders.google_sql_database_instance.master' 'projects/my-project/instances/orders'`)
+	assert.Contains(t, imports.String(), `terraform import 'module.orders.google_sql_user.default' 'my-project/orders/%/admin'`)
+	assert.Contains(t, stderr.String(), "WARNING: Read replica legacy-replica can't be imported")
+}
+
+func TestOnboardMissingInstance(t *testing.T) {
+	t.Parallel()
+
+	var tfvars, imports, stderr bytes.Buffer
+	exitCode := onboard(newTestAPI(), cloudsql.OnboardingOptions{Project: "my-project", Instance: "missing"}, &tfvars, &imports, &stderr)
+	assert.Equal(t, EXIT_CODE_ERROR, exitCode)
+	assert.Contains(t, stderr.String(), "missing")
+	assert.Empty(t, tfvars.String())
+	assert.Empty(t, imports.String())
+}
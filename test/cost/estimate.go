@@ -0,0 +1,225 @@
+package cost
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+const SQL_INSTANCE_RESOURCE_TYPE = "google_sql_database_instance"
+
+const AVAILABILITY_TYPE_REGIONAL = "REGIONAL"
+const ACTIVATION_POLICY_NEVER = "NEVER"
+
+var customTierRegexp = regexp.MustCompile(`^db-custom-(\d+)-(\d+)$`)
+var n1TierRegexp = regexp.MustCompile(`^db-n1-(standard|highmem)-(\d+)$`)
+
+// InstanceCost is the estimated monthly cost of a single instance in the plan.
+type InstanceCost struct {
+	Address          string  `json:"address"`
+	Name             string  `json:"name"`
+	Region           string  `json:"region"`
+	Tier             string  `json:"tier"`
+	DiskType         string  `json:"disk_type"`
+	DiskSizeGb       float64 `json:"disk_size_gb"`
+	HighAvailability bool    `json:"high_availability"`
+	Stopped          bool    `json:"stopped"`
+	ComputeMonthly   float64 `json:"compute_monthly"`
+	StorageMonthly   float64 `json:"storage_monthly"`
+	TotalMonthly     float64 `json:"total_monthly"`
+}
+
+// Estimate is the estimated monthly cost of all Cloud SQL instances in a plan.
+type Estimate struct {
+	Currency     string         `json:"currency"`
+	RatesUpdated string         `json:"rates_updated"`
+	Instances    []InstanceCost `json:"instances"`
+	TotalMonthly float64        `json:"total_monthly"`
+}
+
+// BudgetExceededError is returned by CheckBudget if the estimate is over the budget.
+type BudgetExceededError struct {
+	TotalMonthly  float64
+	MonthlyBudget float64
+	Currency      string
+}
+
+func (err BudgetExceededError) Error() string {
+	return fmt.Sprintf("the estimated monthly cost of %.2f %s exceeds the budget of %.2f %s", err.TotalMonthly, err.Currency, err.MonthlyBudget, err.Currency)
+}
+
+// ParsePlan parses the output of `terraform show -json <plan file>`.
+func ParsePlan(reader io.Reader) (*tfjson.Plan, error) {
+	plan := &tfjson.Plan{}
+	if err := json.NewDecoder(reader).Decode(plan); err != nil {
+		return nil, fmt.Errorf("failed to parse the Terraform plan: %v", err)
+	}
+	return plan, nil
+}
+
+// EstimatePlan estimates the monthly cost of all Cloud SQL instances in the planned values of the given plan, i.e. of
+// the instances as they will be after the plan is applied, including those in child modules. The instances are sorted
+// by address.
+func EstimatePlan(plan *tfjson.Plan, rates *RateTable) (*Estimate, error) {
+	estimate := &Estimate{Currency: rates.Currency, RatesUpdated: rates.Updated, Instances: []InstanceCost{}}
+	if plan.PlannedValues == nil || plan.PlannedValues.RootModule == nil {
+		return estimate, nil
+	}
+
+	modules := []*tfjson.StateModule{plan.PlannedValues.RootModule}
+	for len(modules) > 0 {
+		module := modules[0]
+		modules = append(modules[1:], module.ChildModules...)
+
+		for _, resource := range module.Resources {
+			if resource.Type != SQL_INSTANCE_RESOURCE_TYPE || resource.Mode != tfjson.ManagedResourceMode {
+				continue
+			}
+			instance, err := newInstanceCost(resource)
+			if err != nil {
+				return nil, err
+			}
+			if err := rates.PriceInstance(&instance); err != nil {
+				return nil, fmt.Errorf("failed to price %s: %v", resource.Address, err)
+			}
+			estimate.Instances = append(estimate.Instances, instance)
+			estimate.TotalMonthly += instance.TotalMonthly
+		}
+	}
+
+	sort.Slice(estimate.Instances, func(i, j int) bool { return estimate.Instances[i].Address < estimate.Instances[j].Address })
+	return estimate, nil
+}
+
+func newInstanceCost(resource *tfjson.StateResource) (InstanceCost, error) {
+	attributes := resource.AttributeValues
+	instance := InstanceCost{Address: resource.Address}
+	instance.Name, _ = attributes["name"].(string)
+	instance.Region, _ = attributes["region"].(string)
+
+	settingsList, _ := attributes["settings"].([]interface{})
+	if len(settingsList) == 0 {
+		return instance, fmt.Errorf("%s has no settings in the plan", resource.Address)
+	}
+	settings, _ := settingsList[0].(map[string]interface{})
+
+	instance.Tier, _ = settings["tier"].(string)
+	instance.DiskType, _ = settings["disk_type"].(string)
+	instance.DiskSizeGb, _ = settings["disk_size"].(float64)
+	availabilityType, _ := settings["availability_type"].(string)
+	instance.HighAvailability = availabilityType == AVAILABILITY_TYPE_REGIONAL
+	activationPolicy, _ := settings["activation_policy"].(string)
+	instance.Stopped = activationPolicy == ACTIVATION_POLICY_NEVER
+
+	if instance.Region == "" || instance.Tier == "" {
+		return instance, fmt.Errorf("the region and tier of %s are not known until apply", resource.Address)
+	}
+	return instance, nil
+}
+
+// PriceInstance sets the monthly costs of the given instance. Stopped instances only pay for storage.
+func (rates *RateTable) PriceInstance(instance *InstanceCost) error {
+	regionRates, exists := rates.Regions[instance.Region]
+	if !exists {
+		return fmt.Errorf("the rate table has no prices for region %s", instance.Region)
+	}
+
+	computeHourly, err := regionRates.getComputeHourly(instance.Tier)
+	if err != nil {
+		return err
+	}
+	diskGbMonthly, exists := regionRates.DiskGbMonthly[instance.DiskType]
+	if !exists {
+		return fmt.Errorf("the rate table has no prices for disk type %s in region %s", instance.DiskType, instance.Region)
+	}
+
+	multiplier := 1.0
+	if instance.HighAvailability {
+		multiplier = rates.HighAvailabilityMultiplier
+	}
+	instance.ComputeMonthly = 0
+	if !instance.Stopped {
+		instance.ComputeMonthly = computeHourly * rates.HoursPerMonth * multiplier
+	}
+	instance.StorageMonthly = diskGbMonthly * instance.DiskSizeGb * multiplier
+	instance.TotalMonthly = instance.ComputeMonthly + instance.StorageMonthly
+	return nil
+}
+
+// getComputeHourly returns the hourly price of the given machine type, which is either a shared-core machine type, a
+// custom machine type like db-custom-<vCPUs>-<memory in MB>, or a legacy db-n1-standard-<vCPUs> or
+// db-n1-highmem-<vCPUs> machine type.
+func (regionRates RegionRates) getComputeHourly(tier string) (float64, error) {
+	if price, exists := regionRates.SharedCoreHourly[tier]; exists {
+		return price, nil
+	}
+
+	if matches := customTierRegexp.FindStringSubmatch(tier); matches != nil {
+		vcpus, _ := strconv.ParseFloat(matches[1], 64)
+		memoryMb, _ := strconv.ParseFloat(matches[2], 64)
+		return vcpus*regionRates.VcpuHourly + memoryMb/1024*regionRates.MemoryGbHourly, nil
+	}
+
+	if matches := n1TierRegexp.FindStringSubmatch(tier); matches != nil {
+		vcpus, _ := strconv.ParseFloat(matches[2], 64)
+		memoryGbPerVcpu := N1_STANDARD_MEMORY_GB_PER_VCPU
+		if matches[1] == "highmem" {
+			memoryGbPerVcpu = N1_HIGHMEM_MEMORY_GB_PER_VCPU
+		}
+		return vcpus*regionRates.VcpuHourly + vcpus*memoryGbPerVcpu*regionRates.MemoryGbHourly, nil
+	}
+
+	return 0, fmt.Errorf("unknown machine type %s", tier)
+}
+
+// CheckBudget returns a BudgetExceededError if the estimated monthly total is over the given budget.
+func CheckBudget(estimate *Estimate, monthlyBudget float64) error {
+	if estimate.TotalMonthly > monthlyBudget {
+		return BudgetExceededError{TotalMonthly: estimate.TotalMonthly, MonthlyBudget: monthlyBudget, Currency: estimate.Currency}
+	}
+	return nil
+}
+
+// WriteEstimateHuman writes the given estimate as a table with a row per instance and the monthly total.
+func WriteEstimateHuman(writer io.Writer, estimate *Estimate) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ADDRESS\tREGION\tTIER\tDISK\tHA\tCOMPUTE\tSTORAGE\tMONTHLY")
+	for _, instance := range estimate.Instances {
+		tier := instance.Tier
+		if instance.Stopped {
+			tier += " (stopped)"
+		}
+		fmt.Fprintf(
+			table,
+			"%s\t%s\t%s\t%.0f GB %s\t%t\t%.2f\t%.2f\t%.2f\n",
+			instance.Address,
+			instance.Region,
+			tier,
+			instance.DiskSizeGb,
+			instance.DiskType,
+			instance.HighAvailability,
+			instance.ComputeMonthly,
+			instance.StorageMonthly,
+			instance.TotalMonthly,
+		)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(writer, "\nEstimated total: %.2f %s per month (rates as of %s)\n", estimate.TotalMonthly, estimate.Currency, estimate.RatesUpdated)
+	return err
+}
+
+// WriteEstimateJSON writes the given estimate as indented JSON.
+func WriteEstimateJSON(writer io.Writer, estimate *Estimate) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(estimate)
+}
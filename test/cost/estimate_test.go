@@ -0,0 +1,188 @@
+package cost
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TEST_PLAN_JSON is a trimmed down `terraform show -json` output of a plan with a regional master, a read replica in
+// another region and a stopped read replica
+const TEST_PLAN_JSON = `{
+  "format_version": "0.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "random_id.name", "mode": "managed", "type": "random_id", "name": "name", "values": {}}
+      ],
+      "child_modules": [
+        {
+          "address": "module.postgres",
+          "resources": [
+            {
+              "address": "module.postgres.google_sql_database_instance.master",
+              "mode": "managed",
+              "type": "google_sql_database_instance",
+              "name": "master",
+              "values": {
+                "name": "postgres-ab12",
+                "region": "us-central1",
+                "settings": [{"tier": "db-custom-2-7680", "disk_size": 20, "disk_type": "PD_SSD", "availability_type": "REGIONAL", "activation_policy": "ALWAYS"}]
+              }
+            },
+            {
+              "address": "module.postgres.google_sql_database_instance.read_replica[\"dr\"]",
+              "mode": "managed",
+              "type": "google_sql_database_instance",
+              "name": "read_replica",
+              "index": "dr",
+              "values": {
+                "name": "postgres-ab12-dr",
+                "region": "us-east1",
+                "settings": [{"tier": "db-f1-micro", "disk_size": 10, "disk_type": "PD_HDD", "availability_type": "ZONAL", "activation_policy": "ALWAYS"}]
+              }
+            },
+            {
+              "address": "module.postgres.google_sql_database_instance.read_replica[\"read-0\"]",
+              "mode": "managed",
+              "type": "google_sql_database_instance",
+              "name": "read_replica",
+              "index": "read-0",
+              "values": {
+                "name": "postgres-ab12-read-0",
+                "region": "europe-west1",
+                "settings": [{"tier": "db-n1-standard-1", "disk_size": 10, "disk_type": "PD_SSD", "availability_type": "ZONAL", "activation_policy": "NEVER"}]
+              }
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func newTestEstimate(t *testing.T) *Estimate {
+	plan, err := ParsePlan(strings.NewReader(TEST_PLAN_JSON))
+	require.NoError(t, err)
+	estimate, err := EstimatePlan(plan, DefaultRateTable())
+	require.NoError(t, err)
+	return estimate
+}
+
+func TestEstimatePlan(t *testing.T) {
+	t.Parallel()
+
+	estimate := newTestEstimate(t)
+	require.Len(t, estimate.Instances, 3)
+
+	// (2 vCPUs * 0.0413 + 7.5 GB * 0.0070) * 730 hours, doubled for the regional instance, plus 20 GB * 0.170, doubled
+	master := estimate.Instances[0]
+	assert.Equal(t, "module.postgres.google_sql_database_instance.master", master.Address)
+	assert.True(t, master.HighAvailability)
+	assert.InDelta(t, 197.246, master.ComputeMonthly, 0.001)
+	assert.InDelta(t, 6.8, master.StorageMonthly, 0.001)
+
+	// 0.0105 * 730 hours plus 10 GB * 0.090
+	drReplica := estimate.Instances[1]
+	assert.Equal(t, "us-east1", drReplica.Region)
+	assert.InDelta(t, 8.565, drReplica.TotalMonthly, 0.001)
+
+	// Stopped instances only pay for storage
+	stoppedReplica := estimate.Instances[2]
+	assert.True(t, stoppedReplica.Stopped)
+	assert.Zero(t, stoppedReplica.ComputeMonthly)
+	assert.InDelta(t, 1.87, stoppedReplica.TotalMonthly, 0.001)
+
+	assert.InDelta(t, 214.481, estimate.TotalMonthly, 0.001)
+	assert.Equal(t, "USD", estimate.Currency)
+}
+
+func TestEstimatePlanErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		region   string
+		tier     string
+		diskType string
+	}{
+		{"UnknownRegion", "mars-north1", "db-f1-micro", "PD_SSD"},
+		{"UnknownTier", "us-central1", "db-e2-unknown", "PD_SSD"},
+		{"UnknownDiskType", "us-central1", "db-f1-micro", "PD_BALANCED"},
+		{"UnknownAfterApply", "", "", "PD_SSD"},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			planJSON := strings.NewReplacer(`"us-central1"`, `"`+testCase.region+`"`, `"db-custom-2-7680"`, `"`+testCase.tier+`"`, `"disk_size": 20, "disk_type": "PD_SSD"`, `"disk_size": 20, "disk_type": "`+testCase.diskType+`"`).Replace(TEST_PLAN_JSON)
+			plan, err := ParsePlan(strings.NewReader(planJSON))
+			require.NoError(t, err)
+
+			_, err = EstimatePlan(plan, DefaultRateTable())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGetComputeHourly(t *testing.T) {
+	t.Parallel()
+
+	rates := DefaultRateTable().Regions["us-central1"]
+
+	testCases := []struct {
+		tier     string
+		expected float64
+	}{
+		{"db-f1-micro", 0.0105},
+		{"db-custom-1-3840", 0.0413 + 3.75*0.0070},
+		{"db-n1-standard-1", 0.0413 + 3.75*0.0070},
+		{"db-n1-highmem-4", 4*0.0413 + 26*0.0070},
+	}
+
+	for _, testCase := range testCases {
+		price, err := rates.getComputeHourly(testCase.tier)
+		require.NoError(t, err, testCase.tier)
+		assert.InDelta(t, testCase.expected, price, 0.000001, testCase.tier)
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	t.Parallel()
+
+	estimate := newTestEstimate(t)
+	assert.NoError(t, CheckBudget(estimate, 250))
+
+	err := CheckBudget(estimate, 200)
+	require.Error(t, err)
+	budgetErr, ok := err.(BudgetExceededError)
+	require.True(t, ok)
+	assert.Equal(t, float64(200), budgetErr.MonthlyBudget)
+	assert.Contains(t, err.Error(), "214.48 USD exceeds the budget of 200.00 USD")
+}
+
+func TestWriteEstimate(t *testing.T) {
+	t.Parallel()
+
+	estimate := newTestEstimate(t)
+
+	var human bytes.Buffer
+	require.NoError(t, WriteEstimateHuman(&human, estimate))
+	assert.Contains(t, human.String(), "db-n1-standard-1 (stopped)")
+	assert.Contains(t, human.String(), "Estimated total: 214.48 USD per month (rates as of 2026-10-01)")
+
+	var jsonOutput bytes.Buffer
+	require.NoError(t, WriteEstimateJSON(&jsonOutput, estimate))
+	decoded := Estimate{}
+	require.NoError(t, json.Unmarshal(jsonOutput.Bytes(), &decoded))
+	assert.Equal(t, *estimate, decoded)
+}
@@ -0,0 +1,114 @@
+package cost
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DEFAULT_RATE_TABLE_JSON is the bundled rate table. The prices are approximate on-demand list prices without committed
+// use discounts, network egress or backups. To update them, copy the current prices from the source into this table and
+// bump the updated date, or pass a rate table in the same format to LoadRateTable.
+const DEFAULT_RATE_TABLE_JSON = `{
+  "currency": "USD",
+  "updated": "2026-10-01",
+  "source": "https://cloud.google.com/sql/pricing",
+  "hours_per_month": 730,
+  "high_availability_multiplier": 2,
+  "regions": {
+    "us-central1": {
+      "vcpu_hourly": 0.0413,
+      "memory_gb_hourly": 0.0070,
+      "shared_core_hourly": {"db-f1-micro": 0.0105, "db-g1-small": 0.0350},
+      "disk_gb_monthly": {"PD_SSD": 0.170, "PD_HDD": 0.090}
+    },
+    "us-east1": {
+      "vcpu_hourly": 0.0413,
+      "memory_gb_hourly": 0.0070,
+      "shared_core_hourly": {"db-f1-micro": 0.0105, "db-g1-small": 0.0350},
+      "disk_gb_monthly": {"PD_SSD": 0.170, "PD_HDD": 0.090}
+    },
+    "us-west1": {
+      "vcpu_hourly": 0.0413,
+      "memory_gb_hourly": 0.0070,
+      "shared_core_hourly": {"db-f1-micro": 0.0105, "db-g1-small": 0.0350},
+      "disk_gb_monthly": {"PD_SSD": 0.170, "PD_HDD": 0.090}
+    },
+    "europe-north1": {
+      "vcpu_hourly": 0.0454,
+      "memory_gb_hourly": 0.0077,
+      "shared_core_hourly": {"db-f1-micro": 0.0115, "db-g1-small": 0.0385},
+      "disk_gb_monthly": {"PD_SSD": 0.187, "PD_HDD": 0.099}
+    },
+    "europe-west1": {
+      "vcpu_hourly": 0.0454,
+      "memory_gb_hourly": 0.0077,
+      "shared_core_hourly": {"db-f1-micro": 0.0115, "db-g1-small": 0.0385},
+      "disk_gb_monthly": {"PD_SSD": 0.187, "PD_HDD": 0.099}
+    },
+    "europe-west2": {
+      "vcpu_hourly": 0.0532,
+      "memory_gb_hourly": 0.0090,
+      "shared_core_hourly": {"db-f1-micro": 0.0135, "db-g1-small": 0.0450},
+      "disk_gb_monthly": {"PD_SSD": 0.204, "PD_HDD": 0.108}
+    },
+    "europe-west3": {
+      "vcpu_hourly": 0.0532,
+      "memory_gb_hourly": 0.0090,
+      "shared_core_hourly": {"db-f1-micro": 0.0135, "db-g1-small": 0.0450},
+      "disk_gb_monthly": {"PD_SSD": 0.204, "PD_HDD": 0.108}
+    }
+  }
+}`
+
+// Memory per vCPU of the legacy db-n1-standard-N and db-n1-highmem-N machine types
+const N1_STANDARD_MEMORY_GB_PER_VCPU = 3.75
+const N1_HIGHMEM_MEMORY_GB_PER_VCPU = 6.5
+
+// RateTable holds the prices used to estimate the cost of Cloud SQL instances.
+type RateTable struct {
+	Currency      string  `json:"currency"`
+	Updated       string  `json:"updated"`
+	Source        string  `json:"source"`
+	HoursPerMonth float64 `json:"hours_per_month"`
+	// Highly available (regional) instances are billed at this multiple of the compute and storage prices
+	HighAvailabilityMultiplier float64                `json:"high_availability_multiplier"`
+	Regions                    map[string]RegionRates `json:"regions"`
+}
+
+// RegionRates holds the prices in a single region. Dedicated-core machine types are billed by vCPU and memory, the
+// shared-core machine types have a fixed price, and storage is billed by GB and disk type.
+type RegionRates struct {
+	VcpuHourly       float64            `json:"vcpu_hourly"`
+	MemoryGbHourly   float64            `json:"memory_gb_hourly"`
+	SharedCoreHourly map[string]float64 `json:"shared_core_hourly"`
+	DiskGbMonthly    map[string]float64 `json:"disk_gb_monthly"`
+}
+
+// DefaultRateTable returns the bundled rate table.
+func DefaultRateTable() *RateTable {
+	rates, err := LoadRateTable(strings.NewReader(DEFAULT_RATE_TABLE_JSON))
+	if err != nil {
+		panic(err)
+	}
+	return rates
+}
+
+// LoadRateTable parses a rate table in the format of DEFAULT_RATE_TABLE_JSON.
+func LoadRateTable(reader io.Reader) (*RateTable, error) {
+	rates := &RateTable{}
+	if err := json.NewDecoder(reader).Decode(rates); err != nil {
+		return nil, fmt.Errorf("failed to parse the rate table: %v", err)
+	}
+	if rates.HoursPerMonth <= 0 {
+		return nil, fmt.Errorf("the rate table needs a positive hours_per_month")
+	}
+	if rates.HighAvailabilityMultiplier < 1 {
+		return nil, fmt.Errorf("the rate table needs a high_availability_multiplier of at least 1")
+	}
+	if len(rates.Regions) == 0 {
+		return nil, fmt.Errorf("the rate table has no regions")
+	}
+	return rates, nil
+}
@@ -0,0 +1,48 @@
+package cost
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRateTable(t *testing.T) {
+	t.Parallel()
+
+	rates := DefaultRateTable()
+	assert.Equal(t, "USD", rates.Currency)
+
+	// The regions the tests deploy to have to be priced
+	for _, region := range []string{"europe-north1", "europe-west1", "europe-west2", "europe-west3", "us-central1", "us-east1", "us-west1"} {
+		regionRates, exists := rates.Regions[region]
+		require.True(t, exists, "No prices for %s", region)
+		assert.NotZero(t, regionRates.VcpuHourly, region)
+		assert.NotZero(t, regionRates.DiskGbMonthly["PD_SSD"], region)
+	}
+}
+
+func TestLoadRateTable(t *testing.T) {
+	t.Parallel()
+
+	rates, err := LoadRateTable(strings.NewReader(`{
+		"currency": "EUR",
+		"hours_per_month": 720,
+		"high_availability_multiplier": 2,
+		"regions": {"europe-west1": {"shared_core_hourly": {"db-f1-micro": 0.01}, "disk_gb_monthly": {"PD_SSD": 0.2}}}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", rates.Currency)
+	assert.Equal(t, 0.01, rates.Regions["europe-west1"].SharedCoreHourly["db-f1-micro"])
+
+	for _, invalid := range []string{
+		`not json`,
+		`{"high_availability_multiplier": 2, "regions": {"us-central1": {}}}`,
+		`{"hours_per_month": 730, "regions": {"us-central1": {}}}`,
+		`{"hours_per_month": 730, "high_availability_multiplier": 2}`,
+	} {
+		_, err := LoadRateTable(strings.NewReader(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// Users created through the Cloud SQL API (i.e. the module's master and additional users) get broad privileges on the
+// instance. Least-privilege users therefore have to be created and granted with plain SQL after the deployment.
+const DB_READ_ONLY_USER = "testuser_ro"
+const DB_READ_ONLY_PASS = "testpassword_ro"
+const DB_READ_WRITE_USER = "testuser_rw"
+const DB_READ_WRITE_PASS = "testpassword_rw"
+
+const POSTGRES_READ_ONLY_ROLE = "app_readonly"
+const POSTGRES_READ_WRITE_ROLE = "app_readwrite"
+
+// MySQL error numbers that signal a missing privilege, see
+// https://dev.mysql.com/doc/mysql-errors/5.7/en/server-error-reference.html
+const MYSQL_ER_DBACCESS_DENIED_ERROR = 1044
+const MYSQL_ER_SPECIFIC_ACCESS_DENIED_ERROR = 1227
+const MYSQL_ER_TABLEACCESS_DENIED_ERROR = 1142
+const MYSQL_ER_COLUMNACCESS_DENIED_ERROR = 1143
+
+// Postgres SQLSTATE for insufficient_privilege, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const POSTGRES_INSUFFICIENT_PRIVILEGE = "42501"
+
+// getMySqlLeastPrivilegeStatements returns the statements that create a user with either read-only or read-write access
+// to all tables of the given database.
+func getMySqlLeastPrivilegeStatements(dbName string, user string, password string, readOnly bool) []string {
+	privileges := "SELECT, INSERT, UPDATE, DELETE"
+	if readOnly {
+		privileges = "SELECT"
+	}
+
+	account := fmt.Sprintf("'%s'@'%%'", escapeSqlString(user))
+	return []string{
+		fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY '%s'", account, escapeSqlString(password)),
+		fmt.Sprintf("GRANT %s ON `%s`.* TO %s", privileges, strings.ReplaceAll(dbName, "`", "``"), account),
+	}
+}
+
+// getPostgresLeastPrivilegeStatements returns the statements that create a group role with either read-only or
+// read-write access to all current and future tables in the public schema of the given database, plus a login user
+// that is a member of that role. Postgres has no CREATE ROLE IF NOT EXISTS, so we guard with DO blocks to keep the
+// statements idempotent.
+func getPostgresLeastPrivilegeStatements(dbName string, user string, password string, readOnly bool) []string {
+	role := POSTGRES_READ_WRITE_ROLE
+	tablePrivileges := "SELECT, INSERT, UPDATE, DELETE"
+	if readOnly {
+		role = POSTGRES_READ_ONLY_ROLE
+		tablePrivileges = "SELECT"
+	}
+
+	statements := []string{
+		createPostgresRoleIfNotExists(role, "NOLOGIN"),
+		fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", pq.QuoteIdentifier(dbName), pq.QuoteIdentifier(role)),
+		fmt.Sprintf("GRANT USAGE ON SCHEMA public TO %s", pq.QuoteIdentifier(role)),
+		fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA public TO %s", tablePrivileges, pq.QuoteIdentifier(role)),
+		fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT %s ON TABLES TO %s", tablePrivileges, pq.QuoteIdentifier(role)),
+	}
+
+	// Writers need access to the sequences backing SERIAL columns
+	if !readOnly {
+		statements = append(statements,
+			fmt.Sprintf("GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO %s", pq.QuoteIdentifier(role)),
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO %s", pq.QuoteIdentifier(role)),
+		)
+	}
+
+	loginOptions := fmt.Sprintf("LOGIN PASSWORD '%s' IN ROLE %s", escapeSqlString(password), pq.QuoteIdentifier(role))
+	return append(statements, createPostgresRoleIfNotExists(user, loginOptions))
+}
+
+func createPostgresRoleIfNotExists(role string, options string) string {
+	return fmt.Sprintf(
+		"DO $$ BEGIN IF NOT EXISTS (SELECT FROM pg_catalog.pg_roles WHERE rolname = '%s') THEN CREATE ROLE %s %s; END IF; END $$",
+		escapeSqlString(role), pq.QuoteIdentifier(role), options,
+	)
+}
+
+// createMySqlLeastPrivilegeUsers creates DB_READ_ONLY_USER and DB_READ_WRITE_USER with access to the given database.
+// The connection has to be made as a user that is allowed to grant privileges, e.g. the master user.
+func createMySqlLeastPrivilegeUsers(t *testing.T, db *sql.DB, dbName string) {
+	statements := append(
+		getMySqlLeastPrivilegeStatements(dbName, DB_READ_ONLY_USER, DB_READ_ONLY_PASS, true),
+		getMySqlLeastPrivilegeStatements(dbName, DB_READ_WRITE_USER, DB_READ_WRITE_PASS, false)...,
+	)
+	execStatements(t, db, statements)
+}
+
+// createPostgresLeastPrivilegeUsers creates the read-only and read-write roles with DB_READ_ONLY_USER and
+// DB_READ_WRITE_USER as members. The connection has to be made to the given database, as schema and table grants only
+// apply to the database they are run in.
+func createPostgresLeastPrivilegeUsers(t *testing.T, db *sql.DB, dbName string) {
+	statements := append(
+		getPostgresLeastPrivilegeStatements(dbName, DB_READ_ONLY_USER, DB_READ_ONLY_PASS, true),
+		getPostgresLeastPrivilegeStatements(dbName, DB_READ_WRITE_USER, DB_READ_WRITE_PASS, false)...,
+	)
+	execStatements(t, db, statements)
+}
+
+func execStatements(t *testing.T, db *sql.DB, statements []string) {
+	for _, statement := range statements {
+		logger.Logf(t, "Execute: %s", statement)
+		_, err := db.Exec(statement)
+		require.NoError(t, err, "Failed to execute statement")
+	}
+}
+
+// isPermissionDeniedError returns true if the given error was raised by MySQL or Postgres because the user lacks the
+// privilege for the statement.
+func isPermissionDeniedError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case MYSQL_ER_DBACCESS_DENIED_ERROR, MYSQL_ER_SPECIFIC_ACCESS_DENIED_ERROR, MYSQL_ER_TABLEACCESS_DENIED_ERROR, MYSQL_ER_COLUMNACCESS_DENIED_ERROR:
+			return true
+		}
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == POSTGRES_INSUFFICIENT_PRIVILEGE
+	}
+
+	return false
+}
+
+func escapeSqlString(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMySqlLeastPrivilegeStatements(t *testing.T) {
+	t.Parallel()
+
+	readOnly := getMySqlLeastPrivilegeStatements("testdb", "ro", "pass'word", true)
+	assert.Equal(t, []string{
+		"CREATE USER IF NOT EXISTS 'ro'@'%' IDENTIFIED BY 'pass''word'",
+		"GRANT SELECT ON `testdb`.* TO 'ro'@'%'",
+	}, readOnly)
+
+	readWrite := getMySqlLeastPrivilegeStatements("testdb", "rw", "password", false)
+	assert.Equal(t, "GRANT SELECT, INSERT, UPDATE, DELETE ON `testdb`.* TO 'rw'@'%'", readWrite[1])
+}
+
+func TestGetPostgresLeastPrivilegeStatements(t *testing.T) {
+	t.Parallel()
+
+	readOnly := getPostgresLeastPrivilegeStatements("testdb", "ro", "password", true)
+	assert.Contains(t, readOnly[0], `CREATE ROLE "app_readonly" NOLOGIN`)
+	assert.Contains(t, readOnly, `GRANT CONNECT ON DATABASE "testdb" TO "app_readonly"`)
+	assert.Contains(t, readOnly, `GRANT SELECT ON ALL TABLES IN SCHEMA public TO "app_readonly"`)
+	assert.Contains(t, readOnly[len(readOnly)-1], `CREATE ROLE "ro" LOGIN PASSWORD 'password' IN ROLE "app_readonly"`)
+	for _, statement := range readOnly {
+		assert.NotContains(t, statement, "INSERT")
+		assert.NotContains(t, statement, "SEQUENCES")
+	}
+
+	readWrite := getPostgresLeastPrivilegeStatements("testdb", "rw", "password", false)
+	assert.Contains(t, readWrite, `GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO "app_readwrite"`)
+	assert.Contains(t, readWrite, `GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO "app_readwrite"`)
+}
+
+func TestIsPermissionDeniedError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"MySQLTableAccessDenied", &mysql.MySQLError{Number: 1142, Message: "INSERT command denied to user"}, true},
+		{"MySQLDBAccessDenied", &mysql.MySQLError{Number: 1044, Message: "Access denied for user"}, true},
+		{"MySQLReadOnly", &mysql.MySQLError{Number: 1290, Message: "running with the --read-only option"}, false},
+		{"PostgresInsufficientPrivilege", &pq.Error{Code: "42501", Message: "permission denied for table test"}, true},
+		{"PostgresReadOnlyTransaction", &pq.Error{Code: "25006", Message: "cannot execute INSERT in a read-only transaction"}, false},
+		{"Wrapped", fmt.Errorf("insert failed: %w", &pq.Error{Code: "42501"}), true},
+		{"Other", errors.New("connection refused"), false},
+		{"Nil", nil, false},
+	}
+
+	for _, testCase := range testCases {
+		// Capture range variable so that it doesn't change in the parallel subtests
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expected, isPermissionDeniedError(testCase.err))
+		})
+	}
+}
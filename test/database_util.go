@@ -0,0 +1,167 @@
+package test
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const MYSQL_QUERY_DATABASE_NAMES = "SHOW DATABASES"
+const MYSQL_QUERY_DATABASE_CHARSET = "SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?"
+
+const POSTGRES_QUERY_DATABASE_NAMES = "SELECT datname FROM pg_database WHERE datistemplate = false"
+const POSTGRES_QUERY_DATABASE_CHARSET = "SELECT pg_encoding_to_char(encoding), datcollate FROM pg_database WHERE datname = $1"
+
+// Connections to a stopped instance are expected to fail, so there's no point in waiting long for them
+const STOPPED_INSTANCE_CONNECT_TIMEOUT = 30 * time.Second
+
+// openMySqlConnection connects to the given MySQL database as the given user and pings it, failing the test if the
+// connection can't be established.
+func openMySqlConnection(t *testing.T, host string, user string, password string, dbName string) *sql.DB {
+	return openMySqlConnectionToAddress(t, fmt.Sprintf("%s:3306", host), user, password, dbName)
+}
+
+// openMySqlConnectionToAddress is like openMySqlConnection, but connects to the given "<host>:<port>", e.g. the local
+// end of a tunnel.
+func openMySqlConnectionToAddress(t *testing.T, address string, user string, password string, dbName string) *sql.DB {
+	connectionString := fmt.Sprintf("%s:%s@tcp(%s)/%s", user, password, address, dbName)
+	return openConnection(t, "mysql", connectionString, address, user, dbName)
+}
+
+// openPostgresConnection connects to the given Postgres database as the given user and pings it, failing the test if
+// the connection can't be established. The host may include a port, e.g. the local end of a tunnel.
+func openPostgresConnection(t *testing.T, host string, user string, password string, dbName string) *sql.DB {
+	connectionString := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", user, password, host, dbName)
+	return openConnection(t, "postgres", connectionString, host, user, dbName)
+}
+
+// pingMySqlE connects to the given MySQL database and pings it, giving up after the given timeout. It's meant to check
+// that an instance is not reachable, which otherwise can take minutes until the connection attempt times out.
+func pingMySqlE(host string, user string, password string, dbName string, timeout time.Duration) error {
+	connectionString := fmt.Sprintf("%s:%s@tcp(%s:3306)/%s?timeout=%s", user, password, host, dbName, timeout)
+	db, err := sql.Open("mysql", connectionString)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Ping()
+}
+
+func openConnection(t *testing.T, driverName string, connectionString string, host string, user string, dbName string) *sql.DB {
+	// Does not actually open up the connection - just returns a DB ref
+	logger.Logf(t, "Connecting to: %s/%s as %s", host, dbName, user)
+	db, err := sql.Open(driverName, connectionString)
+	require.NoError(t, err, "Failed to open DB connection")
+
+	// Run ping to actually test the connection
+	if err = db.Ping(); err != nil {
+		db.Close()
+		t.Fatalf("Failed to ping %s/%s as %s: %v", host, dbName, user, err)
+	}
+
+	return db
+}
+
+// getMySqlDatabaseNames returns the names of all databases the connected user can see.
+func getMySqlDatabaseNames(t *testing.T, db *sql.DB) []string {
+	return queryStrings(t, db, MYSQL_QUERY_DATABASE_NAMES)
+}
+
+// getPostgresDatabaseNames returns the names of all non-template databases on the connected server.
+func getPostgresDatabaseNames(t *testing.T, db *sql.DB) []string {
+	return queryStrings(t, db, POSTGRES_QUERY_DATABASE_NAMES)
+}
+
+// getMySqlDatabaseCharset returns the default charset and collation of the given database.
+func getMySqlDatabaseCharset(t *testing.T, db *sql.DB, dbName string) (string, string) {
+	var charset, collation string
+	err := db.QueryRow(MYSQL_QUERY_DATABASE_CHARSET, dbName).Scan(&charset, &collation)
+	require.NoError(t, err, "Failed to query charset of database %s", dbName)
+	return charset, collation
+}
+
+// getPostgresDatabaseCharset returns the encoding and collation of the given database.
+func getPostgresDatabaseCharset(t *testing.T, db *sql.DB, dbName string) (string, string) {
+	var charset, collation string
+	err := db.QueryRow(POSTGRES_QUERY_DATABASE_CHARSET, dbName).Scan(&charset, &collation)
+	require.NoError(t, err, "Failed to query charset of database %s", dbName)
+	return charset, collation
+}
+
+// runMySqlTableTests creates the test table, empties it and inserts a row, checking that the id of the row honours the
+// auto_increment_increment and auto_increment_offset flags of the example, which are both set to the given value.
+func runMySqlTableTests(t *testing.T, db *sql.DB, autoIncrement int64) {
+	// Create table if not exists
+	logger.Logf(t, "Create table: %s", MYSQL_CREATE_TEST_TABLE_WITH_AUTO_INCREMENT_STATEMENT)
+	if _, err := db.Exec(MYSQL_CREATE_TEST_TABLE_WITH_AUTO_INCREMENT_STATEMENT); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// Clean up
+	logger.Logf(t, "Empty table: %s", SQL_EMPTY_TEST_TABLE_STATEMENT)
+	if _, err := db.Exec(SQL_EMPTY_TEST_TABLE_STATEMENT); err != nil {
+		t.Fatalf("Failed to clean up table: %v", err)
+	}
+
+	// Insert data to check that our auto-increment flags worked
+	logger.Logf(t, "Insert data: %s", MYSQL_INSERT_TEST_ROW)
+	stmt, err := db.Prepare(MYSQL_INSERT_TEST_ROW)
+	require.NoError(t, err, "Failed to prepare statement")
+	defer stmt.Close()
+
+	// Execute the statement
+	res, err := stmt.Exec("Grunt")
+	require.NoError(t, err, "Failed to execute statement")
+
+	// Get the last insert id
+	lastId, err := res.LastInsertId()
+	require.NoError(t, err, "Failed to get last insert id")
+
+	// As the offset equals the increment, the modulus should always be 0
+	assert.Equal(t, int64(0), lastId%autoIncrement)
+}
+
+// runPostgresTableTests creates the test table, empties it and inserts a row, checking that the row got an id.
+func runPostgresTableTests(t *testing.T, db *sql.DB) {
+	// Create table if not exists
+	logger.Logf(t, "Create table: %s", POSTGRES_CREATE_TEST_TABLE_WITH_SERIAL)
+	if _, err := db.Exec(POSTGRES_CREATE_TEST_TABLE_WITH_SERIAL); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// Clean up
+	logger.Logf(t, "Empty table: %s", SQL_EMPTY_TEST_TABLE_STATEMENT)
+	if _, err := db.Exec(SQL_EMPTY_TEST_TABLE_STATEMENT); err != nil {
+		t.Fatalf("Failed to clean up table: %v", err)
+	}
+
+	logger.Logf(t, "Insert data: %s", POSTGRES_INSERT_TEST_ROW)
+	var testid int
+	err := db.QueryRow(POSTGRES_INSERT_TEST_ROW).Scan(&testid)
+	require.NoError(t, err, "Failed to insert data")
+
+	assert.True(t, testid > 0, "Data was inserted")
+}
+
+// queryStrings runs a query that returns a single string column and collects all rows.
+func queryStrings(t *testing.T, db *sql.DB, query string, args ...interface{}) []string {
+	rows, err := db.Query(query, args...)
+	require.NoError(t, err, "Failed to run query: %s", query)
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		require.NoError(t, rows.Scan(&value), "Failed to scan row of query: %s", query)
+		values = append(values, value)
+	}
+	require.NoError(t, rows.Err(), "Failed to iterate rows of query: %s", query)
+
+	return values
+}
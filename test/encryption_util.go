@@ -0,0 +1,69 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/stretchr/testify/require"
+)
+
+// Customer-managed keys can't be deleted, so the CMEK tests use a pre-provisioned key instead of creating one per test
+// run. The Cloud SQL service agent of the project needs the Cloud KMS CryptoKey Encrypter/Decrypter role on it.
+const ENV_VAR_CMEK_KEY_NAME = "CLOUD_SQL_CMEK_KEY_NAME"
+
+// A syntactically valid key, used for plan-only tests that never create the instances
+const PLAN_ONLY_CMEK_KEY_NAME_FORMAT = "projects/%s/locations/%s/keyRings/terratest/cryptoKeys/cloud-sql"
+
+var kmsKeyNameRegexp = regexp.MustCompile(`^projects/[^/]+/locations/([^/]+)/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+
+// getEncryptionKeyRegionE returns the location of the given Cloud KMS key, which is the region instances encrypted with
+// it have to live in.
+func getEncryptionKeyRegionE(keyName string) (string, error) {
+	matches := kmsKeyNameRegexp.FindStringSubmatch(keyName)
+	if matches == nil {
+		return "", fmt.Errorf("%q is not a Cloud KMS key name of the form projects/P/locations/L/keyRings/R/cryptoKeys/K", keyName)
+	}
+	return matches[1], nil
+}
+
+// validateEncryptionKeyNameE returns an error if the given key is malformed or can't be used for an instance in the given
+// region.
+func validateEncryptionKeyNameE(keyName string, region string) error {
+	keyRegion, err := getEncryptionKeyRegionE(keyName)
+	if err != nil {
+		return err
+	}
+	if keyRegion != region {
+		return fmt.Errorf("key %s is in location %s, but must be in the instance region %s", keyName, keyRegion, region)
+	}
+	return nil
+}
+
+// validateInstanceEncryptionKey fails the test if the Admin API doesn't report the given instance as encrypted with the
+// expected customer-managed key.
+func validateInstanceEncryptionKey(t *testing.T, api cloudsql.AdminAPI, projectId string, instanceName string, expectedKeyName string) {
+	err := validateInstanceEncryptionKeyE(api, projectId, instanceName, expectedKeyName)
+	require.NoError(t, err, "Instance %s is not encrypted with the expected key", instanceName)
+}
+
+// validateInstanceEncryptionKeyE returns an error if the Admin API doesn't report the given instance as encrypted with the
+// expected customer-managed key.
+func validateInstanceEncryptionKeyE(api cloudsql.AdminAPI, projectId string, instanceName string, expectedKeyName string) error {
+	instance, err := api.GetInstance(projectId, instanceName)
+	if err != nil {
+		return err
+	}
+
+	if instance.DiskEncryptionConfiguration == nil || instance.DiskEncryptionConfiguration.KmsKeyName == "" {
+		return fmt.Errorf("instance %s is not encrypted with a customer-managed key", instanceName)
+	}
+
+	actualKeyName := instance.DiskEncryptionConfiguration.KmsKeyName
+	if actualKeyName != expectedKeyName {
+		return fmt.Errorf("instance %s is encrypted with key %s, expected %s", instanceName, actualKeyName, expectedKeyName)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+const TEST_KEY_NAME = "projects/my-project/locations/us-central1/keyRings/ring/cryptoKeys/key"
+
+func TestValidateEncryptionKeyNameE(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateEncryptionKeyNameE(TEST_KEY_NAME, "us-central1"))
+	assert.Error(t, validateEncryptionKeyNameE(TEST_KEY_NAME, "europe-west1"))
+	assert.Error(t, validateEncryptionKeyNameE("projects/my-project/locations/us-central1/keyRings/ring", "us-central1"))
+	assert.Error(t, validateEncryptionKeyNameE(TEST_KEY_NAME+"/cryptoKeyVersions/1", "us-central1"))
+
+	region, err := getEncryptionKeyRegionE(TEST_KEY_NAME)
+	require.NoError(t, err)
+	assert.Equal(t, "us-central1", region)
+}
+
+func TestValidateInstanceEncryptionKeyE(t *testing.T) {
+	t.Parallel()
+
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{
+			Project:                     "my-project",
+			Name:                        "encrypted",
+			DiskEncryptionConfiguration: &sqladmin.DiskEncryptionConfiguration{KmsKeyName: TEST_KEY_NAME},
+		},
+		&sqladmin.DatabaseInstance{
+			Project: "my-project",
+			Name:    "unencrypted",
+		},
+	)
+
+	assert.NoError(t, validateInstanceEncryptionKeyE(api, "my-project", "encrypted", TEST_KEY_NAME))
+	assert.Error(t, validateInstanceEncryptionKeyE(api, "my-project", "encrypted", TEST_KEY_NAME+"-other"))
+	assert.Error(t, validateInstanceEncryptionKeyE(api, "my-project", "unencrypted", TEST_KEY_NAME))
+
+	err := validateInstanceEncryptionKeyE(api, "my-project", "missing", TEST_KEY_NAME)
+	assert.True(t, cloudsql.IsNotFound(err))
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// How Terraform plans to apply a change of the engine version
+const ENGINE_UPGRADE_IN_PLACE = "in-place upgrade"
+const ENGINE_UPGRADE_REPLACE = "destructive replace"
+
+// getPlannedEngineUpgradeE returns whether the given plan upgrades the engine of the Cloud SQL instances in place or
+// replaces them, along with the sorted addresses of the replaced instances. Replacing an instance deletes it with all
+// its data and creates a new, empty one.
+func getPlannedEngineUpgradeE(plan *terraform.PlanStruct) (string, []string, error) {
+	changes := getPlannedSqlInstanceChanges(plan)
+	if len(changes) == 0 {
+		return "", nil, fmt.Errorf("the plan contains no changes to %s resources", SQL_INSTANCE_RESOURCE_TYPE)
+	}
+
+	replaced := []string{}
+	for address, actions := range changes {
+		switch {
+		case actions.Replace() || actions.Delete():
+			replaced = append(replaced, address)
+		case !actions.Update():
+			return "", nil, fmt.Errorf("unexpected actions %v planned for %s", actions, address)
+		}
+	}
+	sort.Strings(replaced)
+
+	if len(replaced) > 0 {
+		return ENGINE_UPGRADE_REPLACE, replaced, nil
+	}
+	return ENGINE_UPGRADE_IN_PLACE, replaced, nil
+}
+
+// validateInstanceDatabaseVersionsE returns an error unless all given instances run the expected engine version
+// according to the Admin API.
+func validateInstanceDatabaseVersionsE(api cloudsql.AdminAPI, projectId string, instanceNames []string, expectedVersion string) error {
+	for _, instanceName := range instanceNames {
+		instance, err := api.GetInstance(projectId, instanceName)
+		if err != nil {
+			return err
+		}
+		if instance.DatabaseVersion != expectedVersion {
+			return fmt.Errorf("instance %s runs %s, expected %s", instanceName, instance.DatabaseVersion, expectedVersion)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+func TestGetPlannedEngineUpgradeE(t *testing.T) {
+	t.Parallel()
+
+	const master = "module.mysql.google_sql_database_instance.master"
+	const replica = `module.mysql.google_sql_database_instance.read_replica["read-0"]`
+
+	testCases := []struct {
+		name             string
+		changes          []*tfjson.ResourceChange
+		expectedKind     string
+		expectedReplaced []string
+		expectErr        bool
+	}{
+		{
+			"InPlace",
+			[]*tfjson.ResourceChange{
+				newTestResourceChange(master, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionUpdate),
+				newTestResourceChange(replica, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionUpdate),
+			},
+			ENGINE_UPGRADE_IN_PLACE,
+			[]string{},
+			false,
+		},
+		{
+			"Replace",
+			[]*tfjson.ResourceChange{
+				newTestResourceChange(master, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionDelete, tfjson.ActionCreate),
+				newTestResourceChange(replica, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionDelete, tfjson.ActionCreate),
+				newTestResourceChange("module.mysql.data.template_file.read_replica_proxy_connection[\"read-0\"]", "template_file", tfjson.ActionRead),
+			},
+			ENGINE_UPGRADE_REPLACE,
+			[]string{master, replica},
+			false,
+		},
+		{
+			"NoChanges",
+			[]*tfjson.ResourceChange{newTestResourceChange(master, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionNoop)},
+			"",
+			nil,
+			true,
+		},
+		{
+			"UnexpectedCreate",
+			[]*tfjson.ResourceChange{newTestResourceChange(replica, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionCreate)},
+			"",
+			nil,
+			true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{}}
+			for _, change := range testCase.changes {
+				plan.ResourceChangesMap[change.Address] = change
+			}
+
+			kind, replaced, err := getPlannedEngineUpgradeE(plan)
+			if testCase.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedKind, kind)
+			assert.Equal(t, testCase.expectedReplaced, replaced)
+		})
+	}
+}
+
+func TestValidateInstanceDatabaseVersionsE(t *testing.T) {
+	t.Parallel()
+
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "master", DatabaseVersion: "MYSQL_8_0"},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "replica", DatabaseVersion: "MYSQL_5_7"},
+	)
+
+	assert.NoError(t, validateInstanceDatabaseVersionsE(api, "my-project", []string{"master"}, "MYSQL_8_0"))
+	assert.Error(t, validateInstanceDatabaseVersionsE(api, "my-project", []string{"master", "replica"}, "MYSQL_8_0"))
+	assert.Error(t, validateInstanceDatabaseVersionsE(api, "my-project", []string{"missing"}, "MYSQL_8_0"))
+}
@@ -0,0 +1,214 @@
+package test
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const KEY_ENGINE_UPGRADE = "engineUpgrade"
+
+const ENGINE_UPGRADE_ROW_NAME = "PreUpgrade"
+const ENGINE_UPGRADE_ROW_COUNT = 3
+
+func TestEngineUpgrade(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		exampleName        string
+		namePrefix         string
+		versionVar         string
+		oldVersion         string
+		newVersion         string
+		hasFailoverReplica bool
+		openConnection     func(t *testing.T, host string, user string, password string, dbName string) *sql.DB
+		createTable        string
+		insertRow          func(db *sql.DB) error
+	}{
+		{
+			"MySql",
+			EXAMPLE_NAME_REPLICAS,
+			"mysql-upgrade",
+			"mysql_version",
+			"MYSQL_5_7",
+			"MYSQL_8_0",
+			true,
+			openMySqlConnection,
+			MYSQL_CREATE_TEST_TABLE_WITH_AUTO_INCREMENT_STATEMENT,
+			func(db *sql.DB) error {
+				_, err := db.Exec(MYSQL_INSERT_TEST_ROW, ENGINE_UPGRADE_ROW_NAME)
+				return err
+			},
+		},
+		{
+			"Postgres",
+			EXAMPLE_NAME_POSTGRES_REPLICAS,
+			"postgres-upgrade",
+			"postgres_version",
+			"POSTGRES_12",
+			"POSTGRES_14",
+			false,
+			openPostgresConnection,
+			POSTGRES_CREATE_TEST_TABLE_WITH_SERIAL,
+			func(db *sql.DB) error {
+				_, err := db.Exec(POSTGRES_INSERT_TEST_ROW)
+				return err
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			//os.Setenv("SKIP_bootstrap", "true")
+			//os.Setenv("SKIP_deploy", "true")
+			//os.Setenv("SKIP_write_data", "true")
+			//os.Setenv("SKIP_plan_upgrade", "true")
+			//os.Setenv("SKIP_upgrade", "true")
+			//os.Setenv("SKIP_validate_upgrade", "true")
+			//os.Setenv("SKIP_teardown", "true")
+
+			reporter := newStageReporter(t)
+
+			_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+			exampleDir := filepath.Join(_examplesDir, testCase.exampleName)
+
+			// BOOTSTRAP VARIABLES FOR THE TESTS
+			reporter.RunTestStage("bootstrap", func() {
+				projectId := getTestProjectId(t)
+				region := getRandomRegion(t, projectId)
+
+				masterZone, failoverReplicaZone := getTwoDistinctRandomZonesForRegion(t, projectId, region)
+				readReplicaZone := gcp.GetRandomZoneForRegion(t, projectId, region)
+
+				test_structure.SaveString(t, exampleDir, KEY_REGION, region)
+				test_structure.SaveString(t, exampleDir, KEY_MASTER_ZONE, masterZone)
+				test_structure.SaveString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE, failoverReplicaZone)
+				test_structure.SaveString(t, exampleDir, KEY_READ_REPLICA_ZONE, readReplicaZone)
+				test_structure.SaveString(t, exampleDir, KEY_PROJECT, projectId)
+			})
+
+			// AT THE END OF THE TESTS, RUN `terraform destroy`
+			// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
+			defer reporter.RunTestStage("teardown", func() {
+				terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+				terraform.Destroy(t, terraformOptions)
+			})
+
+			// DEPLOY THE OLDER ENGINE VERSION
+			reporter.RunTestStage("deploy", func() {
+				region := test_structure.LoadString(t, exampleDir, KEY_REGION)
+				projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+				masterZone := test_structure.LoadString(t, exampleDir, KEY_MASTER_ZONE)
+				readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
+
+				// Postgres places the standby of a regional instance itself
+				failoverReplicaZone := ""
+				if testCase.hasFailoverReplica {
+					failoverReplicaZone = test_structure.LoadString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE)
+				}
+
+				terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, region, exampleDir, testCase.namePrefix, masterZone, failoverReplicaZone, 1, readReplicaZone)
+				terraformOptions.Vars[testCase.versionVar] = testCase.oldVersion
+				setInstanceNameOverride(t, terraformOptions)
+				test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+				// Waits while the parallel tests already use the instances allowed in the project
+				reserveInstances(t, terraformOptions)
+				initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
+			})
+
+			reporter.RunTestStage("write_data", func() {
+				terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+				publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+				db := testCase.openConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+				defer db.Close()
+
+				_, err := db.Exec(testCase.createTable)
+				require.NoError(t, err, "Failed to create table")
+
+				for i := 0; i < ENGINE_UPGRADE_ROW_COUNT; i++ {
+					require.NoError(t, testCase.insertRow(db), "Failed to insert row")
+				}
+			})
+
+			// REPORT HOW TERRAFORM PLANS TO CHANGE THE ENGINE VERSION
+			reporter.RunTestStage("plan_upgrade", func() {
+				terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+				terraformOptions.Vars[testCase.versionVar] = testCase.newVersion
+				test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+				plan := initAndPlanWithStruct(t, terraformOptions)
+				kind, replaced, err := getPlannedEngineUpgradeE(plan)
+				require.NoError(t, err)
+
+				logger.Logf(t, "Changing %s to %s is a %s", testCase.oldVersion, testCase.newVersion, kind)
+				for _, address := range replaced {
+					logger.Logf(t, "Terraform plans to delete and recreate %s, which loses its data", address)
+				}
+				test_structure.SaveString(t, exampleDir, KEY_ENGINE_UPGRADE, kind)
+			})
+
+			reporter.RunTestStage("upgrade", func() {
+				terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+				terraform.Apply(t, terraformOptions)
+			})
+
+			// VALIDATE THAT THE DATA AND THE REPLICAS SURVIVED THE UPGRADE
+			reporter.RunTestStage("validate_upgrade", func() {
+				terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+				projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+				kind := test_structure.LoadString(t, exampleDir, KEY_ENGINE_UPGRADE)
+
+				instanceNames := []string{terraform.Output(t, terraformOptions, OUTPUT_MASTER_INSTANCE_NAME)}
+				if testCase.hasFailoverReplica {
+					instanceNames = append(instanceNames, terraform.Output(t, terraformOptions, OUTPUT_FAILOVER_INSTANCE_NAME))
+				}
+				instanceNames = append(instanceNames, terraform.OutputList(t, terraformOptions, OUTPUT_READ_REPLICA_INSTANCE_NAMES)...)
+				require.NoError(t, validateInstanceDatabaseVersionsE(newSqlAdminAPI(t), projectId, instanceNames, testCase.newVersion))
+
+				publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+				db := testCase.openConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+				defer db.Close()
+
+				var numRows int
+				err := db.QueryRow(SQL_QUERY_ROW_COUNT).Scan(&numRows)
+				require.NoError(t, err, "Failed to count rows on the master, the %s may have dropped the table", kind)
+				assert.Equal(t, ENGINE_UPGRADE_ROW_COUNT, numRows, "The data on the master did not survive the %s", kind)
+
+				for _, replicaIp := range terraform.OutputList(t, terraformOptions, OUTPUT_READ_REPLICA_PUBLIC_IPS) {
+					replicaDb := testCase.openConnection(t, replicaIp, DB_USER, DB_PASS, DB_NAME)
+					defer replicaDb.Close()
+
+					retry.DoWithRetry(t, fmt.Sprintf("Waiting for the data to be replicated to %s", replicaIp), REPLICATION_MAX_RETRIES, REPLICATION_TIME_BETWEEN_RETRIES, func() (string, error) {
+						var numReplicaRows int
+						if err := replicaDb.QueryRow(SQL_QUERY_ROW_COUNT).Scan(&numReplicaRows); err != nil {
+							return "", err
+						}
+						if numReplicaRows != ENGINE_UPGRADE_ROW_COUNT {
+							return "", fmt.Errorf("found %d rows on the replica, expected %d", numReplicaRows, ENGINE_UPGRADE_ROW_COUNT)
+						}
+						return "", nil
+					})
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const NAME_PREFIX_CROSS_REGION_REPLICAS = "mysql-dr"
+
+func TestMySqlCrossRegionReplicas(t *testing.T) {
+	t.Parallel()
+
+	//os.Setenv("SKIP_bootstrap", "true")
+	//os.Setenv("SKIP_deploy", "true")
+	//os.Setenv("SKIP_validate_replica_regions", "true")
+	//os.Setenv("SKIP_teardown", "true")
+
+	reporter := newStageReporter(t)
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	// BOOTSTRAP VARIABLES FOR THE TESTS
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
+		region, drRegion := getTwoDistinctRandomRegions(t, projectId)
+
+		masterZone, failoverReplicaZone := getTwoDistinctRandomZonesForRegion(t, projectId, region)
+		readReplicaZone := gcp.GetRandomZoneForRegion(t, projectId, region)
+		drReadReplicaZone := gcp.GetRandomZoneForRegion(t, projectId, drRegion)
+
+		test_structure.SaveString(t, exampleDir, KEY_REGION, region)
+		test_structure.SaveString(t, exampleDir, KEY_DR_REGION, drRegion)
+		test_structure.SaveString(t, exampleDir, KEY_MASTER_ZONE, masterZone)
+		test_structure.SaveString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE, failoverReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_READ_REPLICA_ZONE, readReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_DR_READ_REPLICA_ZONE, drReadReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_PROJECT, projectId)
+	})
+
+	// AT THE END OF THE TESTS, RUN `terraform destroy`
+	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
+	defer reporter.RunTestStage("teardown", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		terraform.Destroy(t, terraformOptions)
+	})
+
+	reporter.RunTestStage("deploy", func() {
+		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
+		drRegion := test_structure.LoadString(t, exampleDir, KEY_DR_REGION)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+		masterZone := test_structure.LoadString(t, exampleDir, KEY_MASTER_ZONE)
+		failoverReplicaZone := test_structure.LoadString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE)
+		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
+		drReadReplicaZone := test_structure.LoadString(t, exampleDir, KEY_DR_READ_REPLICA_ZONE)
+
+		// One read replica next to the master and one in the DR region
+		terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, region, exampleDir, NAME_PREFIX_CROSS_REGION_REPLICAS, masterZone, failoverReplicaZone, 2, readReplicaZone)
+		terraformOptions.Vars["read_replica_zones"] = []string{readReplicaZone, drReadReplicaZone}
+		terraformOptions.Vars["read_replica_regions"] = []string{region, drRegion}
+		setInstanceNameOverride(t, terraformOptions)
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
+	})
+
+	// VALIDATE THAT THE PROXY CONNECTIONS POINT TO THE REGIONS THE REPLICAS LIVE IN
+	reporter.RunTestStage("validate_replica_regions", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
+		drRegion := test_structure.LoadString(t, exampleDir, KEY_DR_REGION)
+
+		readReplicaRegions := terraform.OutputList(t, terraformOptions, OUTPUT_READ_REPLICA_REGIONS)
+		readReplicaProxyConnections := terraform.OutputList(t, terraformOptions, OUTPUT_READ_REPLICA_PROXY_CONNECTIONS)
+		require.Len(t, readReplicaProxyConnections, 2)
+		assert.Equal(t, []string{region, drRegion}, readReplicaRegions)
+
+		api := newSqlAdminAPI(t)
+		for index, connection := range readReplicaProxyConnections {
+			logger.Logf(t, "Validating proxy connection %s of read replica %d", connection, index)
+			validateProxyConnectionRegion(t, api, connection)
+
+			_, connectionRegion, _, err := parseProxyConnectionE(connection)
+			require.NoError(t, err)
+			assert.Equal(t, readReplicaRegions[index], connectionRegion)
+		}
+
+		validateProxyConnectionRegion(t, api, terraform.Output(t, terraformOptions, OUTPUT_MASTER_PROXY_CONNECTION))
+		validateProxyConnectionRegion(t, api, terraform.Output(t, terraformOptions, OUTPUT_FAILOVER_PROXY_CONNECTION))
+	})
+}
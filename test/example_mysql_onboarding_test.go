@@ -0,0 +1,140 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+const NAME_PREFIX_ONBOARDING = "mysql-onboarding"
+
+const KEY_ONBOARDING = "onboarding"
+const KEY_ONBOARDING_DIR = "onboardingDir"
+
+const ONBOARDING_TFVARS_FILE = "terraform.tfvars"
+
+// TestMySqlOnboarding deploys the mysql-replicas example as a stand-in for a hand-made instance, generates the module
+// inputs and imports for it the same way cloud-sql-onboard does, imports it into a fresh copy of modules/cloud-sql and
+// checks that Terraform doesn't plan to change the instances.
+func TestMySqlOnboarding(t *testing.T) {
+	t.Parallel()
+
+	//os.Setenv("SKIP_bootstrap", "true")
+	//os.Setenv("SKIP_deploy", "true")
+	//os.Setenv("SKIP_generate", "true")
+	//os.Setenv("SKIP_import", "true")
+	//os.Setenv("SKIP_validate_plan", "true")
+	//os.Setenv("SKIP_teardown", "true")
+
+	reporter := newStageReporter(t)
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	// BOOTSTRAP VARIABLES FOR THE TESTS
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
+		region := getRandomRegion(t, projectId)
+
+		masterZone, failoverReplicaZone := getTwoDistinctRandomZonesForRegion(t, projectId, region)
+		readReplicaZone := gcp.GetRandomZoneForRegion(t, projectId, region)
+
+		test_structure.SaveString(t, exampleDir, KEY_REGION, region)
+		test_structure.SaveString(t, exampleDir, KEY_MASTER_ZONE, masterZone)
+		test_structure.SaveString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE, failoverReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_READ_REPLICA_ZONE, readReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_PROJECT, projectId)
+	})
+
+	// AT THE END OF THE TESTS, RUN `terraform destroy` IN THE EXAMPLE
+	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED. THE IMPORTED COPY
+	// OF THE MODULE MANAGES THE SAME INSTANCES, SO IT IS LEFT ALONE.
+	defer reporter.RunTestStage("teardown", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		terraform.Destroy(t, terraformOptions)
+	})
+
+	// DEPLOY THE INSTANCES TO ONBOARD
+	reporter.RunTestStage("deploy", func() {
+		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+		masterZone := test_structure.LoadString(t, exampleDir, KEY_MASTER_ZONE)
+		failoverReplicaZone := test_structure.LoadString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE)
+		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
+
+		terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, region, exampleDir, NAME_PREFIX_ONBOARDING, masterZone, failoverReplicaZone, 1, readReplicaZone)
+		setInstanceNameOverride(t, terraformOptions)
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
+	})
+
+	// GENERATE THE MODULE INPUTS AND IMPORTS FROM THE LIVE INSTANCES
+	reporter.RunTestStage("generate", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+		masterInstanceName := terraform.Output(t, terraformOptions, OUTPUT_MASTER_INSTANCE_NAME)
+
+		onboarding, err := cloudsql.GenerateOnboarding(newSqlAdminAPI(t), cloudsql.OnboardingOptions{
+			Project:        projectId,
+			Instance:       masterInstanceName,
+			DatabaseName:   DB_NAME,
+			MasterUserName: DB_USER,
+		})
+		require.NoError(t, err)
+		for _, warning := range onboarding.Warnings {
+			logger.Logf(t, "WARNING: %s", warning)
+		}
+
+		onboardingDir := test_structure.CopyTerraformFolderToTemp(t, "../", "modules/cloud-sql")
+		tfvars, err := os.Create(filepath.Join(onboardingDir, ONBOARDING_TFVARS_FILE))
+		require.NoError(t, err)
+		defer tfvars.Close()
+		require.NoError(t, cloudsql.WriteTfvars(tfvars, onboarding))
+
+		test_structure.SaveString(t, exampleDir, KEY_ONBOARDING_DIR, onboardingDir)
+		test_structure.SaveTestData(t, test_structure.FormatTestDataPath(exampleDir, KEY_ONBOARDING), onboarding)
+	})
+
+	reporter.RunTestStage("import", func() {
+		onboardingDir := test_structure.LoadString(t, exampleDir, KEY_ONBOARDING_DIR)
+		onboarding := cloudsql.Onboarding{}
+		test_structure.LoadTestData(t, test_structure.FormatTestDataPath(exampleDir, KEY_ONBOARDING), &onboarding)
+
+		terraformOptions := createOnboardingTerraformOptions(onboardingDir)
+		terraform.Init(t, terraformOptions)
+		for _, resource := range onboarding.Imports {
+			terraform.RunTerraformCommand(t, terraformOptions, "import", resource.Address, resource.ID)
+		}
+	})
+
+	// VALIDATE THAT THE MODULE MATCHES THE IMPORTED INSTANCES
+	reporter.RunTestStage("validate_plan", func() {
+		onboardingDir := test_structure.LoadString(t, exampleDir, KEY_ONBOARDING_DIR)
+		terraformOptions := createOnboardingTerraformOptions(onboardingDir)
+
+		plan := initAndPlanWithStruct(t, terraformOptions)
+		require.NoError(t, validateOnboardingPlanE(plan))
+	})
+}
+
+// createOnboardingTerraformOptions returns the options for the copy of the module the instances are imported to. The
+// inputs come from the generated tfvars and the environment, as `terraform import` doesn't accept -var flags after its
+// arguments.
+func createOnboardingTerraformOptions(onboardingDir string) *terraform.Options {
+	return setRetryableErrors(&terraform.Options{
+		TerraformDir: onboardingDir,
+		EnvVars: map[string]string{
+			"TF_VAR_master_user_password": DB_PASS,
+		},
+	})
+}
@@ -6,7 +6,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/gcp"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -15,19 +14,30 @@ import (
 const NAME_PREFIX_PRIVATE = "mysql-private"
 const EXAMPLE_NAME_PRIVATE = "mysql-private-ip"
 
+// The auto_increment_increment and auto_increment_offset flags of the example
+const AUTO_INCREMENT_PRIVATE = 6
+
 func TestMySqlPrivateIP(t *testing.T) {
+	// The lease has to be taken before t.Parallel(), so the network isn't destroyed before the other tests lease it
+	networkLease := privateNetworkFixture.Lease(t)
 	t.Parallel()
 
 	//os.Setenv("SKIP_bootstrap", "true")
 	//os.Setenv("SKIP_deploy", "true")
 	//os.Setenv("SKIP_validate_outputs", "true")
+	//os.Setenv("SKIP_deploy_bastion", "true")
+	//os.Setenv("SKIP_sql_tests", "true")
+	//os.Setenv("SKIP_teardown_bastion", "true")
 	//os.Setenv("SKIP_teardown", "true")
+	//os.Setenv("SKIP_teardown_private_network", "true")
+
+	reporter := newStageReporter(t)
 
 	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
 	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_PRIVATE)
 
-	test_structure.RunTestStage(t, "bootstrap", func() {
-		projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
 		region := getRandomRegion(t, projectId)
 
 		test_structure.SaveString(t, exampleDir, KEY_REGION, region)
@@ -35,21 +45,25 @@ func TestMySqlPrivateIP(t *testing.T) {
 	})
 
 	// At the end of the test, run `terraform destroy` to clean up any resources that were created
-	defer test_structure.RunTestStage(t, "teardown", func() {
+	defer reporter.RunTestStage("teardown", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 		terraform.Destroy(t, terraformOptions)
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	reporter.RunTestStage("deploy", func() {
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
 		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
-		terraformOptions := createTerratestOptionsForCloudSql(projectId, region, exampleDir, NAME_PREFIX_PRIVATE)
+		terraformOptions := createTerratestOptionsForCloudSql(t, projectId, region, exampleDir, NAME_PREFIX_PRIVATE)
+		terraformOptions.Vars["private_network"] = terraform.Output(t, networkLease.Options(t, projectId), OUTPUT_PRIVATE_NETWORK)
+		setInstanceNameOverride(t, terraformOptions)
 		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
 
-		terraform.InitAndApply(t, terraformOptions)
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
 	})
 
-	test_structure.RunTestStage(t, "validate_outputs", func() {
+	reporter.RunTestStage("validate_outputs", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
@@ -71,4 +85,24 @@ func TestMySqlPrivateIP(t *testing.T) {
 		assert.Equal(t, DB_NAME, dbNameFromOutput)
 		assert.Equal(t, expectedDBConn, proxyConnectionFromOutput)
 	})
+
+	// The bastion host is destroyed before the example, as its firewall rule blocks the deletion of the network
+	defer reporter.RunTestStage("teardown_bastion", func() {
+		destroyBastion(t, exampleDir)
+	})
+
+	reporter.RunTestStage("deploy_bastion", func() {
+		deployBastion(t, exampleDir)
+	})
+
+	// CONNECT TO THE PRIVATE IP THROUGH THE BASTION HOST
+	reporter.RunTestStage("sql_tests", func() {
+		tunnel := openBastionTunnel(t, exampleDir, MYSQL_PORT)
+		defer tunnel.Close()
+
+		db := openMySqlConnectionToAddress(t, tunnel.LocalAddress(), DB_USER, DB_PASS, DB_NAME)
+		defer db.Close()
+
+		runMySqlTableTests(t, db, AUTO_INCREMENT_PRIVATE)
+	})
 }
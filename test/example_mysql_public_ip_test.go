@@ -12,7 +12,6 @@ import (
 
 	mydialer "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/mysql"
 	"github.com/go-sql-driver/mysql"
-	"github.com/gruntwork-io/terratest/modules/gcp"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -24,27 +23,40 @@ const NAME_PREFIX_PUBLIC = "mysql-public"
 const EXAMPLE_NAME_PUBLIC = "mysql-public-ip"
 const EXAMPLE_NAME_CERT = "client-certificate"
 
+// The auto_increment_increment and auto_increment_offset flags of the example
+const AUTO_INCREMENT_PUBLIC = 5
+
+// A maintenance window other than the defaults of the module, to check that the inputs are applied
+var MAINTENANCE_WINDOW = maintenanceWindow{Day: 3, Hour: 2, Track: MAINTENANCE_TRACK_CANARY}
+
 func TestMySqlPublicIP(t *testing.T) {
 	t.Parallel()
 
 	//os.Setenv("SKIP_bootstrap", "true")
 	//os.Setenv("SKIP_deploy", "true")
 	//os.Setenv("SKIP_validate_outputs", "true")
+	//os.Setenv("SKIP_validate_maintenance_window", "true")
 	//os.Setenv("SKIP_sql_tests", "true")
 	//os.Setenv("SKIP_proxy_tests", "true")
+	//os.Setenv("SKIP_additional_users_tests", "true")
+	//os.Setenv("SKIP_least_privilege_tests", "true")
+	//os.Setenv("SKIP_stop_instance", "true")
+	//os.Setenv("SKIP_start_instance", "true")
 	//os.Setenv("SKIP_deploy_cert", "true")
 	//os.Setenv("SKIP_redeploy", "true")
 	//os.Setenv("SKIP_ssl_sql_tests", "true")
 	//os.Setenv("SKIP_teardown_cert", "true")
 	//os.Setenv("SKIP_teardown", "true")
 
+	reporter := newStageReporter(t)
+
 	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
 	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_PUBLIC)
 	certExampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_CERT)
 
 	// BOOTSTRAP VARIABLES FOR THE TESTS
-	test_structure.RunTestStage(t, "bootstrap", func() {
-		projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
 		region := getRandomRegion(t, projectId)
 
 		test_structure.SaveString(t, exampleDir, KEY_REGION, region)
@@ -53,27 +65,33 @@ func TestMySqlPublicIP(t *testing.T) {
 
 	// AT THE END OF THE TESTS, RUN `terraform destroy`
 	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
-	defer test_structure.RunTestStage(t, "teardown", func() {
+	defer reporter.RunTestStage("teardown", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 		terraform.Destroy(t, terraformOptions)
 	})
 
-	defer test_structure.RunTestStage(t, "teardown_cert", func() {
+	defer reporter.RunTestStage("teardown_cert", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, certExampleDir)
 		terraform.Destroy(t, terraformOptions)
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	reporter.RunTestStage("deploy", func() {
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
 		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
-		terraformOptions := createTerratestOptionsForCloudSql(projectId, region, exampleDir, NAME_PREFIX_PUBLIC)
+		terraformOptions := createTerratestOptionsForCloudSql(t, projectId, region, exampleDir, NAME_PREFIX_PUBLIC)
+		terraformOptions.Vars["additional_databases"] = createAdditionalDatabasesVar(MYSQL_ADDITIONAL_DB_CHARSET, MYSQL_ADDITIONAL_DB_COLLATION)
+		terraformOptions.Vars["additional_users"] = createAdditionalUsersVar()
+		setMaintenanceWindowVars(t, terraformOptions, MAINTENANCE_WINDOW)
+		setInstanceNameOverride(t, terraformOptions)
 		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
 
-		terraform.InitAndApply(t, terraformOptions)
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
 	})
 
 	// VALIDATE MODULE OUTPUTS
-	test_structure.RunTestStage(t, "validate_outputs", func() {
+	reporter.RunTestStage("validate_outputs", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
@@ -90,60 +108,29 @@ func TestMySqlPublicIP(t *testing.T) {
 		assert.Equal(t, expectedDBConn, proxyConnectionFromOutput)
 	})
 
-	// TEST REGULAR SQL CLIENT
-	test_structure.RunTestStage(t, "sql_tests", func() {
+	// VALIDATE THE MAINTENANCE WINDOW OF THE LIVE INSTANCE
+	reporter.RunTestStage("validate_maintenance_window", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
 
-		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		instanceName := terraform.Output(t, terraformOptions, OUTPUT_MASTER_INSTANCE_NAME)
+		validateInstanceMaintenanceWindow(t, newSqlAdminAPI(t), projectId, instanceName, MAINTENANCE_WINDOW)
+	})
 
-		connectionString := fmt.Sprintf("%s:%s@tcp(%s:3306)/%s", DB_USER, DB_PASS, publicIp, DB_NAME)
+	// TEST REGULAR SQL CLIENT
+	reporter.RunTestStage("sql_tests", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
-		// Does not actually open up the connection - just returns a DB ref
-		logger.Logf(t, "Connecting to: %s", publicIp)
-		db, err := sql.Open("mysql",
-			connectionString)
-		require.NoError(t, err, "Failed to open DB connection")
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
 
-		// Make sure we clean up properly
+		db := openMySqlConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
 		defer db.Close()
 
-		// Run ping to actually test the connection
-		logger.Log(t, "Ping the DB")
-		if err = db.Ping(); err != nil {
-			t.Fatalf("Failed to ping DB: %v", err)
-		}
-
-		// Create table if not exists
-		logger.Logf(t, "Create table: %s", MYSQL_CREATE_TEST_TABLE_WITH_AUTO_INCREMENT_STATEMENT)
-		if _, err = db.Exec(MYSQL_CREATE_TEST_TABLE_WITH_AUTO_INCREMENT_STATEMENT); err != nil {
-			t.Fatalf("Failed to create table: %v", err)
-		}
-
-		// Clean up
-		logger.Logf(t, "Empty table: %s", SQL_EMPTY_TEST_TABLE_STATEMENT)
-		if _, err = db.Exec(SQL_EMPTY_TEST_TABLE_STATEMENT); err != nil {
-			t.Fatalf("Failed to clean up table: %v", err)
-		}
-
-		// Insert data to check that our auto-increment flags worked
-		logger.Logf(t, "Insert data: %s", MYSQL_INSERT_TEST_ROW)
-		stmt, err := db.Prepare(MYSQL_INSERT_TEST_ROW)
-		require.NoError(t, err, "Failed to prepare statement")
-
-		// Execute the statement
-		res, err := stmt.Exec("Grunt")
-		require.NoError(t, err, "Failed to execute statement")
-
-		// Get the last insert id
-		lastId, err := res.LastInsertId()
-		require.NoError(t, err, "Failed to get last insert id")
-
-		// Since we set the auto increment to 5, modulus should always be 0
-		assert.Equal(t, int64(0), int64(lastId%5))
+		runMySqlTableTests(t, db, AUTO_INCREMENT_PUBLIC)
 	})
 
 	// TEST CLOUD SQL PROXY
-	test_structure.RunTestStage(t, "proxy_tests", func() {
+	reporter.RunTestStage("proxy_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		proxyConn := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PROXY_CONNECTION)
@@ -191,8 +178,124 @@ func TestMySqlPublicIP(t *testing.T) {
 		assert.Equal(t, int64(0), int64(lastId%5))
 	})
 
+	// TEST ADDITIONAL DATABASES AND USERS
+	reporter.RunTestStage("additional_users_tests", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		additionalDBNames := getAdditionalDBNames()
+
+		assert.ElementsMatch(t, additionalDBNames, terraform.OutputList(t, terraformOptions, OUTPUT_ADDITIONAL_DB_NAMES))
+		assert.Len(t, terraform.OutputList(t, terraformOptions, OUTPUT_ADDITIONAL_USER_NAMES), len(ADDITIONAL_DB_USERS))
+
+		// Every additional user should be able to log in to every database and see all of them
+		for user, password := range ADDITIONAL_DB_USERS {
+			for _, dbName := range additionalDBNames {
+				db := openMySqlConnection(t, publicIp, user, password, dbName)
+				visibleDBNames := getMySqlDatabaseNames(t, db)
+				db.Close()
+
+				assert.Subset(t, visibleDBNames, append(additionalDBNames, DB_NAME), "User %s doesn't see the expected databases", user)
+			}
+		}
+
+		// Verify the per-database charset and collation
+		db := openMySqlConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		defer db.Close()
+
+		charset, collation := getMySqlDatabaseCharset(t, db, ADDITIONAL_DB_NAME_APP)
+		assert.Equal(t, MYSQL_ADDITIONAL_DB_CHARSET, charset)
+		assert.Equal(t, MYSQL_ADDITIONAL_DB_COLLATION, collation)
+	})
+
+	// TEST LEAST-PRIVILEGE APPLICATION USERS
+	reporter.RunTestStage("least_privilege_tests", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+
+		masterDB := openMySqlConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		defer masterDB.Close()
+		createMySqlLeastPrivilegeUsers(t, masterDB, DB_NAME)
+
+		// The read-only user may read, but not write
+		readOnlyDB := openMySqlConnection(t, publicIp, DB_READ_ONLY_USER, DB_READ_ONLY_PASS, DB_NAME)
+		defer readOnlyDB.Close()
+
+		logger.Logf(t, "Insert data as %s: %s", DB_READ_ONLY_USER, MYSQL_INSERT_TEST_ROW)
+		_, err := readOnlyDB.Exec(MYSQL_INSERT_TEST_ROW, "ReadOnly")
+		require.Error(t, err, "Read-only user should not be able to insert data")
+		assert.True(t, isPermissionDeniedError(err), "Expected a permission error, got: %v", err)
+
+		var numRows int
+		err = readOnlyDB.QueryRow(SQL_QUERY_ROW_COUNT).Scan(&numRows)
+		require.NoError(t, err, "Read-only user should be able to select data")
+
+		// The read-write user may do both
+		readWriteDB := openMySqlConnection(t, publicIp, DB_READ_WRITE_USER, DB_READ_WRITE_PASS, DB_NAME)
+		defer readWriteDB.Close()
+
+		logger.Logf(t, "Insert data as %s: %s", DB_READ_WRITE_USER, MYSQL_INSERT_TEST_ROW)
+		_, err = readWriteDB.Exec(MYSQL_INSERT_TEST_ROW, "ReadWrite")
+		require.NoError(t, err, "Read-write user should be able to insert data")
+
+		var numRowsAfterInsert int
+		err = readWriteDB.QueryRow(SQL_QUERY_ROW_COUNT).Scan(&numRowsAfterInsert)
+		require.NoError(t, err, "Read-write user should be able to select data")
+		assert.Equal(t, numRows+1, numRowsAfterInsert)
+	})
+
+	// STOP THE INSTANCE, AS DEV ENVIRONMENTS DO OVERNIGHT TO SAVE COST
+	reporter.RunTestStage("stop_instance", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		instanceName := terraform.Output(t, terraformOptions, OUTPUT_MASTER_INSTANCE_NAME)
+
+		// Remember the data, to check that it survives the restart
+		db := openMySqlConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		var numRows int
+		err := db.QueryRow(SQL_QUERY_ROW_COUNT).Scan(&numRows)
+		db.Close()
+		require.NoError(t, err, "Failed to count rows")
+		test_structure.SaveInt(t, exampleDir, KEY_ROW_COUNT, numRows)
+
+		terraformOptions.Vars["activation_policy"] = ACTIVATION_POLICY_NEVER
+		terraform.Apply(t, terraformOptions)
+
+		validateInstanceActivationPolicy(t, newSqlAdminAPI(t), projectId, instanceName, ACTIVATION_POLICY_NEVER)
+
+		logger.Logf(t, "Connecting to stopped instance: %s", publicIp)
+		err = pingMySqlE(publicIp, DB_USER, DB_PASS, DB_NAME, STOPPED_INSTANCE_CONNECT_TIMEOUT)
+		require.Error(t, err, "Should not be able to connect to a stopped instance")
+		logger.Logf(t, "Failed to connect to stopped instance as expected: %v", err)
+	})
+
+	// START THE INSTANCE AGAIN
+	reporter.RunTestStage("start_instance", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		terraformOptions.Vars["activation_policy"] = ACTIVATION_POLICY_ALWAYS
+		terraform.Apply(t, terraformOptions)
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		instanceName := terraform.Output(t, terraformOptions, OUTPUT_MASTER_INSTANCE_NAME)
+
+		validateInstanceActivationPolicy(t, newSqlAdminAPI(t), projectId, instanceName, ACTIVATION_POLICY_ALWAYS)
+
+		db := openMySqlConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		defer db.Close()
+
+		var numRows int
+		err := db.QueryRow(SQL_QUERY_ROW_COUNT).Scan(&numRows)
+		require.NoError(t, err, "Failed to count rows after restart")
+		assert.Equal(t, test_structure.LoadInt(t, exampleDir, KEY_ROW_COUNT), numRows, "Data did not persist across the restart")
+	})
+
 	// CREATE CLIENT CERT
-	test_structure.RunTestStage(t, "deploy_cert", func() {
+	reporter.RunTestStage("deploy_cert", func() {
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
 		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
 
@@ -207,7 +310,7 @@ func TestMySqlPublicIP(t *testing.T) {
 	})
 
 	// REDEPLOY WITH FORCED SSL SETTINGS
-	test_structure.RunTestStage(t, "redeploy", func() {
+	reporter.RunTestStage("redeploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		// Force secure connections
@@ -216,7 +319,7 @@ func TestMySqlPublicIP(t *testing.T) {
 	})
 
 	// RUN TESTS WITH SECURED CONNECTION
-	test_structure.RunTestStage(t, "ssl_sql_tests", func() {
+	reporter.RunTestStage("ssl_sql_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 		terraformOptionsForCert := test_structure.LoadTerraformOptions(t, certExampleDir)
 
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const NAME_PREFIX_REPLICAS_MIGRATION = "mysql-migration"
+
+const KEY_BASELINE_DIR = "baselineDir"
+
+func TestMySqlReplicasStateMigration(t *testing.T) {
+	t.Parallel()
+
+	//os.Setenv("SKIP_bootstrap", "true")
+	//os.Setenv("SKIP_deploy_baseline", "true")
+	//os.Setenv("SKIP_migrate", "true")
+	//os.Setenv("SKIP_validate_migration", "true")
+	//os.Setenv("SKIP_validate_read_replicas_var", "true")
+	//os.Setenv("SKIP_teardown", "true")
+
+	reporter := newStageReporter(t)
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	// BOOTSTRAP VARIABLES FOR THE TESTS
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
+		region := getRandomRegion(t, projectId)
+
+		masterZone, failoverReplicaZone := getTwoDistinctRandomZonesForRegion(t, projectId, region)
+		readReplicaZone := gcp.GetRandomZoneForRegion(t, projectId, region)
+
+		test_structure.SaveString(t, exampleDir, KEY_REGION, region)
+		test_structure.SaveString(t, exampleDir, KEY_MASTER_ZONE, masterZone)
+		test_structure.SaveString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE, failoverReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_READ_REPLICA_ZONE, readReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_PROJECT, projectId)
+	})
+
+	// AT THE END OF THE TESTS, RUN `terraform destroy`
+	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
+	// The saved options point to the baseline until the state is migrated to the example
+	defer reporter.RunTestStage("teardown", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		terraform.Destroy(t, terraformOptions)
+	})
+
+	// DEPLOY THE EXAMPLE WITH THE COUNT BASED READ REPLICAS
+	reporter.RunTestStage("deploy_baseline", func() {
+		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+		masterZone := test_structure.LoadString(t, exampleDir, KEY_MASTER_ZONE)
+		failoverReplicaZone := test_structure.LoadString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE)
+		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
+
+		baselineDir := extractReadReplicaMigrationBaseline(t, EXAMPLE_NAME_REPLICAS)
+		test_structure.SaveString(t, exampleDir, KEY_BASELINE_DIR, baselineDir)
+
+		terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, region, baselineDir, NAME_PREFIX_REPLICAS_MIGRATION, masterZone, failoverReplicaZone, 2, readReplicaZone)
+		terraformOptions.Vars["read_replica_zones"] = []string{readReplicaZone, readReplicaZone}
+		removeUndeclaredVars(t, terraformOptions)
+		setInstanceNameOverride(t, terraformOptions)
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
+	})
+
+	// MOVE THE STATE TO THE CURRENT EXAMPLE AND MIGRATE THE READ REPLICAS
+	reporter.RunTestStage("migrate", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		baselineDir := test_structure.LoadString(t, exampleDir, KEY_BASELINE_DIR)
+
+		copyTerraformState(t, baselineDir, exampleDir)
+		terraformOptions.TerraformDir = exampleDir
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		terraform.Init(t, terraformOptions)
+		migrateReadReplicaState(t, terraformOptions)
+	})
+
+	// THE MIGRATED READ REPLICAS MUST NOT BE REPLACED
+	reporter.RunTestStage("validate_migration", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+		plan := initAndPlanWithStruct(t, terraformOptions)
+		for _, key := range []string{"read-0", "read-1"} {
+			assert.Contains(t, plan.ResourcePlannedValuesMap, `module.mysql.google_sql_database_instance.read_replica["`+key+`"]`)
+		}
+		// No instance is replaced, in-place updates of settings the baseline didn't manage are fine
+		require.NoError(t, validatePlannedInPlaceUpdatesE(plan, []string{}))
+	})
+
+	// THE MIGRATED READ REPLICAS MUST NOT BE REPLACED WHEN MOVING THEM TO THE read_replicas VARIABLE
+	reporter.RunTestStage("validate_read_replicas_var", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
+
+		terraformOptions.Vars["num_read_replicas"] = 0
+		terraformOptions.Vars["read_replicas"] = createIndexedReadReplicasVar([]string{readReplicaZone, readReplicaZone})
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		plan := initAndPlanWithStruct(t, terraformOptions)
+		for _, key := range []string{"read-0", "read-1"} {
+			assert.Contains(t, plan.ResourcePlannedValuesMap, `module.mysql.google_sql_database_instance.read_replica["`+key+`"]`)
+		}
+		require.NoError(t, validatePlannedInPlaceUpdatesE(plan, []string{}))
+	})
+}
@@ -0,0 +1,140 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const NAME_PREFIX_REPLICAS_RESIZE = "mysql-resize"
+
+// The sizes the instances are deployed with, i.e. the defaults of the example, and the sizes they are changed to
+const RESIZE_INITIAL_MACHINE_TYPE = "db-f1-micro"
+const RESIZE_INITIAL_DISK_SIZE = 10
+const RESIZE_MACHINE_TYPE = "db-g1-small"
+const RESIZE_DISK_SIZE = 20
+
+func TestMySqlReplicasResize(t *testing.T) {
+	t.Parallel()
+
+	//os.Setenv("SKIP_bootstrap", "true")
+	//os.Setenv("SKIP_deploy", "true")
+	//os.Setenv("SKIP_resize", "true")
+	//os.Setenv("SKIP_validate_resize", "true")
+	//os.Setenv("SKIP_reject_disk_shrink", "true")
+	//os.Setenv("SKIP_teardown", "true")
+
+	reporter := newStageReporter(t)
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	// BOOTSTRAP VARIABLES FOR THE TESTS
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
+		region := getRandomRegion(t, projectId)
+
+		masterZone, failoverReplicaZone := getTwoDistinctRandomZonesForRegion(t, projectId, region)
+		readReplicaZone := gcp.GetRandomZoneForRegion(t, projectId, region)
+
+		test_structure.SaveString(t, exampleDir, KEY_REGION, region)
+		test_structure.SaveString(t, exampleDir, KEY_MASTER_ZONE, masterZone)
+		test_structure.SaveString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE, failoverReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_READ_REPLICA_ZONE, readReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_PROJECT, projectId)
+	})
+
+	// AT THE END OF THE TESTS, RUN `terraform destroy`
+	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
+	defer reporter.RunTestStage("teardown", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		terraform.Destroy(t, terraformOptions)
+	})
+
+	reporter.RunTestStage("deploy", func() {
+		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+		masterZone := test_structure.LoadString(t, exampleDir, KEY_MASTER_ZONE)
+		failoverReplicaZone := test_structure.LoadString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE)
+		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
+
+		terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, region, exampleDir, NAME_PREFIX_REPLICAS_RESIZE, masterZone, failoverReplicaZone, 1, readReplicaZone)
+		terraformOptions.Vars["machine_type"] = RESIZE_INITIAL_MACHINE_TYPE
+		terraformOptions.Vars["disk_size"] = RESIZE_INITIAL_DISK_SIZE
+		setInstanceNameOverride(t, terraformOptions)
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		db := openMySqlConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		defer db.Close()
+
+		_, err := db.Exec(MYSQL_CREATE_TEST_TABLE_WITH_AUTO_INCREMENT_STATEMENT)
+		require.NoError(t, err, "Failed to create table")
+	})
+
+	// CHANGE THE MACHINE TYPE AND GROW THE DISKS, WHILE WRITING TO THE MASTER
+	reporter.RunTestStage("resize", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		// Pre-flight check, the disks can only grow
+		require.NoError(t, validateDiskSizeIncreaseE(newSqlAdminAPI(t), projectId, getMySqlReplicasInstanceNames(t, terraformOptions), RESIZE_DISK_SIZE))
+
+		terraformOptions.Vars["machine_type"] = RESIZE_MACHINE_TYPE
+		terraformOptions.Vars["disk_size"] = RESIZE_DISK_SIZE
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		readReplicaKeys := []string{}
+		for key := range terraform.OutputMap(t, terraformOptions, OUTPUT_READ_REPLICA_INSTANCE_NAMES_BY_KEY) {
+			readReplicaKeys = append(readReplicaKeys, key)
+		}
+		plan := initAndPlanWithStruct(t, terraformOptions)
+		require.NoError(t, validatePlannedInPlaceUpdatesE(plan, getResizedSqlInstanceAddresses("mysql", readReplicaKeys)))
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		db := openMySqlConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		defer db.Close()
+
+		probe := startWriteProbe(newMySqlWriteProbeFunc(db), WRITE_PROBE_INTERVAL)
+		terraform.Apply(t, terraformOptions)
+		result := probe.Stop()
+
+		logger.Logf(t, "Writes were unavailable for up to %s during the resize, %d of %d writes failed", result.LongestOutage, result.FailedWrites, result.Writes)
+		assert.NoError(t, result.LastWriteErr, "Writes did not recover after the resize")
+	})
+
+	// VALIDATE THAT ALL INSTANCES WERE RESIZED IN PLACE
+	reporter.RunTestStage("validate_resize", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		err := validateInstanceSizesE(newSqlAdminAPI(t), projectId, getMySqlReplicasInstanceNames(t, terraformOptions), RESIZE_MACHINE_TYPE, RESIZE_DISK_SIZE)
+		require.NoError(t, err)
+
+		// The rows written during the resize are still there
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		db := openMySqlConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		defer db.Close()
+		assert.True(t, countMySqlRowsByName(t, db, WRITE_PROBE_ROW_NAME) > 0)
+	})
+
+	// A DISK SHRINK IS REJECTED BEFORE ANYTHING IS APPLIED
+	reporter.RunTestStage("reject_disk_shrink", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		err := validateDiskSizeIncreaseE(newSqlAdminAPI(t), projectId, getMySqlReplicasInstanceNames(t, terraformOptions), RESIZE_INITIAL_DISK_SIZE)
+		require.Error(t, err, "Shrinking the disks should be rejected")
+		logger.Logf(t, "Disk shrink rejected as expected: %v", err)
+	})
+}
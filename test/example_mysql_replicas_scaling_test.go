@@ -0,0 +1,140 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const NAME_PREFIX_REPLICAS_SCALING = "mysql-scaling"
+
+const KEY_READ_REPLICA_NAMES = "readReplicaNames"
+
+// The read replicas are created with the deprecated num_read_replicas variable, which keys them by index
+const SCALING_NUM_READ_REPLICAS = 3
+
+// Removing the replica in the middle, by moving the others to the read_replicas variable, must not affect the others
+const SCALING_REMOVED_READ_REPLICA_KEY = "read-1"
+
+func TestMySqlReplicasScaleDown(t *testing.T) {
+	t.Parallel()
+
+	//os.Setenv("SKIP_bootstrap", "true")
+	//os.Setenv("SKIP_deploy", "true")
+	//os.Setenv("SKIP_scale_down", "true")
+	//os.Setenv("SKIP_validate_scale_down", "true")
+	//os.Setenv("SKIP_teardown", "true")
+
+	reporter := newStageReporter(t)
+
+	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
+	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
+
+	// BOOTSTRAP VARIABLES FOR THE TESTS
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
+		region := getRandomRegion(t, projectId)
+
+		masterZone, failoverReplicaZone := getTwoDistinctRandomZonesForRegion(t, projectId, region)
+		readReplicaZone := gcp.GetRandomZoneForRegion(t, projectId, region)
+
+		test_structure.SaveString(t, exampleDir, KEY_REGION, region)
+		test_structure.SaveString(t, exampleDir, KEY_MASTER_ZONE, masterZone)
+		test_structure.SaveString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE, failoverReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_READ_REPLICA_ZONE, readReplicaZone)
+		test_structure.SaveString(t, exampleDir, KEY_PROJECT, projectId)
+	})
+
+	// AT THE END OF THE TESTS, RUN `terraform destroy`
+	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
+	defer reporter.RunTestStage("teardown", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		terraform.Destroy(t, terraformOptions)
+	})
+
+	reporter.RunTestStage("deploy", func() {
+		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+		masterZone := test_structure.LoadString(t, exampleDir, KEY_MASTER_ZONE)
+		failoverReplicaZone := test_structure.LoadString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE)
+		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
+
+		terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, region, exampleDir, NAME_PREFIX_REPLICAS_SCALING, masterZone, failoverReplicaZone, SCALING_NUM_READ_REPLICAS, readReplicaZone)
+		terraformOptions.Vars["read_replica_zones"] = getScalingReadReplicaZones(readReplicaZone)
+		setInstanceNameOverride(t, terraformOptions)
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
+
+		readReplicaNames := terraform.OutputMap(t, terraformOptions, OUTPUT_READ_REPLICA_INSTANCE_NAMES_BY_KEY)
+		require.Len(t, readReplicaNames, SCALING_NUM_READ_REPLICAS)
+		test_structure.SaveTestData(t, test_structure.FormatTestDataPath(exampleDir, KEY_READ_REPLICA_NAMES), readReplicaNames)
+	})
+
+	// REMOVE THE READ REPLICA IN THE MIDDLE
+	reporter.RunTestStage("scale_down", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
+
+		readReplicas := createIndexedReadReplicasVar(getScalingReadReplicaZones(readReplicaZone))
+		delete(readReplicas, SCALING_REMOVED_READ_REPLICA_KEY)
+		terraformOptions.Vars["num_read_replicas"] = 0
+		terraformOptions.Vars["read_replicas"] = readReplicas
+		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
+
+		// Only the removed replica may be destroyed, all other instances have to stay untouched
+		plan := initAndPlanWithStruct(t, terraformOptions)
+		removedReplica := fmt.Sprintf(`module.mysql.google_sql_database_instance.read_replica["%s"]`, SCALING_REMOVED_READ_REPLICA_KEY)
+		assert.Equal(t, map[string]tfjson.Actions{removedReplica: {tfjson.ActionDelete}}, getPlannedSqlInstanceChanges(plan))
+
+		terraform.Apply(t, terraformOptions)
+	})
+
+	// VALIDATE THAT THE OTHER READ REPLICAS SURVIVED
+	reporter.RunTestStage("validate_scale_down", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		originalReplicaNames := map[string]string{}
+		test_structure.LoadTestData(t, test_structure.FormatTestDataPath(exampleDir, KEY_READ_REPLICA_NAMES), &originalReplicaNames)
+
+		readReplicaNames := terraform.OutputMap(t, terraformOptions, OUTPUT_READ_REPLICA_INSTANCE_NAMES_BY_KEY)
+		api := newSqlAdminAPI(t)
+
+		for key, instanceName := range originalReplicaNames {
+			instance, err := api.GetInstance(projectId, instanceName)
+
+			if key == SCALING_REMOVED_READ_REPLICA_KEY {
+				assert.True(t, cloudsql.IsNotFound(err), "Expected %s to be deleted, got %v", instanceName, err)
+				assert.NotContains(t, readReplicaNames, key)
+				continue
+			}
+
+			require.NoError(t, err, "Expected %s to still exist", instanceName)
+			logger.Logf(t, "Read replica %s is %s", instanceName, instance.State)
+			assert.Equal(t, instanceName, readReplicaNames[key])
+			assert.True(t, strings.HasSuffix(instanceName, "-"+key))
+		}
+	})
+}
+
+// getScalingReadReplicaZones returns the read_replica_zones of the scaling test, placing all replicas in the given zone.
+func getScalingReadReplicaZones(zone string) []string {
+	zones := []string{}
+	for i := 0; i < SCALING_NUM_READ_REPLICAS; i++ {
+		zones = append(zones, zone)
+	}
+	return zones
+}
@@ -3,6 +3,7 @@ package test
 import (
 	"database/sql"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -18,24 +19,41 @@ import (
 const NAME_PREFIX_REPLICAS = "mysql-replicas"
 const EXAMPLE_NAME_REPLICAS = "mysql-replicas"
 
+// The read replica that is promoted in the disaster recovery stage
+const PROMOTED_READ_REPLICA_KEY = "read-0"
+
 func TestMySqlReplicas(t *testing.T) {
 	t.Parallel()
 
 	//os.Setenv("SKIP_bootstrap", "true")
 	//os.Setenv("SKIP_deploy", "true")
 	//os.Setenv("SKIP_validate_outputs", "true")
+	//os.Setenv("SKIP_validate_encryption", "true")
+	//os.Setenv("SKIP_validate_query_insights", "true")
+	//os.Setenv("SKIP_validate_labels", "true")
 	//os.Setenv("SKIP_sql_tests", "true")
 	//os.Setenv("SKIP_read_replica_tests", "true")
+	//os.Setenv("SKIP_promote_read_replica", "true")
 	//os.Setenv("SKIP_teardown", "true")
 
+	reporter := newStageReporter(t)
+
 	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
 	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_REPLICAS)
 
 	// BOOTSTRAP VARIABLES FOR THE TESTS
-	test_structure.RunTestStage(t, "bootstrap", func() {
-		projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
 		region := getRandomRegion(t, projectId)
 
+		// Customer-managed keys are regional, so the instances have to be created in the region of the key
+		encryptionKeyName := os.Getenv(ENV_VAR_CMEK_KEY_NAME)
+		if encryptionKeyName != "" {
+			keyRegion, err := getEncryptionKeyRegionE(encryptionKeyName)
+			require.NoError(t, err, "Invalid %s", ENV_VAR_CMEK_KEY_NAME)
+			region = keyRegion
+		}
+
 		masterZone, failoverReplicaZone := getTwoDistinctRandomZonesForRegion(t, projectId, region)
 		readReplicaZone := gcp.GetRandomZoneForRegion(t, projectId, region)
 
@@ -44,29 +62,38 @@ func TestMySqlReplicas(t *testing.T) {
 		test_structure.SaveString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE, failoverReplicaZone)
 		test_structure.SaveString(t, exampleDir, KEY_READ_REPLICA_ZONE, readReplicaZone)
 		test_structure.SaveString(t, exampleDir, KEY_PROJECT, projectId)
+		test_structure.SaveString(t, exampleDir, KEY_ENCRYPTION_KEY_NAME, encryptionKeyName)
 	})
 
 	// AT THE END OF THE TESTS, RUN `terraform destroy`
 	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
-	defer test_structure.RunTestStage(t, "teardown", func() {
+	defer reporter.RunTestStage("teardown", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 		terraform.Destroy(t, terraformOptions)
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	reporter.RunTestStage("deploy", func() {
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
 		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
 		masterZone := test_structure.LoadString(t, exampleDir, KEY_MASTER_ZONE)
 		failoverReplicaZone := test_structure.LoadString(t, exampleDir, KEY_FAILOVER_REPLICA_ZONE)
 		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
-		terraformOptions := createTerratestOptionsForCloudSqlReplicas(projectId, region, exampleDir, NAME_PREFIX_REPLICAS, masterZone, failoverReplicaZone, 1, readReplicaZone)
+		encryptionKeyName := test_structure.LoadString(t, exampleDir, KEY_ENCRYPTION_KEY_NAME)
+		terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, region, exampleDir, NAME_PREFIX_REPLICAS, masterZone, failoverReplicaZone, 1, readReplicaZone)
+		if encryptionKeyName != "" {
+			terraformOptions.Vars["encryption_key_name"] = encryptionKeyName
+		}
+		setQueryInsightsVars(terraformOptions)
+		setInstanceNameOverride(t, terraformOptions)
 		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
 
-		terraform.InitAndApply(t, terraformOptions)
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
 	})
 
 	// VALIDATE MODULE OUTPUTS
-	test_structure.RunTestStage(t, "validate_outputs", func() {
+	reporter.RunTestStage("validate_outputs", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
@@ -104,8 +131,47 @@ func TestMySqlReplicas(t *testing.T) {
 		assert.Equal(t, expectedReadReplicaDBConn, readReplicaProxyConnectionFromOutput)
 	})
 
+	// VALIDATE THAT ALL INSTANCES ARE ENCRYPTED WITH THE CUSTOMER-MANAGED KEY
+	reporter.RunTestStage("validate_encryption", func() {
+		encryptionKeyName := test_structure.LoadString(t, exampleDir, KEY_ENCRYPTION_KEY_NAME)
+		if encryptionKeyName == "" {
+			logger.Logf(t, "%s is not set, skipping customer-managed encryption key validation", ENV_VAR_CMEK_KEY_NAME)
+			return
+		}
+
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		api := newSqlAdminAPI(t)
+		for _, instanceName := range getMySqlReplicasInstanceNames(t, terraformOptions) {
+			logger.Logf(t, "Validating encryption key of instance %s", instanceName)
+			validateInstanceEncryptionKey(t, api, projectId, instanceName, encryptionKeyName)
+		}
+	})
+
+	// VALIDATE THAT THE QUERY INSIGHTS CONFIGURATION ROUND-TRIPS ON ALL INSTANCES
+	reporter.RunTestStage("validate_query_insights", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		api := newSqlAdminAPI(t)
+		for _, instanceName := range getMySqlReplicasInstanceNames(t, terraformOptions) {
+			logger.Logf(t, "Validating Query Insights configuration of instance %s", instanceName)
+			validateInstanceInsightsConfig(t, api, projectId, instanceName, QUERY_INSIGHTS_CONFIG)
+		}
+	})
+
+	// VALIDATE THAT THE RUN LABELS PROPAGATE TO ALL INSTANCES
+	reporter.RunTestStage("validate_labels", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		instanceNames := getMySqlReplicasInstanceNames(t, terraformOptions)
+		validateInstanceLabels(t, newSqlAdminAPI(t), projectId, instanceNames, getRunLabels(t, terraformOptions))
+	})
+
 	// TEST REGULAR SQL CLIENT
-	test_structure.RunTestStage(t, "sql_tests", func() {
+	reporter.RunTestStage("sql_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
@@ -156,7 +222,7 @@ func TestMySqlReplicas(t *testing.T) {
 	})
 
 	// TEST READ REPLICA WITH REGULAR SQL CLIENT
-	test_structure.RunTestStage(t, "read_replica_tests", func() {
+	reporter.RunTestStage("read_replica_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		readReplicaPublicIpList := terraform.OutputList(t, terraformOptions, OUTPUT_READ_REPLICA_PUBLIC_IPS)
@@ -205,4 +271,63 @@ func TestMySqlReplicas(t *testing.T) {
 		logger.Logf(t, "Number of rows... just for fun: %v", numResults)
 
 	})
+
+	// PROMOTE THE FIRST READ REPLICA TO A STANDALONE INSTANCE, AS IN A DISASTER RECOVERY
+	// This has to be the last stage, as the replica stops replicating from the master afterwards
+	reporter.RunTestStage("promote_read_replica", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
+
+		masterPublicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		readReplicaInstanceName := terraform.OutputMap(t, terraformOptions, OUTPUT_READ_REPLICA_INSTANCE_NAMES_BY_KEY)[PROMOTED_READ_REPLICA_KEY]
+		require.NotEmpty(t, readReplicaInstanceName, "Read replica %s not found", PROMOTED_READ_REPLICA_KEY)
+
+		// Connect to the promoted replica itself, rather than relying on the order of the read replica outputs
+		api := newSqlAdminAPI(t)
+		readReplicaPublicIp := getInstancePublicIp(t, api, projectId, readReplicaInstanceName)
+
+		// Write data on the master and wait for it to reach the replica
+		masterDb := openMySqlConnection(t, masterPublicIp, DB_USER, DB_PASS, DB_NAME)
+		defer masterDb.Close()
+
+		logger.Logf(t, "Insert data: %s", MYSQL_INSERT_TEST_ROW)
+		_, err := masterDb.Exec(MYSQL_INSERT_TEST_ROW, PROMOTION_MARKER_ROW_NAME)
+		require.NoError(t, err, "Failed to insert marker row on master")
+
+		readReplicaDb := openMySqlConnection(t, readReplicaPublicIp, DB_USER, DB_PASS, DB_NAME)
+		defer readReplicaDb.Close()
+		waitForMySqlRowReplicated(t, readReplicaDb, PROMOTION_MARKER_ROW_NAME)
+
+		promoteReadReplica(t, api, projectId, readReplicaInstanceName)
+
+		instance := getSqlInstance(t, api, projectId, readReplicaInstanceName)
+		assert.Empty(t, instance.MasterInstanceName, "Promoted instance still has a master")
+
+		// The promoted instance has to be writable and retain the replicated data. It restarted during the promotion,
+		// so we need a new connection.
+		promotedDb := openMySqlConnection(t, readReplicaPublicIp, DB_USER, DB_PASS, DB_NAME)
+		defer promotedDb.Close()
+
+		_, err = promotedDb.Exec(MYSQL_INSERT_TEST_ROW, PROMOTED_ROW_NAME)
+		require.NoError(t, err, "Failed to write to promoted instance")
+		assert.Equal(t, 1, countMySqlRowsByName(t, promotedDb, PROMOTION_MARKER_ROW_NAME))
+		assert.Equal(t, 1, countMySqlRowsByName(t, promotedDb, PROMOTED_ROW_NAME))
+
+		// Terraform still manages the promoted instance as a read replica. Report what it would do to reconcile, so
+		// the runbook can tell whether to remove the instance from the state or let Terraform recreate the replica.
+		plan := initAndPlanWithStruct(t, terraformOptions)
+		logPlannedSqlInstanceChanges(t, plan)
+
+		promotedAddress := fmt.Sprintf(`module.mysql.google_sql_database_instance.read_replica["%s"]`, PROMOTED_READ_REPLICA_KEY)
+		assert.Contains(t, getPlannedSqlInstanceChanges(plan), promotedAddress, "Expected terraform plan to detect the promotion")
+	})
+}
+
+// getMySqlReplicasInstanceNames returns the names of the master, failover replica and read replicas of the example.
+func getMySqlReplicasInstanceNames(t *testing.T, terraformOptions *terraform.Options) []string {
+	instanceNames := []string{
+		terraform.Output(t, terraformOptions, OUTPUT_MASTER_INSTANCE_NAME),
+		terraform.Output(t, terraformOptions, OUTPUT_FAILOVER_INSTANCE_NAME),
+	}
+	return append(instanceNames, terraform.OutputList(t, terraformOptions, OUTPUT_READ_REPLICA_INSTANCE_NAMES)...)
 }
@@ -3,13 +3,13 @@ package test
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/postgres"
-	"github.com/gruntwork-io/terratest/modules/gcp"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -29,48 +29,62 @@ func TestPostgresPublicIP(t *testing.T) {
 	//os.Setenv("SKIP_validate_outputs", "true")
 	//os.Setenv("SKIP_sql_tests", "true")
 	//os.Setenv("SKIP_proxy_tests", "true")
+	//os.Setenv("SKIP_additional_users_tests", "true")
+	//os.Setenv("SKIP_least_privilege_tests", "true")
+	//os.Setenv("SKIP_iam_auth_tests", "true")
 	//os.Setenv("SKIP_deploy_cert", "true")
 	//os.Setenv("SKIP_redeploy", "true")
 	//os.Setenv("SKIP_ssl_sql_tests", "true")
 	//os.Setenv("SKIP_teardown_cert", "true")
 	//os.Setenv("SKIP_teardown", "true")
 
+	reporter := newStageReporter(t)
+
 	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
 	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_POSTGRES_PUBLIC)
 	certExampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_CERT)
 
 	// BOOTSTRAP VARIABLES FOR THE TESTS
-	test_structure.RunTestStage(t, "bootstrap", func() {
-		projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
 		region := getRandomRegion(t, projectId)
 
 		test_structure.SaveString(t, exampleDir, KEY_REGION, region)
 		test_structure.SaveString(t, exampleDir, KEY_PROJECT, projectId)
+		test_structure.SaveString(t, exampleDir, KEY_IAM_USER_EMAIL, getIamPrincipalEmail(t))
 	})
 
 	// AT THE END OF THE TESTS, RUN `terraform destroy`
 	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
-	defer test_structure.RunTestStage(t, "teardown", func() {
+	defer reporter.RunTestStage("teardown", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 		terraform.Destroy(t, terraformOptions)
 	})
 
-	defer test_structure.RunTestStage(t, "teardown_cert", func() {
+	defer reporter.RunTestStage("teardown_cert", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, certExampleDir)
 		terraform.Destroy(t, terraformOptions)
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	reporter.RunTestStage("deploy", func() {
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
 		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
-		terraformOptions := createTerratestOptionsForCloudSql(projectId, region, exampleDir, NAME_PREFIX_POSTGRES_PUBLIC)
+		terraformOptions := createTerratestOptionsForCloudSql(t, projectId, region, exampleDir, NAME_PREFIX_POSTGRES_PUBLIC)
+		terraformOptions.Vars["additional_databases"] = createAdditionalDatabasesVar(POSTGRES_ADDITIONAL_DB_CHARSET, POSTGRES_ADDITIONAL_DB_COLLATION)
+		terraformOptions.Vars["additional_users"] = createAdditionalUsersVar()
+		terraformOptions.Vars["iam_users"] = []map[string]string{
+			{"email": test_structure.LoadString(t, exampleDir, KEY_IAM_USER_EMAIL)},
+		}
+		setInstanceNameOverride(t, terraformOptions)
 		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
 
-		terraform.InitAndApply(t, terraformOptions)
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
 	})
 
 	// VALIDATE MODULE OUTPUTS
-	test_structure.RunTestStage(t, "validate_outputs", func() {
+	reporter.RunTestStage("validate_outputs", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
@@ -88,49 +102,19 @@ func TestPostgresPublicIP(t *testing.T) {
 	})
 
 	// TEST REGULAR SQL CLIENT
-	test_structure.RunTestStage(t, "sql_tests", func() {
+	reporter.RunTestStage("sql_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
 
-		connectionString := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", DB_USER, DB_PASS, publicIp, DB_NAME)
-
-		// Does not actually open up the connection - just returns a DB ref
-		logger.Logf(t, "Connecting to: %s", publicIp)
-		db, err := sql.Open("postgres", connectionString)
-		require.NoError(t, err, "Failed to open DB connection")
-
-		// Make sure we clean up properly
+		db := openPostgresConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
 		defer db.Close()
 
-		// Run ping to actually test the connection
-		logger.Log(t, "Ping the DB")
-		if err = db.Ping(); err != nil {
-			t.Fatalf("Failed to ping DB: %v", err)
-		}
-
-		// Create table if not exists
-		logger.Logf(t, "Create table: %s", POSTGRES_CREATE_TEST_TABLE_WITH_SERIAL)
-		if _, err = db.Exec(POSTGRES_CREATE_TEST_TABLE_WITH_SERIAL); err != nil {
-			t.Fatalf("Failed to create table: %v", err)
-		}
-
-		// Clean up
-		logger.Logf(t, "Empty table: %s", SQL_EMPTY_TEST_TABLE_STATEMENT)
-		if _, err = db.Exec(SQL_EMPTY_TEST_TABLE_STATEMENT); err != nil {
-			t.Fatalf("Failed to clean up table: %v", err)
-		}
-
-		logger.Logf(t, "Insert data: %s", POSTGRES_INSERT_TEST_ROW)
-		var testid int
-		err = db.QueryRow(POSTGRES_INSERT_TEST_ROW).Scan(&testid)
-		require.NoError(t, err, "Failed to insert data")
-
-		assert.True(t, testid > 0, "Data was inserted")
+		runPostgresTableTests(t, db)
 	})
 
 	// TEST CLOUD SQL PROXY
-	test_structure.RunTestStage(t, "proxy_tests", func() {
+	reporter.RunTestStage("proxy_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		proxyConn := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PROXY_CONNECTION)
@@ -164,8 +148,99 @@ func TestPostgresPublicIP(t *testing.T) {
 		assert.True(t, testid > 0, "Assert data was inserted")
 	})
 
+	// TEST ADDITIONAL DATABASES AND USERS
+	reporter.RunTestStage("additional_users_tests", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		additionalDBNames := getAdditionalDBNames()
+
+		assert.ElementsMatch(t, additionalDBNames, terraform.OutputList(t, terraformOptions, OUTPUT_ADDITIONAL_DB_NAMES))
+		assert.Len(t, terraform.OutputList(t, terraformOptions, OUTPUT_ADDITIONAL_USER_NAMES), len(ADDITIONAL_DB_USERS))
+
+		// Every additional user should be able to log in to every database and see all of them
+		for user, password := range ADDITIONAL_DB_USERS {
+			for _, dbName := range additionalDBNames {
+				db := openPostgresConnection(t, publicIp, user, password, dbName)
+				visibleDBNames := getPostgresDatabaseNames(t, db)
+				db.Close()
+
+				assert.Subset(t, visibleDBNames, append(additionalDBNames, DB_NAME), "User %s doesn't see the expected databases", user)
+			}
+		}
+
+		// Verify the per-database charset and collation
+		db := openPostgresConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		defer db.Close()
+
+		charset, collation := getPostgresDatabaseCharset(t, db, ADDITIONAL_DB_NAME_APP)
+		assert.Equal(t, POSTGRES_ADDITIONAL_DB_CHARSET, charset)
+		assert.Equal(t, POSTGRES_ADDITIONAL_DB_COLLATION, collation)
+	})
+
+	// TEST LEAST-PRIVILEGE APPLICATION USERS
+	reporter.RunTestStage("least_privilege_tests", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+
+		masterDB := openPostgresConnection(t, publicIp, DB_USER, DB_PASS, DB_NAME)
+		defer masterDB.Close()
+		createPostgresLeastPrivilegeUsers(t, masterDB, DB_NAME)
+
+		// The read-only user may read, but not write
+		readOnlyDB := openPostgresConnection(t, publicIp, DB_READ_ONLY_USER, DB_READ_ONLY_PASS, DB_NAME)
+		defer readOnlyDB.Close()
+
+		logger.Logf(t, "Insert data as %s: %s", DB_READ_ONLY_USER, POSTGRES_INSERT_TEST_ROW)
+		var testid int
+		err := readOnlyDB.QueryRow(POSTGRES_INSERT_TEST_ROW).Scan(&testid)
+		require.Error(t, err, "Read-only user should not be able to insert data")
+		assert.True(t, isPermissionDeniedError(err), "Expected a permission error, got: %v", err)
+
+		var numRows int
+		err = readOnlyDB.QueryRow(SQL_QUERY_ROW_COUNT).Scan(&numRows)
+		require.NoError(t, err, "Read-only user should be able to select data")
+
+		// The read-write user may do both
+		readWriteDB := openPostgresConnection(t, publicIp, DB_READ_WRITE_USER, DB_READ_WRITE_PASS, DB_NAME)
+		defer readWriteDB.Close()
+
+		logger.Logf(t, "Insert data as %s: %s", DB_READ_WRITE_USER, POSTGRES_INSERT_TEST_ROW)
+		err = readWriteDB.QueryRow(POSTGRES_INSERT_TEST_ROW).Scan(&testid)
+		require.NoError(t, err, "Read-write user should be able to insert data")
+
+		var numRowsAfterInsert int
+		err = readWriteDB.QueryRow(SQL_QUERY_ROW_COUNT).Scan(&numRowsAfterInsert)
+		require.NoError(t, err, "Read-write user should be able to select data")
+		assert.Equal(t, numRows+1, numRowsAfterInsert)
+	})
+
+	// TEST IAM DATABASE AUTHENTICATION
+	reporter.RunTestStage("iam_auth_tests", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
+
+		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
+		iamUserEmail := test_structure.LoadString(t, exampleDir, KEY_IAM_USER_EMAIL)
+		expectedUserName := getIamDatabaseUserName(true, iamUserEmail)
+
+		iamUserNames := terraform.OutputMap(t, terraformOptions, OUTPUT_IAM_USER_NAMES)
+		assert.Equal(t, map[string]string{iamUserEmail: expectedUserName}, iamUserNames)
+
+		// Log in with an OAuth 2.0 access token as password instead of DB_PASS
+		password := getIamDatabasePassword(t, newIamTokenSource(t))
+		connectionString := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=require", url.QueryEscape(expectedUserName), url.QueryEscape(password), publicIp, DB_NAME)
+		db := openConnection(t, "postgres", connectionString, publicIp, expectedUserName, DB_NAME)
+		defer db.Close()
+
+		var currentUser string
+		err := db.QueryRow("SELECT current_user").Scan(&currentUser)
+		require.NoError(t, err, "Failed to query current user")
+		assert.Equal(t, expectedUserName, currentUser)
+	})
+
 	// CREATE CLIENT CERT
-	test_structure.RunTestStage(t, "deploy_cert", func() {
+	reporter.RunTestStage("deploy_cert", func() {
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
 		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
 
@@ -180,7 +255,7 @@ func TestPostgresPublicIP(t *testing.T) {
 	})
 
 	// REDEPLOY WITH FORCED SSL SETTINGS
-	test_structure.RunTestStage(t, "redeploy", func() {
+	reporter.RunTestStage("redeploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		// Force secure connections
@@ -189,7 +264,7 @@ func TestPostgresPublicIP(t *testing.T) {
 	})
 
 	// RUN TESTS WITH SECURED CONNECTION
-	test_structure.RunTestStage(t, "ssl_sql_tests", func() {
+	reporter.RunTestStage("ssl_sql_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 		terraformOptionsForCert := test_structure.LoadTerraformOptions(t, certExampleDir)
 
@@ -7,7 +7,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/gcp"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -29,12 +28,14 @@ func TestPostgresReplicas(t *testing.T) {
 	//os.Setenv("SKIP_read_replica_tests", "true")
 	//os.Setenv("SKIP_teardown", "true")
 
+	reporter := newStageReporter(t)
+
 	_examplesDir := test_structure.CopyTerraformFolderToTemp(t, "../", "examples")
 	exampleDir := filepath.Join(_examplesDir, EXAMPLE_NAME_POSTGRES_REPLICAS)
 
 	// BOOTSTRAP VARIABLES FOR THE TESTS
-	test_structure.RunTestStage(t, "bootstrap", func() {
-		projectId := gcp.GetGoogleProjectIDFromEnvVar(t)
+	reporter.RunTestStage("bootstrap", func() {
+		projectId := getTestProjectId(t)
 		region := getRandomRegion(t, projectId)
 
 		masterZone, readReplicaZone := getTwoDistinctRandomZonesForRegion(t, projectId, region)
@@ -47,13 +48,13 @@ func TestPostgresReplicas(t *testing.T) {
 
 	// AT THE END OF THE TESTS, RUN `terraform destroy`
 	// TO CLEAN UP ANY RESOURCES THAT WERE CREATED
-	defer test_structure.RunTestStage(t, "teardown", func() {
+	defer reporter.RunTestStage("teardown", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 		terraform.Destroy(t, terraformOptions)
 	})
 
 	// AT THE END OF THE TESTS, CLEAN UP ANY POSTGRES OBJECTS THAT WERE CREATED
-	defer test_structure.RunTestStage(t, "cleanup_postgres_objects", func() {
+	defer reporter.RunTestStage("cleanup_postgres_objects", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
@@ -75,19 +76,22 @@ func TestPostgresReplicas(t *testing.T) {
 		}
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	reporter.RunTestStage("deploy", func() {
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
 		projectId := test_structure.LoadString(t, exampleDir, KEY_PROJECT)
 		masterZone := test_structure.LoadString(t, exampleDir, KEY_MASTER_ZONE)
 		readReplicaZone := test_structure.LoadString(t, exampleDir, KEY_READ_REPLICA_ZONE)
-		terraformOptions := createTerratestOptionsForCloudSqlReplicas(projectId, region, exampleDir, NAME_PREFIX_POSTGRES_REPLICAS, masterZone, "", 1, readReplicaZone)
+		terraformOptions := createTerratestOptionsForCloudSqlReplicas(t, projectId, region, exampleDir, NAME_PREFIX_POSTGRES_REPLICAS, masterZone, "", 1, readReplicaZone)
+		setInstanceNameOverride(t, terraformOptions)
 		test_structure.SaveTerraformOptions(t, exampleDir, terraformOptions)
 
-		terraform.InitAndApply(t, terraformOptions)
+		// Waits while the parallel tests already use the instances allowed in the project
+		reserveInstances(t, terraformOptions)
+		initAndApplyWithInstanceName(t, exampleDir, terraformOptions)
 	})
 
 	// VALIDATE MODULE OUTPUTS
-	test_structure.RunTestStage(t, "validate_outputs", func() {
+	reporter.RunTestStage("validate_outputs", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		region := test_structure.LoadString(t, exampleDir, KEY_REGION)
@@ -117,7 +121,7 @@ func TestPostgresReplicas(t *testing.T) {
 	})
 
 	// TEST REGULAR SQL CLIENT
-	test_structure.RunTestStage(t, "sql_tests", func() {
+	reporter.RunTestStage("sql_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		publicIp := terraform.Output(t, terraformOptions, OUTPUT_MASTER_PUBLIC_IP)
@@ -159,7 +163,7 @@ func TestPostgresReplicas(t *testing.T) {
 	})
 
 	// TEST READ REPLICA WITH REGULAR SQL CLIENT
-	test_structure.RunTestStage(t, "read_replica_tests", func() {
+	reporter.RunTestStage("read_replica_tests", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, exampleDir)
 
 		readReplicaPublicIpList := terraform.OutputList(t, terraformOptions, OUTPUT_READ_REPLICA_PUBLIC_IPS)
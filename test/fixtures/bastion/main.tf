@@ -0,0 +1,76 @@
+# ------------------------------------------------------------------------------
+# LAUNCH A BASTION HOST IN A PRIVATE NETWORK
+# This fixture is only used by the tests, to reach the private IP of a Cloud SQL instance through an SSH tunnel.
+# ------------------------------------------------------------------------------
+
+# ------------------------------------------------------------------------------
+# CONFIGURE OUR GCP CONNECTION
+# ------------------------------------------------------------------------------
+
+provider "google-beta" {
+  project = var.project
+  region  = var.region
+}
+
+terraform {
+  # This module is now only being tested with Terraform 1.0.x. However, to make upgrading easier, we are setting
+  # 0.12.26 as the minimum version, as that version added support for required_providers with source URLs, making it
+  # forwards compatible with 1.0.x code.
+  required_version = ">= 0.12.26"
+
+  required_providers {
+    google-beta = {
+      source  = "hashicorp/google-beta"
+      version = "~> 3.90.0"
+    }
+  }
+}
+
+# ------------------------------------------------------------------------------
+# ALLOW SSH TO THE BASTION HOST
+# ------------------------------------------------------------------------------
+
+resource "google_compute_firewall" "ssh" {
+  provider = google-beta
+  name     = "${var.name}-ssh"
+  network  = var.network
+
+  allow {
+    protocol = "tcp"
+    ports    = ["22"]
+  }
+
+  source_ranges = var.allowed_ssh_cidr_blocks
+  target_tags   = [var.name]
+}
+
+# ------------------------------------------------------------------------------
+# CREATE THE BASTION HOST
+# ------------------------------------------------------------------------------
+
+resource "google_compute_instance" "bastion" {
+  provider     = google-beta
+  name         = var.name
+  zone         = var.zone
+  machine_type = var.machine_type
+  tags         = [var.name]
+
+  boot_disk {
+    initialize_params {
+      image = var.image
+    }
+  }
+
+  network_interface {
+    network = var.network
+
+    # An ephemeral public IP, so the tests can reach the host
+    access_config {}
+  }
+
+  metadata = {
+    # Use the key from the metadata instead of OS Login, so the tests don't need extra IAM permissions
+    enable-oslogin = "FALSE"
+    ssh-keys       = "${var.ssh_user}:${var.ssh_public_key}"
+  }
+}
@@ -0,0 +1,9 @@
+output "public_ip" {
+  description = "The public IP address of the bastion host"
+  value       = google_compute_instance.bastion.network_interface[0].access_config[0].nat_ip
+}
+
+output "ssh_user" {
+  description = "The user to connect to the bastion host as"
+  value       = var.ssh_user
+}
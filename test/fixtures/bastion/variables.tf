@@ -0,0 +1,62 @@
+# ---------------------------------------------------------------------------------------------------------------------
+# REQUIRED PARAMETERS
+# These variables are expected to be passed in by the operator
+# ---------------------------------------------------------------------------------------------------------------------
+
+variable "project" {
+  description = "The project ID to host the bastion host in."
+  type        = string
+}
+
+variable "region" {
+  description = "The region to host the bastion host in."
+  type        = string
+}
+
+variable "zone" {
+  description = "The zone to host the bastion host in. Must be in the given region."
+  type        = string
+}
+
+variable "name" {
+  description = "The name of the bastion host. Also used for the name of its firewall rule and as its network tag."
+  type        = string
+}
+
+variable "network" {
+  description = "Self link to the network to launch the bastion host in, e.g. the private network of the Cloud SQL instance."
+  type        = string
+}
+
+variable "ssh_user" {
+  description = "The user to create on the bastion host for the given SSH key."
+  type        = string
+}
+
+variable "ssh_public_key" {
+  description = "The SSH public key of the user in OpenSSH authorized_keys format."
+  type        = string
+}
+
+# ---------------------------------------------------------------------------------------------------------------------
+# OPTIONAL PARAMETERS
+# Generally, these values won't need to be changed.
+# ---------------------------------------------------------------------------------------------------------------------
+
+variable "machine_type" {
+  description = "The machine type of the bastion host."
+  type        = string
+  default     = "e2-micro"
+}
+
+variable "image" {
+  description = "The boot image of the bastion host."
+  type        = string
+  default     = "debian-cloud/debian-11"
+}
+
+variable "allowed_ssh_cidr_blocks" {
+  description = "The CIDR blocks allowed to connect to the bastion host over SSH."
+  type        = list(string)
+  default     = ["0.0.0.0/0"]
+}
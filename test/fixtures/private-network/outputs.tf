@@ -0,0 +1,4 @@
+output "private_network" {
+  description = "Self link to the private network. It is only output once the private services connection is established, so instances can be connected to it right away."
+  value       = google_service_networking_connection.private_vpc_connection.network
+}
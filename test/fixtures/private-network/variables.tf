@@ -0,0 +1,19 @@
+# ---------------------------------------------------------------------------------------------------------------------
+# REQUIRED PARAMETERS
+# These variables are expected to be passed in by the operator
+# ---------------------------------------------------------------------------------------------------------------------
+
+variable "project" {
+  description = "The project ID to host the network in."
+  type        = string
+}
+
+variable "region" {
+  description = "The region of the provider. The network itself is global and auto-creates a subnetwork in every region."
+  type        = string
+}
+
+variable "name" {
+  description = "The name of the network. Also used as the prefix of the name of its reserved address range."
+  type        = string
+}
@@ -6,6 +6,11 @@ require (
 	github.com/GoogleCloudPlatform/cloudsql-proxy v0.0.0-20200504171905-7e668d9ad0ba
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gruntwork-io/terratest v0.37.5
+	github.com/hashicorp/terraform-json v0.12.0
 	github.com/lib/pq v1.5.1
 	github.com/stretchr/testify v1.5.1
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
+	google.golang.org/api v0.21.0
 )
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// The IAM principal to log in with can be set explicitly. Otherwise we use the service account the tests authenticate
+// with, which needs the Cloud SQL Instance User role (or any role with the cloudsql.instances.login permission).
+const ENV_VAR_IAM_DB_USER_EMAIL = "IAM_DB_USER_EMAIL"
+const ENV_VAR_GOOGLE_APPLICATION_CREDENTIALS = "GOOGLE_APPLICATION_CREDENTIALS"
+
+// Scope of the OAuth 2.0 access token that is used as password for IAM database authentication
+const IAM_DB_AUTH_SCOPE = "https://www.googleapis.com/auth/sqlservice.admin"
+
+const SERVICE_ACCOUNT_EMAIL_SUFFIX = ".gserviceaccount.com"
+
+// getIamPrincipalEmail returns the email of the IAM principal the tests log in with using IAM database authentication.
+func getIamPrincipalEmail(t *testing.T) string {
+	email, err := getIamPrincipalEmailE()
+	require.NoError(t, err, "Failed to determine the IAM principal for IAM database authentication")
+	return email
+}
+
+// getIamPrincipalEmailE returns the email in IAM_DB_USER_EMAIL or, if not set, the client email of the service account
+// key referenced by GOOGLE_APPLICATION_CREDENTIALS.
+func getIamPrincipalEmailE() (string, error) {
+	if email := os.Getenv(ENV_VAR_IAM_DB_USER_EMAIL); email != "" {
+		return email, nil
+	}
+
+	credentialsFile := os.Getenv(ENV_VAR_GOOGLE_APPLICATION_CREDENTIALS)
+	if credentialsFile == "" {
+		return "", fmt.Errorf("neither %s nor %s is set", ENV_VAR_IAM_DB_USER_EMAIL, ENV_VAR_GOOGLE_APPLICATION_CREDENTIALS)
+	}
+
+	content, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return "", err
+	}
+
+	var credentials struct {
+		ClientEmail string `json:"client_email"`
+	}
+	if err := json.Unmarshal(content, &credentials); err != nil {
+		return "", err
+	}
+	if credentials.ClientEmail == "" {
+		return "", fmt.Errorf("%s does not contain a client_email, set %s instead", credentialsFile, ENV_VAR_IAM_DB_USER_EMAIL)
+	}
+
+	return credentials.ClientEmail, nil
+}
+
+// getIamDatabaseUserName returns the database user name the given IAM principal logs in with. This mirrors the
+// iam_user_names output of the cloud-sql module: Postgres uses the full email of IAM users and drops the
+// '.gserviceaccount.com' suffix of service accounts, while MySQL users are created with the full email, but log in
+// with the part before the '@'.
+func getIamDatabaseUserName(isPostgres bool, email string) string {
+	if isPostgres {
+		return strings.TrimSuffix(email, SERVICE_ACCOUNT_EMAIL_SUFFIX)
+	}
+	return strings.SplitN(email, "@", 2)[0]
+}
+
+// newIamTokenSource returns a token source for the default Google credentials of the tests.
+func newIamTokenSource(t *testing.T) oauth2.TokenSource {
+	tokenSource, err := google.DefaultTokenSource(context.Background(), IAM_DB_AUTH_SCOPE)
+	require.NoError(t, err, "Failed to create token source for IAM database authentication")
+	return tokenSource
+}
+
+// getIamDatabasePassword returns the password to log in with IAM database authentication, i.e. a fresh OAuth 2.0
+// access token of the given token source.
+func getIamDatabasePassword(t *testing.T, tokenSource oauth2.TokenSource) string {
+	password, err := getIamDatabasePasswordE(tokenSource)
+	require.NoError(t, err, "Failed to get access token for IAM database authentication")
+	return password
+}
+
+// getIamDatabasePasswordE returns the access token of the given token source to use as database password.
+func getIamDatabasePasswordE(tokenSource oauth2.TokenSource) (string, error) {
+	token, err := tokenSource.Token()
+	if err != nil {
+		return "", err
+	}
+	if !token.Valid() {
+		return "", fmt.Errorf("token source returned an invalid or expired access token")
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+type failingTokenSource struct{}
+
+func (failingTokenSource) Token() (*oauth2.Token, error) {
+	return nil, errors.New("no credentials")
+}
+
+func TestGetIamDatabaseUserName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "jane@example.com", getIamDatabaseUserName(true, "jane@example.com"))
+	assert.Equal(t, "ci@my-project.iam", getIamDatabaseUserName(true, "ci@my-project.iam.gserviceaccount.com"))
+	assert.Equal(t, "jane", getIamDatabaseUserName(false, "jane@example.com"))
+	assert.Equal(t, "ci", getIamDatabaseUserName(false, "ci@my-project.iam.gserviceaccount.com"))
+}
+
+func TestGetIamDatabasePasswordE(t *testing.T) {
+	t.Parallel()
+
+	validToken := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ya29.token", Expiry: time.Now().Add(time.Hour)})
+	password, err := getIamDatabasePasswordE(validToken)
+	require.NoError(t, err)
+	assert.Equal(t, "ya29.token", password)
+
+	expiredToken := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ya29.token", Expiry: time.Now().Add(-time.Hour)})
+	_, err = getIamDatabasePasswordE(expiredToken)
+	assert.Error(t, err)
+
+	_, err = getIamDatabasePasswordE(failingTokenSource{})
+	assert.Error(t, err)
+}
+
+// Not parallel, as it modifies the environment
+func TestGetIamPrincipalEmailE(t *testing.T) {
+	credentialsFile := createTempFile(t, []byte(`{"type": "service_account", "client_email": "ci@my-project.iam.gserviceaccount.com"}`))
+	defer os.Remove(credentialsFile.Name())
+
+	emptyCredentialsFile, err := ioutil.TempFile(os.TempDir(), "temp-")
+	require.NoError(t, err)
+	emptyCredentialsFile.Close()
+	defer os.Remove(emptyCredentialsFile.Name())
+
+	defer restoreEnv(ENV_VAR_IAM_DB_USER_EMAIL)()
+	defer restoreEnv(ENV_VAR_GOOGLE_APPLICATION_CREDENTIALS)()
+
+	os.Setenv(ENV_VAR_IAM_DB_USER_EMAIL, "")
+	os.Setenv(ENV_VAR_GOOGLE_APPLICATION_CREDENTIALS, credentialsFile.Name())
+	email, err := getIamPrincipalEmailE()
+	require.NoError(t, err)
+	assert.Equal(t, "ci@my-project.iam.gserviceaccount.com", email)
+
+	os.Setenv(ENV_VAR_IAM_DB_USER_EMAIL, "jane@example.com")
+	email, err = getIamPrincipalEmailE()
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", email)
+
+	os.Setenv(ENV_VAR_IAM_DB_USER_EMAIL, "")
+	os.Setenv(ENV_VAR_GOOGLE_APPLICATION_CREDENTIALS, emptyCredentialsFile.Name())
+	_, err = getIamPrincipalEmailE()
+	assert.Error(t, err)
+
+	os.Setenv(ENV_VAR_GOOGLE_APPLICATION_CREDENTIALS, "")
+	_, err = getIamPrincipalEmailE()
+	assert.Error(t, err)
+}
+
+// restoreEnv returns a function that resets the given environment variable to its current value.
+func restoreEnv(name string) func() {
+	value, isSet := os.LookupEnv(name)
+	return func() {
+		if isSet {
+			os.Setenv(name, value)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"golang.org/x/sync/semaphore"
+)
+
+// The maximum number of Cloud SQL instances the tests create at the same time in a project. Set it according to the
+// instance and operation quotas of the projects the tests run in.
+const ENV_MAX_INSTANCES = "CLOUD_SQL_TEST_MAX_INSTANCES"
+const DEFAULT_MAX_INSTANCES = 12
+
+// The limiters of the test run, by project
+var testInstanceLimiters = map[string]*instanceLimiter{}
+var testInstanceLimitersMutex sync.Mutex
+
+// instanceLimiter limits the number of instances that exist at the same time across all tests. Tests reserve the
+// number of instances they create before deploying and release them after their teardown, queueing while the limit is
+// reached.
+type instanceLimiter struct {
+	capacity  int64
+	semaphore *semaphore.Weighted
+
+	mutex    sync.Mutex
+	reserved int64
+}
+
+func newInstanceLimiter(capacity int64) *instanceLimiter {
+	return &instanceLimiter{capacity: capacity, semaphore: semaphore.NewWeighted(capacity)}
+}
+
+// newInstanceLimiterFromEnv creates a limiter with the capacity in ENV_MAX_INSTANCES, or DEFAULT_MAX_INSTANCES if it
+// isn't set.
+func newInstanceLimiterFromEnv() (*instanceLimiter, error) {
+	value := os.Getenv(ENV_MAX_INSTANCES)
+	if value == "" {
+		return newInstanceLimiter(DEFAULT_MAX_INSTANCES), nil
+	}
+
+	capacity, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || capacity < 1 {
+		return nil, fmt.Errorf("%s must be a positive number of instances, got %q", ENV_MAX_INSTANCES, value)
+	}
+	return newInstanceLimiter(capacity), nil
+}
+
+// reserveInstances reserves the instances the given example options create with the limiter of their project, waiting
+// until enough instances are available. They are released when the test finishes, after its teardown stages.
+func reserveInstances(t *testing.T, terraformOptions *terraform.Options) {
+	projectId := terraformOptions.Vars["project"].(string)
+	limiter, err := getProjectInstanceLimiterE(projectId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := limiter.Acquire(t, getExampleInstanceCount(terraformOptions))
+	t.Cleanup(release)
+}
+
+// getProjectInstanceLimiterE returns the limiter of the given project, creating it on first use.
+func getProjectInstanceLimiterE(projectId string) (*instanceLimiter, error) {
+	testInstanceLimitersMutex.Lock()
+	defer testInstanceLimitersMutex.Unlock()
+
+	if limiter, exists := testInstanceLimiters[projectId]; exists {
+		return limiter, nil
+	}
+	limiter, err := newInstanceLimiterFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	testInstanceLimiters[projectId] = limiter
+	return limiter, nil
+}
+
+// Acquire waits until the given number of instances is available and reserves them, logging how long it waited. Call
+// the returned function to release them. A test that needs more instances than the capacity reserves all of them, so
+// it runs alone instead of waiting forever.
+func (limiter *instanceLimiter) Acquire(t *testing.T, count int64) func() {
+	if count > limiter.capacity {
+		logger.Logf(t, "The test creates %d instances, more than the limit of %d set with %s. Waiting for all of them.", count, limiter.capacity, ENV_MAX_INSTANCES)
+		count = limiter.capacity
+	}
+
+	start := time.Now()
+	limiter.mutex.Lock()
+	logger.Logf(t, "Reserving %d instances, %d of %d are in use", count, limiter.reserved, limiter.capacity)
+	limiter.mutex.Unlock()
+
+	// Stop waiting once the test run is interrupted, so the instances released by the teardown of other tests aren't
+	// used to deploy more
+	if err := limiter.semaphore.Acquire(testRunInterruption.Context(), count); err != nil {
+		t.Fatalf("Stopped waiting for %d instances, as the tests were interrupted: %v", count, err)
+	}
+
+	limiter.mutex.Lock()
+	limiter.reserved += count
+	logger.Logf(t, "Reserved %d instances after waiting %s, %d of %d are in use", count, time.Since(start).Round(time.Second), limiter.reserved, limiter.capacity)
+	limiter.mutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			limiter.mutex.Lock()
+			limiter.reserved -= count
+			limiter.mutex.Unlock()
+
+			limiter.semaphore.Release(count)
+			logger.Logf(t, "Released %d instances", count)
+		})
+	}
+}
+
+// getExampleInstanceCount returns the number of instances the example with the given options creates: the master, the
+// failover replica of the MySQL replicas example and the read replicas. PostgreSQL has no failover replica, its high
+// availability is part of the master, so the failover_replica_zone of the PostgreSQL replicas example is unused.
+func getExampleInstanceCount(terraformOptions *terraform.Options) int64 {
+	count := int64(1)
+	if failoverReplicaZone, _ := terraformOptions.Vars["failover_replica_zone"].(string); failoverReplicaZone != "" && isMySqlExample(terraformOptions) {
+		count++
+	}
+
+	switch numReadReplicas := terraformOptions.Vars["num_read_replicas"].(type) {
+	case int:
+		count += int64(numReadReplicas)
+	case float64:
+		// As decoded by test_structure.LoadTerraformOptions
+		count += int64(numReadReplicas)
+	}
+	if readReplicas, exists := terraformOptions.Vars["read_replicas"].(map[string]interface{}); exists {
+		count += int64(len(readReplicas))
+	}
+	return count
+}
+
+// isMySqlExample returns whether the given options deploy one of the MySQL examples, which are named after their engine.
+func isMySqlExample(terraformOptions *terraform.Options) bool {
+	return strings.HasPrefix(filepath.Base(terraformOptions.TerraformDir), "mysql")
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceLimiterQueuesReservations(t *testing.T) {
+	t.Parallel()
+
+	limiter := newInstanceLimiter(3)
+	releaseFirst := limiter.Acquire(t, 2)
+
+	acquired := make(chan func())
+	go func() {
+		acquired <- limiter.Acquire(t, 2)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Reserved more instances than the capacity")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	releaseFirst()
+	// Releasing twice doesn't free the instances of other tests
+	releaseFirst()
+
+	select {
+	case releaseSecond := <-acquired:
+		assert.Equal(t, int64(2), limiter.reserved)
+		releaseSecond()
+	case <-time.After(5 * time.Second):
+		t.Fatal("The queued reservation didn't get the released instances")
+	}
+	assert.Equal(t, int64(0), limiter.reserved)
+}
+
+func TestInstanceLimiterCapsLargeReservations(t *testing.T) {
+	t.Parallel()
+
+	limiter := newInstanceLimiter(2)
+	release := limiter.Acquire(t, 5)
+	assert.Equal(t, int64(2), limiter.reserved)
+	release()
+	assert.Equal(t, int64(0), limiter.reserved)
+}
+
+func TestNewInstanceLimiterFromEnv(t *testing.T) {
+	// Not parallel, as it sets environment variables
+	previous, wasSet := os.LookupEnv(ENV_MAX_INSTANCES)
+	defer func() {
+		if wasSet {
+			os.Setenv(ENV_MAX_INSTANCES, previous)
+		} else {
+			os.Unsetenv(ENV_MAX_INSTANCES)
+		}
+	}()
+
+	os.Unsetenv(ENV_MAX_INSTANCES)
+	limiter, err := newInstanceLimiterFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(DEFAULT_MAX_INSTANCES), limiter.capacity)
+
+	os.Setenv(ENV_MAX_INSTANCES, "4")
+	limiter, err = newInstanceLimiterFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), limiter.capacity)
+
+	for _, invalid := range []string{"0", "-1", "many"} {
+		os.Setenv(ENV_MAX_INSTANCES, invalid)
+		_, err = newInstanceLimiterFromEnv()
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestGetExampleInstanceCount(t *testing.T) {
+	t.Parallel()
+
+	publicOptions := createTerratestOptionsForCloudSql(t, "test-project", "us-central1", "/tmp/"+EXAMPLE_NAME_PUBLIC, NAME_PREFIX_PUBLIC)
+	assert.Equal(t, int64(1), getExampleInstanceCount(publicOptions))
+
+	replicasOptions := createTerratestOptionsForCloudSqlReplicas(t, "test-project", "us-central1", "/tmp/"+EXAMPLE_NAME_REPLICAS, NAME_PREFIX_REPLICAS, "us-central1-a", "us-central1-b", 2, "us-central1-c")
+	assert.Equal(t, int64(4), getExampleInstanceCount(replicasOptions))
+
+	replicasOptions.Vars["read_replicas"] = map[string]interface{}{"a": map[string]interface{}{}, "b": map[string]interface{}{}}
+	assert.Equal(t, int64(6), getExampleInstanceCount(replicasOptions))
+
+	// The PostgreSQL replicas example takes a failover replica zone, but doesn't create a failover replica
+	postgresReplicasOptions := createTerratestOptionsForCloudSqlReplicas(t, "test-project", "us-central1", "/tmp/"+EXAMPLE_NAME_POSTGRES_REPLICAS, NAME_PREFIX_POSTGRES_REPLICAS, "us-central1-a", "us-central1-b", 1, "us-central1-c")
+	assert.Equal(t, int64(2), getExampleInstanceCount(postgresReplicasOptions))
+
+	// As decoded by test_structure.LoadTerraformOptions, without a failover replica zone
+	decodedOptions := &terraform.Options{TerraformDir: "/tmp/" + EXAMPLE_NAME_REPLICAS, Vars: map[string]interface{}{"failover_replica_zone": "", "num_read_replicas": float64(1)}}
+	assert.Equal(t, int64(2), getExampleInstanceCount(decodedOptions))
+}
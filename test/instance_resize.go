@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// Writes are attempted this often while the instances are resized, and each write gives up after WRITE_PROBE_TIMEOUT,
+// so a write to a restarting instance doesn't block the probe until the TCP connection times out
+const WRITE_PROBE_INTERVAL = 1 * time.Second
+const WRITE_PROBE_TIMEOUT = 5 * time.Second
+
+const WRITE_PROBE_ROW_NAME = "Probe"
+
+// validatePlannedInPlaceUpdatesE returns an error unless Terraform plans to update each of the given Cloud SQL instances
+// in place. Replacing an instance, i.e. deleting and recreating it, loses its data and IP addresses.
+func validatePlannedInPlaceUpdatesE(plan *terraform.PlanStruct, addresses []string) error {
+	changes := getPlannedSqlInstanceChanges(plan)
+
+	for _, address := range addresses {
+		actions, exists := changes[address]
+		if !exists {
+			return fmt.Errorf("the plan contains no changes to %s", address)
+		}
+		if !actions.Update() {
+			return fmt.Errorf("the plan doesn't update %s in place, planned actions are %v", address, actions)
+		}
+	}
+
+	// Changes to any other instance must not replace it either
+	for address, actions := range changes {
+		if !actions.Update() {
+			return fmt.Errorf("the plan doesn't update %s in place, planned actions are %v", address, actions)
+		}
+	}
+	return nil
+}
+
+// validateDiskSizeIncreaseE is a pre-flight check that returns an error if any of the given instances currently has a
+// larger data disk than the desired size. The disk of a Cloud SQL instance can't be shrunk, so applying the change
+// would fail halfway through or, with some provider versions, recreate the instance.
+func validateDiskSizeIncreaseE(api cloudsql.AdminAPI, projectId string, instanceNames []string, desiredDiskSizeGb int64) error {
+	for _, instanceName := range instanceNames {
+		instance, err := api.GetInstance(projectId, instanceName)
+		if err != nil {
+			return err
+		}
+		if instance.Settings == nil {
+			return fmt.Errorf("instance %s has no settings", instanceName)
+		}
+		if instance.Settings.DataDiskSizeGb > desiredDiskSizeGb {
+			return fmt.Errorf("the disk of instance %s can't be shrunk from %d GB to %d GB", instanceName, instance.Settings.DataDiskSizeGb, desiredDiskSizeGb)
+		}
+	}
+	return nil
+}
+
+// validateInstanceSizesE returns an error unless all given instances have the expected machine type and disk size
+// according to the Admin API.
+func validateInstanceSizesE(api cloudsql.AdminAPI, projectId string, instanceNames []string, expectedTier string, expectedDiskSizeGb int64) error {
+	for _, instanceName := range instanceNames {
+		instance, err := api.GetInstance(projectId, instanceName)
+		if err != nil {
+			return err
+		}
+		if instance.Settings == nil {
+			return fmt.Errorf("instance %s has no settings", instanceName)
+		}
+		if instance.Settings.Tier != expectedTier {
+			return fmt.Errorf("instance %s has machine type %s, expected %s", instanceName, instance.Settings.Tier, expectedTier)
+		}
+		if instance.Settings.DataDiskSizeGb != expectedDiskSizeGb {
+			return fmt.Errorf("instance %s has a %d GB disk, expected %d GB", instanceName, instance.Settings.DataDiskSizeGb, expectedDiskSizeGb)
+		}
+	}
+	return nil
+}
+
+// writeProbeResult summarizes the writes attempted by a writeProbe.
+type writeProbeResult struct {
+	Writes        int
+	FailedWrites  int
+	LastWriteErr  error
+	LongestOutage time.Duration
+}
+
+// writeProbe repeatedly runs a write in the background to measure how long the database doesn't accept writes, e.g.
+// while the instance restarts to apply a new machine type.
+type writeProbe struct {
+	write    func() error
+	interval time.Duration
+	stop     chan struct{}
+	done     sync.WaitGroup
+	result   writeProbeResult
+}
+
+// startWriteProbe starts running the given write every interval until Stop is called.
+func startWriteProbe(write func() error, interval time.Duration) *writeProbe {
+	probe := &writeProbe{
+		write:    write,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+	probe.done.Add(1)
+	go probe.run()
+	return probe
+}
+
+func (probe *writeProbe) run() {
+	defer probe.done.Done()
+
+	ticker := time.NewTicker(probe.interval)
+	defer ticker.Stop()
+
+	lastSuccess := time.Now()
+	for {
+		select {
+		case <-probe.stop:
+			// An outage that is still ongoing counts until the probe is stopped
+			if probe.result.LastWriteErr != nil {
+				probe.recordOutage(time.Since(lastSuccess))
+			}
+			return
+		case <-ticker.C:
+		}
+
+		err := probe.write()
+		probe.result.Writes++
+		probe.result.LastWriteErr = err
+		if err != nil {
+			probe.result.FailedWrites++
+			continue
+		}
+
+		now := time.Now()
+		probe.recordOutage(now.Sub(lastSuccess))
+		lastSuccess = now
+	}
+}
+
+func (probe *writeProbe) recordOutage(outage time.Duration) {
+	if outage > probe.result.LongestOutage {
+		probe.result.LongestOutage = outage
+	}
+}
+
+// Stop stops the probe and returns the result. The longest outage is the longest time between two successful writes,
+// so it can't be shorter than the probe interval.
+func (probe *writeProbe) Stop() writeProbeResult {
+	close(probe.stop)
+	probe.done.Wait()
+	return probe.result
+}
+
+// newMySqlWriteProbeFunc returns a write for a writeProbe that inserts a row into the test table of the given MySQL
+// database.
+func newMySqlWriteProbeFunc(db *sql.DB) func() error {
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), WRITE_PROBE_TIMEOUT)
+		defer cancel()
+
+		_, err := db.ExecContext(ctx, MYSQL_INSERT_TEST_ROW, WRITE_PROBE_ROW_NAME)
+		return err
+	}
+}
+
+// getResizedSqlInstanceAddresses returns the sorted addresses of the master, the failover replica and the given read
+// replicas of the module with the given name.
+func getResizedSqlInstanceAddresses(moduleName string, readReplicaKeys []string) []string {
+	addresses := []string{
+		fmt.Sprintf("module.%s.%s.master", moduleName, SQL_INSTANCE_RESOURCE_TYPE),
+		fmt.Sprintf("module.%s.%s.failover_replica[0]", moduleName, SQL_INSTANCE_RESOURCE_TYPE),
+	}
+	for _, key := range readReplicaKeys {
+		addresses = append(addresses, fmt.Sprintf(`module.%s.%s.read_replica["%s"]`, moduleName, SQL_INSTANCE_RESOURCE_TYPE, key))
+	}
+	sort.Strings(addresses)
+	return addresses
+}
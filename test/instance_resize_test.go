@@ -0,0 +1,110 @@
+package test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+func TestValidatePlannedInPlaceUpdatesE(t *testing.T) {
+	t.Parallel()
+
+	addresses := getResizedSqlInstanceAddresses("mysql", []string{"read-0"})
+	newPlan := func(changes ...*tfjson.ResourceChange) *terraform.PlanStruct {
+		plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{}}
+		for _, change := range changes {
+			plan.ResourceChangesMap[change.Address] = change
+		}
+		return plan
+	}
+
+	updates := []*tfjson.ResourceChange{}
+	for _, address := range addresses {
+		updates = append(updates, newTestResourceChange(address, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionUpdate))
+	}
+	assert.NoError(t, validatePlannedInPlaceUpdatesE(newPlan(updates...), addresses))
+
+	// A replaced replica
+	replaced := newTestResourceChange(addresses[2], SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionDelete, tfjson.ActionCreate)
+	assert.Error(t, validatePlannedInPlaceUpdatesE(newPlan(updates[0], updates[1], replaced), addresses))
+
+	// An unchanged master
+	unchanged := newTestResourceChange(addresses[0], SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionNoop)
+	assert.Error(t, validatePlannedInPlaceUpdatesE(newPlan(unchanged, updates[1], updates[2]), addresses))
+
+	// Another instance that is replaced
+	otherReplica := newTestResourceChange(`module.mysql.google_sql_database_instance.read_replica["read-1"]`, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionCreate, tfjson.ActionDelete)
+	assert.Error(t, validatePlannedInPlaceUpdatesE(newPlan(append(updates, otherReplica)...), addresses))
+}
+
+func TestValidateDiskSizeIncreaseE(t *testing.T) {
+	t.Parallel()
+
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "master", Settings: &sqladmin.Settings{DataDiskSizeGb: 20}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "replica", Settings: &sqladmin.Settings{DataDiskSizeGb: 30}},
+	)
+
+	assert.NoError(t, validateDiskSizeIncreaseE(api, "my-project", []string{"master", "replica"}, 30))
+	assert.NoError(t, validateDiskSizeIncreaseE(api, "my-project", []string{"master"}, 20))
+	assert.Error(t, validateDiskSizeIncreaseE(api, "my-project", []string{"master", "replica"}, 20))
+	assert.Error(t, validateDiskSizeIncreaseE(api, "my-project", []string{"missing"}, 20))
+}
+
+func TestValidateInstanceSizesE(t *testing.T) {
+	t.Parallel()
+
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "master", Settings: &sqladmin.Settings{Tier: "db-g1-small", DataDiskSizeGb: 20}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "replica", Settings: &sqladmin.Settings{Tier: "db-f1-micro", DataDiskSizeGb: 20}},
+	)
+
+	assert.NoError(t, validateInstanceSizesE(api, "my-project", []string{"master"}, "db-g1-small", 20))
+	assert.Error(t, validateInstanceSizesE(api, "my-project", []string{"master", "replica"}, "db-g1-small", 20))
+	assert.Error(t, validateInstanceSizesE(api, "my-project", []string{"master"}, "db-g1-small", 10))
+}
+
+func TestWriteProbe(t *testing.T) {
+	t.Parallel()
+
+	var failing int32
+	probe := startWriteProbe(func() error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("instance is restarting")
+		}
+		return nil
+	}, time.Millisecond)
+
+	time.Sleep(10 * time.Millisecond)
+	atomic.StoreInt32(&failing, 1)
+	time.Sleep(50 * time.Millisecond)
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(10 * time.Millisecond)
+
+	result := probe.Stop()
+	assert.NoError(t, result.LastWriteErr)
+	assert.True(t, result.FailedWrites > 0)
+	assert.True(t, result.Writes > result.FailedWrites)
+	assert.True(t, result.LongestOutage >= 50*time.Millisecond, "Longest outage was %s", result.LongestOutage)
+}
+
+func TestWriteProbeOngoingOutage(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	probe := startWriteProbe(func() error { return errors.New("instance is restarting") }, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	result := probe.Stop()
+	assert.Error(t, result.LastWriteErr)
+	assert.Equal(t, result.Writes, result.FailedWrites)
+	assert.True(t, result.LongestOutage >= 20*time.Millisecond, "Longest outage was %s", result.LongestOutage)
+	assert.True(t, result.LongestOutage <= time.Since(start))
+}
@@ -0,0 +1,352 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The time left before the go test -timeout at which the test run is interrupted, so the teardown stages have time to
+// destroy what the tests created before go test kills them
+const ENV_TEARDOWN_MARGIN = "CLOUD_SQL_TEST_TEARDOWN_MARGIN"
+const DEFAULT_TEARDOWN_MARGIN = 20 * time.Minute
+
+// The name of the summary of an interrupted test run in ENV_TEST_REPORT_DIR
+const INTERRUPT_SUMMARY_FILE_NAME = "interrupt.json"
+
+// testRunInterruption is the interruption of the tests in this package, triggered by TestMain.
+var testRunInterruption = newTestInterruption(log.New(os.Stdout, "TestMain ", log.LstdFlags).Printf)
+
+// teardownResult is the outcome of a teardown stage that ran after the test run was interrupted.
+type teardownResult struct {
+	Test string `json:"test"`
+	stageResult
+}
+
+// interruptSummary records why the test run was interrupted and what was cleaned up afterwards.
+type interruptSummary struct {
+	Reason        string           `json:"reason"`
+	InterruptedAt time.Time        `json:"interrupted_at"`
+	Teardowns     []teardownResult `json:"teardowns"`
+}
+
+// testInterruption lets the tests wind down gracefully once the test run is interrupted: the stage reporters cancel all
+// stages except the teardown stages, so each test fails at its next stage and runs its deferred teardown stages, which
+// are recorded for the summary. Waiting for resources, e.g. instances from the instanceLimiter, is cancelled through
+// its context. The Terraform commands of the running stages, e.g. a terraform apply, are interrupted with SIGINT, unless
+// CTRL + C already interrupted them, so Terraform stops gracefully and the stage fails. The terraform destroy of the
+// teardown stages keeps running.
+type testInterruption struct {
+	logf   func(format string, args ...interface{})
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Interrupts the commands of the running stages, see interruptChildProcesses
+	interruptProcesses func() error
+	// Exits the test binary on a second signal
+	exit func(code int)
+
+	mutex         sync.Mutex
+	reason        string
+	interruptedAt time.Time
+	teardowns     []teardownResult
+}
+
+func newTestInterruption(logf func(format string, args ...interface{})) *testInterruption {
+	ctx, cancel := context.WithCancel(context.Background())
+	interruption := &testInterruption{logf: logf, ctx: ctx, cancel: cancel, exit: os.Exit, teardowns: []teardownResult{}}
+	interruption.interruptProcesses = interruption.interruptChildProcesses
+	return interruption
+}
+
+// Interrupt interrupts the test run for the given reason and the commands of the running stages. Only the first
+// interruption counts.
+func (interruption *testInterruption) Interrupt(reason string) {
+	if !interruption.interruptStages(reason) {
+		return
+	}
+	if err := interruption.interruptProcesses(); err != nil {
+		interruption.logf("Failed to interrupt the commands of the running stages: %v", err)
+	}
+}
+
+// interruptStages interrupts the test run for the given reason, without interrupting the commands of the running
+// stages. It returns false if the test run was already interrupted.
+func (interruption *testInterruption) interruptStages(reason string) bool {
+	interruption.mutex.Lock()
+	defer interruption.mutex.Unlock()
+
+	if interruption.reason != "" {
+		return false
+	}
+	interruption.logf("Interrupting the tests: %s. Cancelling the remaining stages and running the teardown stages.", reason)
+	interruption.reason = reason
+	interruption.interruptedAt = time.Now()
+	interruption.cancel()
+	return true
+}
+
+// interruptChildProcesses sends SIGINT to the processes started by the tests, e.g. a terraform apply, so they stop
+// gracefully and their stage fails. The terraform destroy of the teardown stages keeps running.
+func (interruption *testInterruption) interruptChildProcesses() error {
+	processes, err := listChildProcessesE(os.Getpid())
+	if err != nil {
+		return err
+	}
+
+	for _, process := range processes {
+		if isTerraformDestroy(process.Args) {
+			continue
+		}
+		interruption.logf("Interrupting %s (pid %d)", strings.Join(process.Args, " "), process.Pid)
+		running, err := os.FindProcess(process.Pid)
+		if err == nil {
+			err = running.Signal(os.Interrupt)
+		}
+		if err != nil {
+			interruption.logf("Failed to interrupt pid %d, it may have exited in the meantime: %v", process.Pid, err)
+		}
+	}
+	return nil
+}
+
+// IsInterrupted returns whether the test run was interrupted.
+func (interruption *testInterruption) IsInterrupted() bool {
+	interruption.mutex.Lock()
+	defer interruption.mutex.Unlock()
+
+	return interruption.reason != ""
+}
+
+// Context returns a context that is cancelled when the test run is interrupted.
+func (interruption *testInterruption) Context() context.Context {
+	return interruption.ctx
+}
+
+// RecordTeardown records the outcome of a teardown stage of the given test, if it ran after the interruption.
+func (interruption *testInterruption) RecordTeardown(testName string, result stageResult) {
+	interruption.mutex.Lock()
+	defer interruption.mutex.Unlock()
+
+	if interruption.reason == "" {
+		return
+	}
+	interruption.logf("Teardown stage %s of %s after the interruption: %s", result.Name, testName, result.Status)
+	interruption.teardowns = append(interruption.teardowns, teardownResult{Test: testName, stageResult: result})
+}
+
+// getSummary returns the summary of the interruption, or false if the test run wasn't interrupted.
+func (interruption *testInterruption) getSummary() (interruptSummary, bool) {
+	interruption.mutex.Lock()
+	defer interruption.mutex.Unlock()
+
+	summary := interruptSummary{
+		Reason:        interruption.reason,
+		InterruptedAt: interruption.interruptedAt,
+		Teardowns:     append([]teardownResult{}, interruption.teardowns...),
+	}
+	return summary, interruption.reason != ""
+}
+
+// Report logs the summary of the interruption and writes it to the given directory, if set. It does nothing if the test
+// run wasn't interrupted.
+func (interruption *testInterruption) Report(reportDir string) error {
+	summary, interrupted := interruption.getSummary()
+	if !interrupted {
+		return nil
+	}
+
+	interruption.logf("The tests were interrupted: %s. %d teardown stages ran afterwards:", summary.Reason, len(summary.Teardowns))
+	for _, teardown := range summary.Teardowns {
+		interruption.logf("  %s %s: %s", teardown.Test, teardown.Name, teardown.Status)
+	}
+
+	if reportDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		return err
+	}
+	jsonSummary, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(reportDir, INTERRUPT_SUMMARY_FILE_NAME), jsonSummary, 0644)
+}
+
+// Watch interrupts the test run on SIGINT or SIGTERM, or once the given deadline is reached. A second signal exits
+// right away, without waiting for the teardown stages. A zero deadline is ignored. Call the returned function to stop
+// watching.
+func (interruption *testInterruption) Watch(deadline time.Time) func() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	stopWatching := interruption.watch(signals, deadline)
+	return func() {
+		signal.Stop(signals)
+		stopWatching()
+	}
+}
+
+// watch interrupts the test run on the given signals or deadline, like Watch.
+func (interruption *testInterruption) watch(signals <-chan os.Signal, deadline time.Time) func() {
+	// A nil channel never fires, so without a deadline only the signals interrupt the test run
+	var timer *time.Timer
+	var deadlineReached <-chan time.Time
+	if !deadline.IsZero() {
+		timer = time.NewTimer(time.Until(deadline))
+		deadlineReached = timer.C
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case received := <-signals:
+				if interruption.IsInterrupted() {
+					interruption.logf("Received %v again, exiting without waiting for the teardown stages", received)
+					interruption.exit(1)
+					continue
+				}
+				reason := fmt.Sprintf("received %v", received)
+				if isInterruptFromTerminal(received) {
+					// CTRL + C already interrupted the Terraform commands too, and a second interrupt makes Terraform
+					// exit right away, without saving its state
+					interruption.interruptStages(reason)
+				} else {
+					interruption.Interrupt(reason)
+				}
+			case <-deadlineReached:
+				interruption.Interrupt("the go test -timeout is approaching")
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		close(stop)
+	}
+}
+
+// isInterruptFromTerminal returns whether the given signal is likely CTRL + C in a terminal, which the terminal sends to
+// all processes in the foreground, including the commands started by the tests. go test doesn't pass on its stdin, so
+// this checks whether the tests have a controlling terminal instead.
+func isInterruptFromTerminal(received os.Signal) bool {
+	if received != syscall.SIGINT {
+		return false
+	}
+	terminal, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	terminal.Close()
+	return true
+}
+
+// getInterruptDeadlineE returns when to interrupt the test run so the teardown margin is left before the go test
+// -timeout, or a zero time if there is no timeout. If the timeout is shorter than the margin, half of the timeout is
+// left for the teardown.
+func getInterruptDeadlineE(startedAt time.Time, timeout time.Duration) (time.Time, error) {
+	if timeout <= 0 {
+		return time.Time{}, nil
+	}
+
+	margin := DEFAULT_TEARDOWN_MARGIN
+	if value := os.Getenv(ENV_TEARDOWN_MARGIN); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return time.Time{}, fmt.Errorf("%s must be a duration like 20m, got %q", ENV_TEARDOWN_MARGIN, value)
+		}
+		margin = parsed
+	}
+	if margin >= timeout {
+		margin = timeout / 2
+	}
+	return startedAt.Add(timeout - margin), nil
+}
+
+// getTestTimeout returns the value of the go test -timeout flag. Call it after flag.Parse().
+func getTestTimeout() time.Duration {
+	timeoutFlag := flag.Lookup("test.timeout")
+	if timeoutFlag == nil {
+		return 0
+	}
+	timeout, _ := timeoutFlag.Value.(flag.Getter).Get().(time.Duration)
+	return timeout
+}
+
+// isTeardownStage returns whether the given stage cleans up after a test, so it still runs after an interruption.
+func isTeardownStage(stageName string) bool {
+	return strings.HasPrefix(stageName, "teardown")
+}
+
+// childProcess is a process started by the tests, e.g. a Terraform command.
+type childProcess struct {
+	Pid  int
+	Args []string
+}
+
+// listChildProcessesE returns the processes whose parent is the given process, as listed by ps.
+func listChildProcessesE(parentPid int) ([]childProcess, error) {
+	cmd := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=", "-o", "args=")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the processes with ps: %v", err)
+	}
+
+	processes := []childProcess{}
+	for _, process := range parseChildProcesses(string(output), parentPid) {
+		// Skip the ps command itself
+		if process.Pid != cmd.Process.Pid {
+			processes = append(processes, process)
+		}
+	}
+	return processes, nil
+}
+
+// parseChildProcesses returns the processes whose parent is the given process from the output of ps -o pid,ppid,args.
+func parseChildProcesses(output string, parentPid int) []childProcess {
+	processes := []childProcess{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, pidErr := strconv.Atoi(fields[0])
+		ppid, ppidErr := strconv.Atoi(fields[1])
+		if pidErr != nil || ppidErr != nil || ppid != parentPid {
+			continue
+		}
+		processes = append(processes, childProcess{Pid: pid, Args: fields[2:]})
+	}
+	return processes
+}
+
+// isTerraformDestroy returns whether the given command line runs terraform destroy, as the teardown stages do.
+func isTerraformDestroy(args []string) bool {
+	if len(args) == 0 || filepath.Base(args[0]) != "terraform" {
+		return false
+	}
+	for _, arg := range args[1:] {
+		if arg == "destroy" {
+			return true
+		}
+	}
+	return false
+}
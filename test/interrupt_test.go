@@ -0,0 +1,215 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestInterruptionWithLog creates an interruption that logs to the given test. It doesn't interrupt the processes
+// started by other tests.
+func newTestInterruptionWithLog(t *testing.T) *testInterruption {
+	interruption := newTestInterruption(func(format string, args ...interface{}) { t.Logf(format, args...) })
+	interruption.interruptProcesses = func() error { return nil }
+	return interruption
+}
+
+func TestStageReporterRunsOnlyTeardownStagesAfterInterrupt(t *testing.T) {
+	t.Parallel()
+
+	interruption := newTestInterruptionWithLog(t)
+	reporter := newStageReporter(t)
+	reporter.interruption = interruption
+
+	// Teardown stages that run before the interruption aren't recorded
+	reporter.RunTestStage("teardown_before", func() {})
+	assert.False(t, reporter.isCancelled("deploy"))
+
+	interruption.Interrupt("received interrupt")
+	interruption.Interrupt("the go test -timeout is approaching")
+	assert.True(t, interruption.IsInterrupted())
+	assert.Error(t, interruption.Context().Err())
+
+	assert.True(t, reporter.isCancelled("deploy"))
+	assert.True(t, reporter.isCancelled("sql_tests"))
+	assert.False(t, reporter.isCancelled("teardown"))
+	assert.False(t, reporter.isCancelled("teardown_cert"))
+
+	ran := false
+	reporter.RunTestStage("teardown_cert", func() { ran = true })
+	assert.True(t, ran)
+
+	summary, interrupted := interruption.getSummary()
+	require.True(t, interrupted)
+	assert.Equal(t, "received interrupt", summary.Reason)
+	require.Len(t, summary.Teardowns, 1)
+	assert.Equal(t, t.Name(), summary.Teardowns[0].Test)
+	assert.Equal(t, "teardown_cert", summary.Teardowns[0].Name)
+	assert.Equal(t, STAGE_STATUS_PASSED, summary.Teardowns[0].Status)
+}
+
+func TestTestInterruptionReport(t *testing.T) {
+	t.Parallel()
+
+	reportDir, err := ioutil.TempDir("", "interrupt-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(reportDir)
+
+	interruption := newTestInterruptionWithLog(t)
+	require.NoError(t, interruption.Report(reportDir))
+	assert.NoFileExists(t, filepath.Join(reportDir, INTERRUPT_SUMMARY_FILE_NAME))
+
+	interruption.Interrupt("received terminated")
+	interruption.RecordTeardown("TestMySqlReplicas", stageResult{Name: "teardown", Status: STAGE_STATUS_PASSED, DurationSeconds: 312})
+	interruption.RecordTeardown("TestMySqlPublicIP", stageResult{Name: "teardown_cert", Status: STAGE_STATUS_FAILED, Error: STAGE_FAILED_MESSAGE})
+	require.NoError(t, interruption.Report(reportDir))
+
+	content, err := ioutil.ReadFile(filepath.Join(reportDir, INTERRUPT_SUMMARY_FILE_NAME))
+	require.NoError(t, err)
+	summary := interruptSummary{}
+	require.NoError(t, json.Unmarshal(content, &summary))
+	assert.Equal(t, "received terminated", summary.Reason)
+	require.Len(t, summary.Teardowns, 2)
+	assert.Equal(t, "TestMySqlReplicas", summary.Teardowns[0].Test)
+	assert.Equal(t, "teardown", summary.Teardowns[0].Name)
+	assert.Equal(t, 312.0, summary.Teardowns[0].DurationSeconds)
+	assert.Equal(t, STAGE_STATUS_FAILED, summary.Teardowns[1].Status)
+}
+
+func TestTestInterruptionWatchDeadline(t *testing.T) {
+	t.Parallel()
+
+	interruption := newTestInterruptionWithLog(t)
+	stopWatching := interruption.Watch(time.Now().Add(50 * time.Millisecond))
+	defer stopWatching()
+
+	select {
+	case <-interruption.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("The test run wasn't interrupted at the deadline")
+	}
+	summary, _ := interruption.getSummary()
+	assert.Equal(t, "the go test -timeout is approaching", summary.Reason)
+}
+
+func TestTestInterruptionWatchSignals(t *testing.T) {
+	t.Parallel()
+
+	interruption := newTestInterruptionWithLog(t)
+	processesInterrupted := make(chan struct{}, 1)
+	interruption.interruptProcesses = func() error {
+		processesInterrupted <- struct{}{}
+		return nil
+	}
+	exitCodes := make(chan int, 1)
+	interruption.exit = func(code int) { exitCodes <- code }
+
+	signals := make(chan os.Signal, 2)
+	stopWatching := interruption.watch(signals, time.Time{})
+	defer stopWatching()
+
+	signals <- syscall.SIGTERM
+	select {
+	case <-processesInterrupted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The running commands weren't interrupted on the signal")
+	}
+	assert.Error(t, interruption.Context().Err())
+	summary, _ := interruption.getSummary()
+	assert.Equal(t, "received terminated", summary.Reason)
+
+	// A second signal exits without waiting for the teardown stages
+	signals <- syscall.SIGINT
+	select {
+	case code := <-exitCodes:
+		assert.Equal(t, 1, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("The second signal didn't exit")
+	}
+	assert.Empty(t, processesInterrupted)
+}
+
+func TestTestInterruptionInterruptsChildProcesses(t *testing.T) {
+	// Not parallel, as it interrupts all processes started by the tests
+
+	sleep := exec.Command("sleep", "30")
+	require.NoError(t, sleep.Start())
+
+	interruption := newTestInterruption(func(format string, args ...interface{}) { t.Logf(format, args...) })
+	interruption.Interrupt("received interrupt")
+
+	err := sleep.Wait()
+	require.Error(t, err)
+	exitErr, isExitErr := err.(*exec.ExitError)
+	require.True(t, isExitErr, "Unexpected error %v", err)
+	assert.Equal(t, syscall.SIGINT, exitErr.Sys().(syscall.WaitStatus).Signal())
+}
+
+func TestParseChildProcesses(t *testing.T) {
+	t.Parallel()
+
+	output := `    1     0 /sbin/init
+  812     1 go test -v -timeout 60m
+  840   812 /tmp/go-build/test.test -test.v -test.timeout 60m
+  901   840 /usr/local/bin/terraform apply -input=false -auto-approve -lock=false
+  902   840 terraform destroy -auto-approve -input=false
+  903   901 /root/.terraform.d/plugins/terraform-provider-google_v3.5.0_x5
+  904   840
+  abc   840 invalid
+`
+	processes := parseChildProcesses(output, 840)
+	require.Len(t, processes, 2)
+	assert.Equal(t, 901, processes[0].Pid)
+	assert.Equal(t, []string{"/usr/local/bin/terraform", "apply", "-input=false", "-auto-approve", "-lock=false"}, processes[0].Args)
+	assert.Equal(t, 902, processes[1].Pid)
+
+	assert.False(t, isTerraformDestroy(processes[0].Args))
+	assert.True(t, isTerraformDestroy(processes[1].Args))
+	assert.False(t, isTerraformDestroy([]string{"rm", "destroy"}))
+	assert.False(t, isTerraformDestroy(nil))
+}
+
+func TestGetInterruptDeadline(t *testing.T) {
+	// Not parallel, as it sets environment variables
+	previous, wasSet := os.LookupEnv(ENV_TEARDOWN_MARGIN)
+	defer func() {
+		if wasSet {
+			os.Setenv(ENV_TEARDOWN_MARGIN, previous)
+		} else {
+			os.Unsetenv(ENV_TEARDOWN_MARGIN)
+		}
+	}()
+
+	startedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		margin   string
+		timeout  time.Duration
+		expected time.Time
+	}{
+		{"", 0, time.Time{}},
+		{"", 2 * time.Hour, startedAt.Add(100 * time.Minute)},
+		{"30m", 2 * time.Hour, startedAt.Add(90 * time.Minute)},
+		{"0s", time.Hour, startedAt.Add(time.Hour)},
+		// The margin doesn't fit in the timeout, so half of it is left for the teardown
+		{"", 10 * time.Minute, startedAt.Add(5 * time.Minute)},
+	}
+	for _, testCase := range testCases {
+		os.Setenv(ENV_TEARDOWN_MARGIN, testCase.margin)
+		deadline, err := getInterruptDeadlineE(startedAt, testCase.timeout)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, deadline, fmt.Sprintf("margin %q, timeout %s", testCase.margin, testCase.timeout))
+	}
+
+	os.Setenv(ENV_TEARDOWN_MARGIN, "soon")
+	_, err := getInterruptDeadlineE(startedAt, time.Hour)
+	assert.Error(t, err)
+}
@@ -0,0 +1,31 @@
+package test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestMain interrupts the tests gracefully on SIGINT, SIGTERM or shortly before the go test -timeout, so the teardown
+// stages still destroy what the tests created, and reports what was cleaned up.
+func TestMain(m *testing.M) {
+	startedAt := time.Now()
+	flag.Parse()
+
+	deadline, err := getInterruptDeadlineE(startedAt, getTestTimeout())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	stopWatching := testRunInterruption.Watch(deadline)
+
+	code := m.Run()
+
+	stopWatching()
+	if err := testRunInterruption.Report(os.Getenv(ENV_TEST_REPORT_DIR)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write the interrupt summary: %v\n", err)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+const MAINTENANCE_TRACK_CANARY = "canary"
+const MAINTENANCE_TRACK_STABLE = "stable"
+
+// maintenanceWindow holds the maintenance_window_day, maintenance_window_hour and maintenance_track inputs of the module.
+type maintenanceWindow struct {
+	Day   int64
+	Hour  int64
+	Track string
+}
+
+// validateMaintenanceWindowE returns an error if the given maintenance window would be rejected by the Cloud SQL API.
+// Checking this before `terraform apply` fails fast instead of halfway through creating the instance.
+func validateMaintenanceWindowE(window maintenanceWindow) error {
+	if window.Day < 1 || window.Day > 7 {
+		return fmt.Errorf("maintenance window day %d is not a day of the week from 1 (Monday) to 7 (Sunday)", window.Day)
+	}
+	if window.Hour < 0 || window.Hour > 23 {
+		return fmt.Errorf("maintenance window hour %d is not an hour of the day from 0 to 23", window.Hour)
+	}
+	if window.Track != MAINTENANCE_TRACK_CANARY && window.Track != MAINTENANCE_TRACK_STABLE {
+		return fmt.Errorf("maintenance track %q is neither %q nor %q", window.Track, MAINTENANCE_TRACK_CANARY, MAINTENANCE_TRACK_STABLE)
+	}
+	return nil
+}
+
+// setMaintenanceWindowVars validates the given maintenance window and sets the maintenance variables of the example,
+// failing the test if the window is invalid.
+func setMaintenanceWindowVars(t *testing.T, terraformOptions *terraform.Options, window maintenanceWindow) {
+	require.NoError(t, validateMaintenanceWindowE(window), "Invalid maintenance window")
+
+	terraformOptions.Vars["maintenance_window_day"] = window.Day
+	terraformOptions.Vars["maintenance_window_hour"] = window.Hour
+	terraformOptions.Vars["maintenance_track"] = window.Track
+}
+
+// validateInstanceMaintenanceWindow fails the test unless the Admin API reports the given maintenance window for the
+// instance.
+func validateInstanceMaintenanceWindow(t *testing.T, api cloudsql.AdminAPI, projectId string, instanceName string, expectedWindow maintenanceWindow) {
+	err := validateInstanceMaintenanceWindowE(api, projectId, instanceName, expectedWindow)
+	require.NoError(t, err, "Instance %s doesn't have the expected maintenance window", instanceName)
+}
+
+// validateInstanceMaintenanceWindowE returns an error unless the Admin API reports the given maintenance window for the
+// instance.
+func validateInstanceMaintenanceWindowE(api cloudsql.AdminAPI, projectId string, instanceName string, expectedWindow maintenanceWindow) error {
+	instance, err := api.GetInstance(projectId, instanceName)
+	if err != nil {
+		return err
+	}
+	if instance.Settings == nil || instance.Settings.MaintenanceWindow == nil {
+		return fmt.Errorf("instance %s has no maintenance window", instanceName)
+	}
+
+	actual := instance.Settings.MaintenanceWindow
+	actualWindow := maintenanceWindow{Day: actual.Day, Hour: actual.Hour, Track: actual.UpdateTrack}
+	if actualWindow != expectedWindow {
+		return fmt.Errorf("instance %s has maintenance window %+v, expected %+v", instanceName, actualWindow, expectedWindow)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/stretchr/testify/assert"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+func TestValidateMaintenanceWindowE(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		window    maintenanceWindow
+		expectErr bool
+	}{
+		{"Monday", maintenanceWindow{1, 0, MAINTENANCE_TRACK_STABLE}, false},
+		{"Sunday", maintenanceWindow{7, 23, MAINTENANCE_TRACK_CANARY}, false},
+		{"DayZero", maintenanceWindow{0, 3, MAINTENANCE_TRACK_STABLE}, true},
+		{"DayEight", maintenanceWindow{8, 3, MAINTENANCE_TRACK_STABLE}, true},
+		{"NegativeHour", maintenanceWindow{1, -1, MAINTENANCE_TRACK_STABLE}, true},
+		{"Hour24", maintenanceWindow{1, 24, MAINTENANCE_TRACK_STABLE}, true},
+		{"UnknownTrack", maintenanceWindow{1, 3, "beta"}, true},
+		{"EmptyTrack", maintenanceWindow{1, 3, ""}, true},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateMaintenanceWindowE(testCase.window)
+			if testCase.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateInstanceMaintenanceWindowE(t *testing.T) {
+	t.Parallel()
+
+	window := maintenanceWindow{Day: 2, Hour: 4, Track: MAINTENANCE_TRACK_CANARY}
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "windowed", Settings: &sqladmin.Settings{MaintenanceWindow: &sqladmin.MaintenanceWindow{Day: 2, Hour: 4, UpdateTrack: MAINTENANCE_TRACK_CANARY}}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "other", Settings: &sqladmin.Settings{MaintenanceWindow: &sqladmin.MaintenanceWindow{Day: 7, Hour: 7, UpdateTrack: MAINTENANCE_TRACK_STABLE}}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "none", Settings: &sqladmin.Settings{}},
+	)
+
+	assert.NoError(t, validateInstanceMaintenanceWindowE(api, "my-project", "windowed", window))
+	assert.Error(t, validateInstanceMaintenanceWindowE(api, "my-project", "other", window))
+	assert.Error(t, validateInstanceMaintenanceWindowE(api, "my-project", "none", window))
+	assert.Error(t, validateInstanceMaintenanceWindowE(api, "my-project", "missing", window))
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+const SQL_USER_RESOURCE_TYPE = "google_sql_user"
+const NULL_RESOURCE_TYPE = "null_resource"
+
+// validateOnboardingPlanE returns an error if the plan after importing an existing instance into the module changes
+// anything but the expected leftovers: the Admin API doesn't return passwords, so Terraform sets the password of each
+// imported user, and the null resource the module uses to emulate depends_on has nothing to import.
+func validateOnboardingPlanE(plan *terraform.PlanStruct) error {
+	unexpected := []string{}
+	for address, change := range plan.ResourceChangesMap {
+		if change.Change == nil || change.Change.Actions.NoOp() || change.Change.Actions.Read() {
+			continue
+		}
+		if change.Type == NULL_RESOURCE_TYPE && change.Change.Actions.Create() {
+			continue
+		}
+		if change.Type == SQL_USER_RESOURCE_TYPE && change.Change.Actions.Update() && onlyPasswordChanged(change.Change.Before, change.Change.After) {
+			continue
+		}
+		unexpected = append(unexpected, fmt.Sprintf("%s (%v)", address, change.Change.Actions))
+	}
+
+	if len(unexpected) > 0 {
+		sort.Strings(unexpected)
+		return fmt.Errorf("the plan after the import changes %s", strings.Join(unexpected, ", "))
+	}
+	return nil
+}
+
+func onlyPasswordChanged(before interface{}, after interface{}) bool {
+	beforeAttributes, beforeOk := before.(map[string]interface{})
+	afterAttributes, afterOk := after.(map[string]interface{})
+	if !beforeOk || !afterOk {
+		return false
+	}
+
+	for _, attributes := range []map[string]interface{}{beforeAttributes, afterAttributes} {
+		for name := range attributes {
+			if name != "password" && !reflect.DeepEqual(beforeAttributes[name], afterAttributes[name]) {
+				return false
+			}
+		}
+	}
+	return true
+}
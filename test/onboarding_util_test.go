@@ -0,0 +1,68 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestResourceChangeWithValues(address string, resourceType string, actions tfjson.Actions, before interface{}, after interface{}) *tfjson.ResourceChange {
+	return &tfjson.ResourceChange{
+		Address: address,
+		Type:    resourceType,
+		Change:  &tfjson.Change{Actions: actions, Before: before, After: after},
+	}
+}
+
+func newTestChangePlanStruct(changes ...*tfjson.ResourceChange) *terraform.PlanStruct {
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{}}
+	for _, change := range changes {
+		plan.ResourceChangesMap[change.Address] = change
+	}
+	return plan
+}
+
+func TestValidateOnboardingPlanE(t *testing.T) {
+	t.Parallel()
+
+	noOp := tfjson.Actions{tfjson.ActionNoop}
+	update := tfjson.Actions{tfjson.ActionUpdate}
+	create := tfjson.Actions{tfjson.ActionCreate}
+	replace := tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}
+
+	user := map[string]interface{}{"name": "testuser", "host": "%", "password": nil}
+	userWithPassword := map[string]interface{}{"name": "testuser", "host": "%", "password": "testpassword"}
+	userWithOtherHost := map[string]interface{}{"name": "testuser", "host": "localhost", "password": "testpassword"}
+
+	master := newTestResourceChangeWithValues("google_sql_database_instance.master", SQL_INSTANCE_RESOURCE_TYPE, noOp, nil, nil)
+	passwordChange := newTestResourceChangeWithValues("google_sql_user.default", SQL_USER_RESOURCE_TYPE, update, user, userWithPassword)
+	dependencyGetter := newTestResourceChangeWithValues("null_resource.dependency_getter", NULL_RESOURCE_TYPE, create, nil, map[string]interface{}{})
+
+	assert.NoError(t, validateOnboardingPlanE(newTestChangePlanStruct(master, passwordChange, dependencyGetter)))
+
+	testCases := []struct {
+		name   string
+		change *tfjson.ResourceChange
+	}{
+		{"InstanceUpdate", newTestResourceChangeWithValues("google_sql_database_instance.master", SQL_INSTANCE_RESOURCE_TYPE, update, map[string]interface{}{}, map[string]interface{}{})},
+		{"InstanceCreate", newTestResourceChangeWithValues(`google_sql_database_instance.read_replica["read-0"]`, SQL_INSTANCE_RESOURCE_TYPE, create, nil, map[string]interface{}{})},
+		{"UserHostChange", newTestResourceChangeWithValues("google_sql_user.default", SQL_USER_RESOURCE_TYPE, update, user, userWithOtherHost)},
+		{"UserReplace", newTestResourceChangeWithValues("google_sql_user.default", SQL_USER_RESOURCE_TYPE, replace, user, userWithPassword)},
+	}
+
+	for _, testCase := range testCases {
+		// The following is necessary to make sure testCase's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateOnboardingPlanE(newTestChangePlanStruct(dependencyGetter, testCase.change))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.change.Address)
+		})
+	}
+}
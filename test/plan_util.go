@@ -0,0 +1,203 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terraform-google-sql/test/cost"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const SQL_INSTANCE_RESOURCE_TYPE = "google_sql_database_instance"
+
+// initAndPlanWithStruct runs `terraform init` and `terraform plan` with the given options and parses the plan. The plan
+// is written to a temporary file, so the options used by other stages are not modified.
+func initAndPlanWithStruct(t *testing.T, terraformOptions *terraform.Options) *terraform.PlanStruct {
+	planFile, err := ioutil.TempFile(terraformOptions.TerraformDir, "*.tfplan")
+	require.NoError(t, err, "Failed to create plan file")
+	require.NoError(t, planFile.Close())
+	defer os.Remove(planFile.Name())
+
+	planOptions, err := terraformOptions.Clone()
+	require.NoError(t, err, "Failed to clone Terraform options")
+	planOptions.PlanFilePath = planFile.Name()
+
+	return terraform.InitAndPlanAndShowWithStruct(t, planOptions)
+}
+
+// getPlannedSqlInstanceAddresses returns the sorted addresses of all Cloud SQL instances in the planned values.
+func getPlannedSqlInstanceAddresses(plan *terraform.PlanStruct) []string {
+	addresses := []string{}
+	for address, resource := range plan.ResourcePlannedValuesMap {
+		if resource.Type == SQL_INSTANCE_RESOURCE_TYPE {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// getPlannedSqlInstanceChanges returns a map of the addresses of all Cloud SQL instances that Terraform plans to change to
+// the planned actions. Instances without changes are left out.
+func getPlannedSqlInstanceChanges(plan *terraform.PlanStruct) map[string]tfjson.Actions {
+	changes := map[string]tfjson.Actions{}
+	for address, change := range plan.ResourceChangesMap {
+		if change.Type != SQL_INSTANCE_RESOURCE_TYPE || change.Change == nil || change.Change.Actions.NoOp() {
+			continue
+		}
+		changes[address] = change.Change.Actions
+	}
+	return changes
+}
+
+// getPlannedAttributeE returns the planned value of the given top level attribute of the resource with the given address.
+func getPlannedAttributeE(plan *terraform.PlanStruct, address string, attribute string) (interface{}, error) {
+	resource, exists := plan.ResourcePlannedValuesMap[address]
+	if !exists {
+		return nil, fmt.Errorf("resource %s is not in the plan", address)
+	}
+	return resource.AttributeValues[attribute], nil
+}
+
+// validatePlannedEncryptionKeysE returns an error unless every planned Cloud SQL instance is encrypted with the expected
+// key.
+func validatePlannedEncryptionKeysE(plan *terraform.PlanStruct, expectedKeyName string) error {
+	addresses := getPlannedSqlInstanceAddresses(plan)
+	if len(addresses) == 0 {
+		return fmt.Errorf("the plan contains no %s resources", SQL_INSTANCE_RESOURCE_TYPE)
+	}
+
+	for _, address := range addresses {
+		keyName, err := getPlannedAttributeE(plan, address, "encryption_key_name")
+		if err != nil {
+			return err
+		}
+		if keyName != expectedKeyName {
+			return fmt.Errorf("%s is planned with encryption key %v, expected %s", address, keyName, expectedKeyName)
+		}
+	}
+
+	return nil
+}
+
+// getPlannedSettingsBlockE returns the planned values of the given nested block, e.g. insights_config, in the settings of
+// the Cloud SQL instance with the given address.
+func getPlannedSettingsBlockE(plan *terraform.PlanStruct, address string, block string) (map[string]interface{}, error) {
+	settings, err := getPlannedSettingsE(plan, address)
+	if err != nil {
+		return nil, err
+	}
+
+	nestedBlock, err := getSingleNestedBlockE(settings[block])
+	if err != nil {
+		return nil, fmt.Errorf("%s has invalid %s: %v", address, block, err)
+	}
+	return nestedBlock, nil
+}
+
+// getPlannedSettingsE returns the planned values of the settings of the Cloud SQL instance with the given address.
+func getPlannedSettingsE(plan *terraform.PlanStruct, address string) (map[string]interface{}, error) {
+	settings, err := getPlannedAttributeE(plan, address, "settings")
+	if err != nil {
+		return nil, err
+	}
+
+	settingsBlock, err := getSingleNestedBlockE(settings)
+	if err != nil {
+		return nil, fmt.Errorf("%s has invalid settings: %v", address, err)
+	}
+	return settingsBlock, nil
+}
+
+// getPlannedDatabaseFlagsE returns the planned database flags of the Cloud SQL instance with the given address as a map
+// of flag name to value.
+func getPlannedDatabaseFlagsE(plan *terraform.PlanStruct, address string) (map[string]string, error) {
+	settings, err := getPlannedSettingsE(plan, address)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := map[string]string{}
+	flagBlocks, _ := settings["database_flags"].([]interface{})
+	for _, flagBlock := range flagBlocks {
+		flag, isMap := flagBlock.(map[string]interface{})
+		if !isMap {
+			return nil, fmt.Errorf("%s has invalid database flag %v", address, flagBlock)
+		}
+		name, _ := flag["name"].(string)
+		value, _ := flag["value"].(string)
+		flags[name] = value
+	}
+	return flags, nil
+}
+
+// getSingleNestedBlockE returns the only element of a nested block, which Terraform represents as a list of objects.
+func getSingleNestedBlockE(value interface{}) (map[string]interface{}, error) {
+	blocks, isList := value.([]interface{})
+	if !isList || len(blocks) != 1 {
+		return nil, fmt.Errorf("expected a single block, got %v", value)
+	}
+
+	block, isMap := blocks[0].(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("expected a block, got %v", blocks[0])
+	}
+	return block, nil
+}
+
+// validatePlannedInsightsConfigsE returns an error unless every planned Cloud SQL instance has the expected Query
+// Insights configuration.
+func validatePlannedInsightsConfigsE(plan *terraform.PlanStruct, expected cloudsql.InsightsConfig) error {
+	addresses := getPlannedSqlInstanceAddresses(plan)
+	if len(addresses) == 0 {
+		return fmt.Errorf("the plan contains no %s resources", SQL_INSTANCE_RESOURCE_TYPE)
+	}
+
+	for _, address := range addresses {
+		insightsConfig, err := getPlannedSettingsBlockE(plan, address, "insights_config")
+		if err != nil {
+			return err
+		}
+
+		// Numbers are decoded from the plan JSON as float64
+		actual := cloudsql.InsightsConfig{}
+		actual.QueryInsightsEnabled, _ = insightsConfig["query_insights_enabled"].(bool)
+		queryStringLength, _ := insightsConfig["query_string_length"].(float64)
+		actual.QueryStringLength = int64(queryStringLength)
+		actual.RecordApplicationTags, _ = insightsConfig["record_application_tags"].(bool)
+		actual.RecordClientAddress, _ = insightsConfig["record_client_address"].(bool)
+
+		if actual != expected {
+			return fmt.Errorf("%s is planned with Query Insights configuration %+v, expected %+v", address, actual, expected)
+		}
+	}
+
+	return nil
+}
+
+// validatePlanWithinBudget estimates the monthly cost of the Cloud SQL instances in the given plan with the bundled rate
+// table, logs the estimate and fails the test if it exceeds the given monthly budget.
+func validatePlanWithinBudget(t *testing.T, plan *terraform.PlanStruct, monthlyBudget float64) *cost.Estimate {
+	estimate, err := estimatePlanCostE(plan)
+	require.NoError(t, err, "Failed to estimate the cost of the plan")
+
+	var output strings.Builder
+	require.NoError(t, cost.WriteEstimateHuman(&output, estimate))
+	logger.Logf(t, "Estimated cost of the plan:\n%s", output.String())
+
+	require.NoError(t, cost.CheckBudget(estimate, monthlyBudget))
+	return estimate
+}
+
+// estimatePlanCostE estimates the monthly cost of the Cloud SQL instances in the given plan with the bundled rate table.
+func estimatePlanCostE(plan *terraform.PlanStruct) (*cost.Estimate, error) {
+	return cost.EstimatePlan(&plan.RawPlan, cost.DefaultRateTable())
+}
@@ -0,0 +1,169 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPlanStruct(resources ...*tfjson.StateResource) *terraform.PlanStruct {
+	plan := &terraform.PlanStruct{ResourcePlannedValuesMap: map[string]*tfjson.StateResource{}}
+	for _, resource := range resources {
+		plan.ResourcePlannedValuesMap[resource.Address] = resource
+	}
+	return plan
+}
+
+func newTestPlannedInstance(address string, attributes map[string]interface{}) *tfjson.StateResource {
+	return &tfjson.StateResource{
+		Address:         address,
+		Type:            SQL_INSTANCE_RESOURCE_TYPE,
+		AttributeValues: attributes,
+	}
+}
+
+func TestValidatePlannedEncryptionKeysE(t *testing.T) {
+	t.Parallel()
+
+	encrypted := newTestPlannedInstance("module.mysql.google_sql_database_instance.master", map[string]interface{}{"encryption_key_name": TEST_KEY_NAME})
+	encryptedReplica := newTestPlannedInstance(`module.mysql.google_sql_database_instance.read_replica["read-0"]`, map[string]interface{}{"encryption_key_name": TEST_KEY_NAME})
+	unencryptedReplica := newTestPlannedInstance(`module.mysql.google_sql_database_instance.read_replica["read-1"]`, map[string]interface{}{"encryption_key_name": nil})
+	otherResource := &tfjson.StateResource{Address: "random_id.name", Type: "random_id"}
+
+	assert.NoError(t, validatePlannedEncryptionKeysE(newTestPlanStruct(encrypted, encryptedReplica, otherResource), TEST_KEY_NAME))
+	assert.Error(t, validatePlannedEncryptionKeysE(newTestPlanStruct(encrypted, encryptedReplica, unencryptedReplica), TEST_KEY_NAME))
+	assert.Error(t, validatePlannedEncryptionKeysE(newTestPlanStruct(otherResource), TEST_KEY_NAME))
+
+	assert.Equal(
+		t,
+		[]string{"module.mysql.google_sql_database_instance.master", `module.mysql.google_sql_database_instance.read_replica["read-0"]`},
+		getPlannedSqlInstanceAddresses(newTestPlanStruct(encryptedReplica, otherResource, encrypted)),
+	)
+}
+
+func newTestPlannedInsightsSettings(enabled bool, queryStringLength float64) map[string]interface{} {
+	return map[string]interface{}{
+		"settings": []interface{}{
+			map[string]interface{}{
+				"insights_config": []interface{}{
+					map[string]interface{}{
+						"query_insights_enabled":  enabled,
+						"query_string_length":     queryStringLength,
+						"record_application_tags": enabled,
+						"record_client_address":   false,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestValidatePlannedInsightsConfigsE(t *testing.T) {
+	t.Parallel()
+
+	expected := cloudsql.InsightsConfig{QueryInsightsEnabled: true, QueryStringLength: 2048, RecordApplicationTags: true}
+
+	master := newTestPlannedInstance("module.mysql.google_sql_database_instance.master", newTestPlannedInsightsSettings(true, 2048))
+	replica := newTestPlannedInstance(`module.mysql.google_sql_database_instance.read_replica["read-0"]`, newTestPlannedInsightsSettings(true, 2048))
+	disabledReplica := newTestPlannedInstance(`module.mysql.google_sql_database_instance.read_replica["read-1"]`, newTestPlannedInsightsSettings(false, 2048))
+	shortReplica := newTestPlannedInstance(`module.mysql.google_sql_database_instance.read_replica["read-2"]`, newTestPlannedInsightsSettings(true, 1024))
+	noSettings := newTestPlannedInstance(`module.mysql.google_sql_database_instance.read_replica["read-3"]`, map[string]interface{}{})
+
+	assert.NoError(t, validatePlannedInsightsConfigsE(newTestPlanStruct(master, replica), expected))
+	assert.Error(t, validatePlannedInsightsConfigsE(newTestPlanStruct(master, disabledReplica), expected))
+	assert.Error(t, validatePlannedInsightsConfigsE(newTestPlanStruct(master, shortReplica), expected))
+	assert.Error(t, validatePlannedInsightsConfigsE(newTestPlanStruct(master, noSettings), expected))
+}
+
+func TestGetPlannedDatabaseFlagsE(t *testing.T) {
+	t.Parallel()
+
+	replica := newTestPlannedInstance(`module.mysql.google_sql_database_instance.read_replica["read-0"]`, map[string]interface{}{
+		"settings": []interface{}{
+			map[string]interface{}{
+				"tier": "db-f1-micro",
+				"database_flags": []interface{}{
+					map[string]interface{}{"name": "auto_increment_increment", "value": "7"},
+					map[string]interface{}{"name": "long_query_time", "value": "10"},
+				},
+			},
+		},
+	})
+	noFlags := newTestPlannedInstance(`module.mysql.google_sql_database_instance.read_replica["read-1"]`, map[string]interface{}{
+		"settings": []interface{}{map[string]interface{}{}},
+	})
+	plan := newTestPlanStruct(replica, noFlags)
+
+	flags, err := getPlannedDatabaseFlagsE(plan, replica.Address)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"auto_increment_increment": "7", "long_query_time": "10"}, flags)
+
+	flags, err = getPlannedDatabaseFlagsE(plan, noFlags.Address)
+	assert.NoError(t, err)
+	assert.Empty(t, flags)
+
+	settings, err := getPlannedSettingsE(plan, replica.Address)
+	assert.NoError(t, err)
+	assert.Equal(t, "db-f1-micro", settings["tier"])
+
+	_, err = getPlannedDatabaseFlagsE(plan, "module.mysql.google_sql_database_instance.master")
+	assert.Error(t, err)
+}
+
+func newTestResourceChange(address string, resourceType string, actions ...tfjson.Action) *tfjson.ResourceChange {
+	return &tfjson.ResourceChange{
+		Address: address,
+		Type:    resourceType,
+		Change:  &tfjson.Change{Actions: actions},
+	}
+}
+
+func TestGetPlannedSqlInstanceChanges(t *testing.T) {
+	t.Parallel()
+
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{}}
+	for _, change := range []*tfjson.ResourceChange{
+		newTestResourceChange("module.mysql.google_sql_database_instance.master", SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionNoop),
+		newTestResourceChange(`module.mysql.google_sql_database_instance.read_replica["a"]`, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionNoop),
+		newTestResourceChange(`module.mysql.google_sql_database_instance.read_replica["b"]`, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionDelete),
+		newTestResourceChange(`module.mysql.google_sql_database_instance.read_replica["c"]`, SQL_INSTANCE_RESOURCE_TYPE, tfjson.ActionUpdate),
+		newTestResourceChange(`module.mysql.data.template_file.read_replica_proxy_connection["b"]`, "template_file", tfjson.ActionDelete),
+	} {
+		plan.ResourceChangesMap[change.Address] = change
+	}
+
+	assert.Equal(
+		t,
+		map[string]tfjson.Actions{
+			`module.mysql.google_sql_database_instance.read_replica["b"]`: {tfjson.ActionDelete},
+			`module.mysql.google_sql_database_instance.read_replica["c"]`: {tfjson.ActionUpdate},
+		},
+		getPlannedSqlInstanceChanges(plan),
+	)
+}
+
+func TestEstimatePlanCostE(t *testing.T) {
+	t.Parallel()
+
+	settings := []interface{}{map[string]interface{}{"tier": "db-f1-micro", "disk_size": float64(10), "disk_type": "PD_SSD"}}
+	plan := &terraform.PlanStruct{RawPlan: tfjson.Plan{PlannedValues: &tfjson.StateValues{RootModule: &tfjson.StateModule{
+		ChildModules: []*tfjson.StateModule{{
+			Address: "module.mysql",
+			Resources: []*tfjson.StateResource{{
+				Address:         "module.mysql.google_sql_database_instance.master",
+				Mode:            tfjson.ManagedResourceMode,
+				Type:            SQL_INSTANCE_RESOURCE_TYPE,
+				AttributeValues: map[string]interface{}{"region": PLAN_REGION, "settings": settings},
+			}},
+		}},
+	}}}}
+
+	estimate, err := estimatePlanCostE(plan)
+	assert.NoError(t, err)
+	assert.Len(t, estimate.Instances, 1)
+	assert.Greater(t, estimate.TotalMonthly, 0.0)
+	assert.Less(t, estimate.TotalMonthly, float64(PLAN_MONTHLY_BUDGET))
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/gcp"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// A comma separated list of the projects to spread the example tests across, e.g. so each project only has to fit the
+// instances of some of the tests in its quotas
+const ENV_PROJECT_POOL = "CLOUD_SQL_TEST_PROJECTS"
+
+// A file listing the projects to spread the example tests across, one per line. Empty lines and lines starting with #
+// are ignored. Only used if ENV_PROJECT_POOL isn't set.
+const ENV_PROJECT_POOL_FILE = "CLOUD_SQL_TEST_PROJECTS_FILE"
+
+// The example tests that pick a project from the pool, sorted by name. They are assigned to the projects round-robin
+// in this order, so every project gets the same number of tests, give or take one. TestExampleTestsAreListed checks that
+// all example tests are listed.
+var EXAMPLE_TESTS = []string{
+	"TestEngineUpgrade/MySql",
+	"TestEngineUpgrade/Postgres",
+	"TestMySqlCrossRegionReplicas",
+	"TestMySqlOnboarding",
+	"TestMySqlPrivateIP",
+	"TestMySqlPublicIP",
+	"TestMySqlReplicas",
+	"TestMySqlReplicasResize",
+	"TestMySqlReplicasScaleDown",
+	"TestMySqlReplicasStateMigration",
+	"TestPostgresPrivateIP",
+	"TestPostgresPublicIP",
+	"TestPostgresReplicas",
+}
+
+// getTestProjectId returns the project the given test deploys its example in. If a pool of projects is configured, it
+// picks one of them based on the name of the test, so the tests are spread across the pool and a test always runs in
+// the same project for the same pool. Otherwise, it returns the project from the usual environment variables. Call it
+// in the bootstrap stage and save the result, so the later stages use the same project even if the pool changes.
+func getTestProjectId(t *testing.T) string {
+	pool, err := getProjectPoolE()
+	require.NoError(t, err, "Failed to read the project pool")
+
+	if len(pool) == 0 {
+		return gcp.GetGoogleProjectIDFromEnvVar(t)
+	}
+
+	projectId := selectProject(pool, EXAMPLE_TESTS, t.Name())
+	logger.Logf(t, "Using project %s from the pool of %d projects", projectId, len(pool))
+	return projectId
+}
+
+// getProjectPoolE returns the projects configured with ENV_PROJECT_POOL or ENV_PROJECT_POOL_FILE, or no projects if
+// neither is set.
+func getProjectPoolE() ([]string, error) {
+	if value := os.Getenv(ENV_PROJECT_POOL); value != "" {
+		return parseProjectPool(strings.Split(value, ",")), nil
+	}
+
+	path := os.Getenv(ENV_PROJECT_POOL_FILE)
+	if path == "" {
+		return nil, nil
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := parseProjectPool(strings.Split(string(contents), "\n"))
+	if len(pool) == 0 {
+		return nil, fmt.Errorf("the project pool file %s set with %s doesn't list any projects", path, ENV_PROJECT_POOL_FILE)
+	}
+	return pool, nil
+}
+
+// parseProjectPool returns the project IDs in the given entries, without comments, empty entries and duplicates.
+func parseProjectPool(entries []string) []string {
+	pool := []string{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		projectId := strings.TrimSpace(entry)
+		if projectId == "" || strings.HasPrefix(projectId, "#") || seen[projectId] {
+			continue
+		}
+		seen[projectId] = true
+		pool = append(pool, projectId)
+	}
+	return pool
+}
+
+// selectProject picks the project of the given test from the pool: the tests in the given sorted list are assigned to
+// the projects of the sorted pool round-robin, so the choice doesn't depend on the order of the pool and the tests are
+// spread evenly. Adding or removing a project moves most of the tests to another project. Tests that aren't listed
+// are assigned by a hash of their name instead.
+func selectProject(pool []string, testNames []string, testName string) string {
+	sortedPool := append([]string{}, pool...)
+	sort.Strings(sortedPool)
+
+	index := sort.SearchStrings(testNames, testName)
+	if index == len(testNames) || testNames[index] != testName {
+		hash := sha256.Sum256([]byte(testName))
+		index = int(binary.BigEndian.Uint64(hash[:8]) % uint64(len(sortedPool)))
+	}
+	return sortedPool[index%len(sortedPool)]
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFunctionRegexp = regexp.MustCompile(`(?m)^func (Test\w+)\(t \*testing\.T\)`)
+
+func TestGetProjectPool(t *testing.T) {
+	// Not parallel, as it sets environment variables
+	for _, key := range []string{ENV_PROJECT_POOL, ENV_PROJECT_POOL_FILE} {
+		previous, wasSet := os.LookupEnv(key)
+		defer func(key string) {
+			if wasSet {
+				os.Setenv(key, previous)
+			} else {
+				os.Unsetenv(key)
+			}
+		}(key)
+		os.Unsetenv(key)
+	}
+
+	pool, err := getProjectPoolE()
+	require.NoError(t, err)
+	assert.Empty(t, pool)
+
+	poolDir, err := ioutil.TempDir("", "project-pool")
+	require.NoError(t, err)
+	defer os.RemoveAll(poolDir)
+
+	poolFile := filepath.Join(poolDir, "projects")
+	require.NoError(t, ioutil.WriteFile(poolFile, []byte("# Projects with raised Cloud SQL quotas\nproject-b\n\n  project-c  \nproject-b\n"), 0644))
+	os.Setenv(ENV_PROJECT_POOL_FILE, poolFile)
+	pool, err = getProjectPoolE()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"project-b", "project-c"}, pool)
+
+	// The environment variable takes precedence over the file
+	os.Setenv(ENV_PROJECT_POOL, "project-a, project-b,")
+	pool, err = getProjectPoolE()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"project-a", "project-b"}, pool)
+
+	os.Unsetenv(ENV_PROJECT_POOL)
+	require.NoError(t, ioutil.WriteFile(poolFile, []byte("# No projects yet\n"), 0644))
+	_, err = getProjectPoolE()
+	assert.Error(t, err)
+
+	os.Setenv(ENV_PROJECT_POOL_FILE, filepath.Join(poolDir, "missing"))
+	_, err = getProjectPoolE()
+	assert.Error(t, err)
+}
+
+func TestSelectProjectIsDeterministic(t *testing.T) {
+	t.Parallel()
+
+	pool := []string{"project-a", "project-b", "project-c"}
+	reversed := []string{"project-c", "project-b", "project-a"}
+
+	for _, testName := range append([]string{"TestUnlisted", "TestUnlisted/Subtest"}, EXAMPLE_TESTS...) {
+		selected := selectProject(pool, EXAMPLE_TESTS, testName)
+		assert.Contains(t, pool, selected)
+		assert.Equal(t, selected, selectProject(pool, EXAMPLE_TESTS, testName), testName)
+		assert.Equal(t, selected, selectProject(reversed, EXAMPLE_TESTS, testName), testName)
+	}
+	assert.Equal(t, "project-a", selectProject([]string{"project-a"}, EXAMPLE_TESTS, "TestMySqlPublicIP"))
+}
+
+func TestSelectProjectBalancesTests(t *testing.T) {
+	t.Parallel()
+
+	pool := []string{"project-a", "project-b", "project-c", "project-d"}
+	for size := 1; size <= len(pool); size++ {
+		counts := map[string]int{}
+		for _, testName := range EXAMPLE_TESTS {
+			counts[selectProject(pool[:size], EXAMPLE_TESTS, testName)]++
+		}
+
+		require.Len(t, counts, size)
+		expected := float64(len(EXAMPLE_TESTS)) / float64(size)
+		for projectId, count := range counts {
+			assert.InDelta(t, expected, count, 1, "%s in a pool of %d projects", projectId, size)
+		}
+	}
+}
+
+func TestExampleTestsAreListed(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, sort.StringsAreSorted(EXAMPLE_TESTS), "EXAMPLE_TESTS must be sorted")
+
+	testFiles, err := filepath.Glob("example_*_test.go")
+	require.NoError(t, err)
+
+	exampleTests := map[string]bool{}
+	for _, testFile := range testFiles {
+		contents, err := ioutil.ReadFile(testFile)
+		require.NoError(t, err)
+		if !strings.Contains(string(contents), "getTestProjectId(t)") {
+			continue
+		}
+		for _, match := range testFunctionRegexp.FindAllStringSubmatch(string(contents), -1) {
+			exampleTests[match[1]] = true
+		}
+	}
+	require.NotEmpty(t, exampleTests)
+
+	// Tests with subtests are listed by their subtests
+	listedTests := map[string]bool{}
+	for _, testName := range EXAMPLE_TESTS {
+		topLevelName := strings.SplitN(testName, "/", 2)[0]
+		assert.True(t, exampleTests[topLevelName], "%s in EXAMPLE_TESTS is not an example test", testName)
+		listedTests[topLevelName] = true
+	}
+	for testName := range exampleTests {
+		assert.True(t, listedTests[testName], "%s is missing from EXAMPLE_TESTS", testName)
+	}
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// The baseline commit of this repo, which creates the read replicas with count. The migration test deploys it before
+// migrating to the current module, so it has to be in the history of the checkout the tests run in.
+const READ_REPLICA_MIGRATION_BASELINE_REF = "303f6dc29db273937450460211ee72562857fa0c"
+
+// Read replicas used to be created with count, so their state addresses end in an index like read_replica[0]. Now they
+// are created with for_each and keyed as read_replica["read-0"], which keeps their instance names. Moving the state
+// is therefore enough to migrate existing replicas without recreating them.
+var countReadReplicaAddressRegexp = regexp.MustCompile(`^(.*google_sql_database_instance\.read_replica)\[(\d+)\]$`)
+
+var variableDeclarationRegexp = regexp.MustCompile(`(?m)^variable "([^"]+)"`)
+
+// getReadReplicaStateMoves returns a map of the count based read replica addresses in the given state addresses to the
+// for_each based addresses they have to be moved to.
+func getReadReplicaStateMoves(stateAddresses []string) map[string]string {
+	moves := map[string]string{}
+	for _, address := range stateAddresses {
+		matches := countReadReplicaAddressRegexp.FindStringSubmatch(address)
+		if matches != nil {
+			moves[address] = fmt.Sprintf(`%s["read-%s"]`, matches[1], matches[2])
+		}
+	}
+	return moves
+}
+
+// migrateReadReplicaState moves the count based read replicas in the state of the given Terraform working directory to
+// their for_each based addresses by running `terraform state mv`.
+func migrateReadReplicaState(t *testing.T, terraformOptions *terraform.Options) {
+	err := migrateReadReplicaStateE(t, terraformOptions)
+	require.NoError(t, err, "Failed to migrate the read replica state")
+}
+
+// migrateReadReplicaStateE moves the count based read replicas in the state of the given Terraform working directory to
+// their for_each based addresses by running `terraform state mv`.
+func migrateReadReplicaStateE(t *testing.T, terraformOptions *terraform.Options) error {
+	stateList, err := terraform.RunTerraformCommandAndGetStdoutE(t, terraformOptions, "state", "list")
+	if err != nil {
+		return err
+	}
+
+	moves := getReadReplicaStateMoves(strings.Fields(stateList))
+	sources := []string{}
+	for source := range moves {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		logger.Logf(t, "Moving %s to %s", source, moves[source])
+		if _, err := terraform.RunTerraformCommandE(t, terraformOptions, "state", "mv", source, moves[source]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createIndexedReadReplicasVar creates the read_replicas variable with the keys the deprecated num_read_replicas and
+// read_replica_zones variables give the replicas in the given zones, which takes over these replicas without changes.
+func createIndexedReadReplicasVar(zones []string) map[string]interface{} {
+	readReplicas := map[string]interface{}{}
+	for index, zone := range zones {
+		readReplicas[fmt.Sprintf(cloudsql.INDEXED_READ_REPLICA_KEY_FORMAT, index)] = map[string]interface{}{"zone": zone}
+	}
+	return readReplicas
+}
+
+// extractReadReplicaMigrationBaseline extracts the examples and modules of READ_REPLICA_MIGRATION_BASELINE_REF to a
+// temporary folder and returns the path of the given example in it.
+func extractReadReplicaMigrationBaseline(t *testing.T, exampleName string) string {
+	baselineDir, err := ioutil.TempDir("", "read-replica-migration-baseline")
+	require.NoError(t, err)
+	archive := filepath.Join(baselineDir, "baseline.tar")
+
+	shell.RunCommand(t, shell.Command{
+		Command:    "git",
+		Args:       []string{"archive", "--format=tar", "--output", archive, READ_REPLICA_MIGRATION_BASELINE_REF, "examples", "modules"},
+		WorkingDir: "..",
+	})
+	shell.RunCommand(t, shell.Command{Command: "tar", Args: []string{"-xf", archive, "-C", baselineDir}})
+
+	return filepath.Join(baselineDir, "examples", exampleName)
+}
+
+// removeUndeclaredVars removes the variables the Terraform code in the options' dir doesn't declare, e.g. variables
+// added to an example after the migration baseline, as Terraform fails on values for undeclared variables.
+func removeUndeclaredVars(t *testing.T, terraformOptions *terraform.Options) {
+	files, err := filepath.Glob(filepath.Join(terraformOptions.TerraformDir, "*.tf"))
+	require.NoError(t, err)
+
+	declared := map[string]bool{}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		for _, matches := range variableDeclarationRegexp.FindAllStringSubmatch(string(content), -1) {
+			declared[matches[1]] = true
+		}
+	}
+
+	for name := range terraformOptions.Vars {
+		if !declared[name] {
+			logger.Logf(t, "Not passing %s, which %s doesn't declare", name, terraformOptions.TerraformDir)
+			delete(terraformOptions.Vars, name)
+		}
+	}
+}
+
+// copyTerraformState copies the local state of the given source dir to the target dir, so the Terraform code in the
+// target dir takes over the resources.
+func copyTerraformState(t *testing.T, sourceDir string, targetDir string) {
+	state, err := ioutil.ReadFile(filepath.Join(sourceDir, "terraform.tfstate"))
+	require.NoError(t, err, "Failed to read the state of %s", sourceDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(targetDir, "terraform.tfstate"), state, 0644))
+}
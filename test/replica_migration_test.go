@@ -0,0 +1,72 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReadReplicaStateMoves(t *testing.T) {
+	t.Parallel()
+
+	stateAddresses := []string{
+		"random_id.name",
+		"module.mysql.data.template_file.read_replica_proxy_connection[0]",
+		"module.mysql.google_sql_database_instance.master",
+		"module.mysql.google_sql_database_instance.failover_replica[0]",
+		"module.mysql.google_sql_database_instance.read_replica[0]",
+		"module.mysql.google_sql_database_instance.read_replica[12]",
+		`module.mysql.google_sql_database_instance.read_replica["analytics"]`,
+		"google_sql_database_instance.read_replica[1]",
+	}
+
+	expected := map[string]string{
+		"module.mysql.google_sql_database_instance.read_replica[0]":  `module.mysql.google_sql_database_instance.read_replica["read-0"]`,
+		"module.mysql.google_sql_database_instance.read_replica[12]": `module.mysql.google_sql_database_instance.read_replica["read-12"]`,
+		"google_sql_database_instance.read_replica[1]":               `google_sql_database_instance.read_replica["read-1"]`,
+	}
+
+	assert.Equal(t, expected, getReadReplicaStateMoves(stateAddresses))
+	assert.Empty(t, getReadReplicaStateMoves([]string{`module.mysql.google_sql_database_instance.read_replica["read-0"]`}))
+}
+
+func TestRemoveUndeclaredVars(t *testing.T) {
+	t.Parallel()
+
+	exampleDir, err := ioutil.TempDir("", "undeclared-vars")
+	require.NoError(t, err)
+	defer os.RemoveAll(exampleDir)
+
+	variables := "variable \"project\" {\n  type = string\n}\n\nvariable \"num_read_replicas\" {}\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(exampleDir, "variables.tf"), []byte(variables), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(exampleDir, "main.tf"), []byte("# variable \"commented\" {}\n"), 0644))
+
+	terraformOptions := &terraform.Options{
+		TerraformDir: exampleDir,
+		Vars: map[string]interface{}{
+			"project":           "test-project",
+			"num_read_replicas": 2,
+			"custom_labels":     map[string]string{"run": "1"},
+			"commented":         true,
+		},
+	}
+	removeUndeclaredVars(t, terraformOptions)
+	assert.Equal(t, map[string]interface{}{"project": "test-project", "num_read_replicas": 2}, terraformOptions.Vars)
+}
+
+func TestCreateIndexedReadReplicasVar(t *testing.T) {
+	t.Parallel()
+
+	expected := map[string]interface{}{
+		"read-0": map[string]interface{}{"zone": "us-central1-a"},
+		"read-1": map[string]interface{}{"zone": "us-central1-b"},
+		"read-2": map[string]interface{}{"zone": "us-central1-a"},
+	}
+	assert.Equal(t, expected, createIndexedReadReplicasVar([]string{"us-central1-a", "us-central1-b", "us-central1-a"}))
+	assert.Empty(t, createIndexedReadReplicasVar([]string{}))
+}
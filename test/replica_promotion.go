@@ -0,0 +1,88 @@
+package test
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// Promoting a replica restarts it, which can take a while on small machine types
+const PROMOTION_TIMEOUT = 30 * time.Minute
+const OPERATION_POLL_INTERVAL = 10 * time.Second
+
+const REPLICATION_MAX_RETRIES = 30
+const REPLICATION_TIME_BETWEEN_RETRIES = 10 * time.Second
+
+// Rows written to the master before and to the replica after the promotion
+const PROMOTION_MARKER_ROW_NAME = "DrMarker"
+const PROMOTED_ROW_NAME = "Promoted"
+
+const SQL_QUERY_ROW_COUNT_BY_NAME = "SELECT count(*) FROM test WHERE name = ?"
+
+// promoteReadReplica promotes the given read replica to a standalone instance via the Admin API and waits for the
+// promotion to finish.
+func promoteReadReplica(t *testing.T, api cloudsql.AdminAPI, projectId string, instanceName string) {
+	logger.Logf(t, "Promoting read replica %s", instanceName)
+	operation, err := api.PromoteReplica(projectId, instanceName)
+	require.NoError(t, err, "Failed to promote read replica %s", instanceName)
+
+	_, err = cloudsql.WaitForOperation(api, projectId, operation, PROMOTION_TIMEOUT, OPERATION_POLL_INTERVAL)
+	require.NoError(t, err, "Failed to promote read replica %s", instanceName)
+}
+
+// waitForMySqlRowReplicated waits until a row with the given name in the test table has been replicated to the given
+// replica.
+func waitForMySqlRowReplicated(t *testing.T, db *sql.DB, name string) {
+	retry.DoWithRetry(t, fmt.Sprintf("Waiting for row %s to be replicated", name), REPLICATION_MAX_RETRIES, REPLICATION_TIME_BETWEEN_RETRIES, func() (string, error) {
+		count, err := countMySqlRowsByNameE(db, name)
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return "", fmt.Errorf("row %s has not been replicated yet", name)
+		}
+		return "", nil
+	})
+}
+
+// countMySqlRowsByName returns the number of rows with the given name in the test table.
+func countMySqlRowsByName(t *testing.T, db *sql.DB, name string) int {
+	count, err := countMySqlRowsByNameE(db, name)
+	require.NoError(t, err, "Failed to count rows with name %s", name)
+	return count
+}
+
+// countMySqlRowsByNameE returns the number of rows with the given name in the test table.
+func countMySqlRowsByNameE(db *sql.DB, name string) (int, error) {
+	var count int
+	err := db.QueryRow(SQL_QUERY_ROW_COUNT_BY_NAME, name).Scan(&count)
+	return count, err
+}
+
+// logPlannedSqlInstanceChanges logs the planned changes to the Cloud SQL instances, so the output of the test documents
+// how to reconcile the state, e.g. after a replica has been promoted outside of Terraform.
+func logPlannedSqlInstanceChanges(t *testing.T, plan *terraform.PlanStruct) {
+	changes := getPlannedSqlInstanceChanges(plan)
+	if len(changes) == 0 {
+		logger.Log(t, "terraform plan proposes no changes to the Cloud SQL instances")
+		return
+	}
+
+	addresses := []string{}
+	for address := range changes {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		logger.Logf(t, "terraform plan proposes %v for %s", changes[address], address)
+	}
+}
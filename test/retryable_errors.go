@@ -0,0 +1,96 @@
+package test
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// Terratest has a single retry policy per command, so all errors in RETRYABLE_CLOUD_SQL_ERRORS are retried with the
+// same policy. It has to cover the slowest errors to clear: conflicting operations on an instance take a few minutes,
+// e.g. while a replica is being created, and the private services connection is only released a while after the last
+// instance using it is deleted. Rate limits and backend errors, which usually clear within seconds, wait just as long.
+const RETRYABLE_ERRORS_MAX_RETRIES = 6
+const RETRYABLE_ERRORS_TIME_BETWEEN_RETRIES = 2 * time.Minute
+
+// retryableError is a known transient error in the output of Terraform. The pattern is matched against the whole
+// output and the example is an actual error message, used to test the pattern.
+type retryableError struct {
+	Pattern     string
+	Description string
+	Example     string
+}
+
+// RETRYABLE_CLOUD_SQL_ERRORS is the catalog of transient Cloud SQL and Service Networking errors. Errors that are not
+// transient, like instanceAlreadyExists for a recently deleted instance name, must not match any of the patterns.
+var RETRYABLE_CLOUD_SQL_ERRORS = []retryableError{
+	{
+		Pattern:     `Error 409: .*operationInProgress`,
+		Description: "Another operation is already running on the Cloud SQL instance.",
+		Example:     "Error: Error, failed to create instance mysql-replicas-abc123-failover: googleapi: Error 409: Operation failed because another operation was already in progress., operationInProgress",
+	},
+	{
+		Pattern:     `Error 409: .*invalidState`,
+		Description: "The Cloud SQL instance is not yet in a state to handle the request.",
+		Example:     "Error: Error, failed to update user testuser in instance mysql-public-abc123: googleapi: Error 409: The instance or operation is not in an appropriate state to handle the request., invalidState",
+	},
+	{
+		Pattern:     `Error 429: .*(rateLimitExceeded|RESOURCE_EXHAUSTED)`,
+		Description: "The Cloud SQL Admin API rate limit was exceeded.",
+		Example:     "Error: Error when reading or editing Database Instance mysql-public-abc123: googleapi: Error 429: Quota exceeded for quota metric 'Queries' and limit 'Queries per minute per user', rateLimitExceeded",
+	},
+	{
+		Pattern:     `Error 50[03]: .*(backendError|internalError|Internal error|Service Unavailable)`,
+		Description: "The Cloud SQL Admin API had an internal error.",
+		Example:     "Error: Error, failed to create instance postgres-public-abc123: googleapi: Error 503: The service is currently unavailable., backendError",
+	},
+	{
+		Pattern:     `Cannot modify allocated ranges in CreateConnection`,
+		Description: "The private services connection is still being created or updated.",
+		Example:     "Error: Error waiting for Create Service Networking Connection: Error code 9, message: Cannot modify allocated ranges in CreateConnection. Please use UpdateConnection.",
+	},
+	{
+		Pattern:     `Producer services \(e\.g\. CloudSQL, Cloud Memstore, etc\.\) are still using this connection`,
+		Description: "The private services connection is still in use by a recently deleted Cloud SQL instance.",
+		Example:     "Error: Unable to remove Service Networking Connection, err: Error waiting for Delete Service Networking Connection: Error code 9, message: Failed to delete connection; Producer services (e.g. CloudSQL, Cloud Memstore, etc.) are still using this connection.",
+	},
+	{
+		Pattern:     `Error waiting for (Create|Update|Delete) Service Networking Connection: .*Error code 10`,
+		Description: "A concurrent change to the private services connection aborted this one.",
+		Example:     "Error: Error waiting for Create Service Networking Connection: Error code 10, message: Operation aborted because a concurrent operation on the same network is in progress.",
+	},
+}
+
+// setRetryableErrors adds the terratest defaults and RETRYABLE_CLOUD_SQL_ERRORS to the retryable errors of the given
+// options and sets the retry policy of the catalog, RETRYABLE_ERRORS_MAX_RETRIES and
+// RETRYABLE_ERRORS_TIME_BETWEEN_RETRIES, unless the options already retry more often or wait longer.
+func setRetryableErrors(terraformOptions *terraform.Options) *terraform.Options {
+	if terraformOptions.RetryableTerraformErrors == nil {
+		terraformOptions.RetryableTerraformErrors = map[string]string{}
+	}
+	for pattern, description := range terraform.DefaultRetryableTerraformErrors {
+		terraformOptions.RetryableTerraformErrors[pattern] = description
+	}
+	for _, retryableError := range RETRYABLE_CLOUD_SQL_ERRORS {
+		terraformOptions.RetryableTerraformErrors[retryableError.Pattern] = retryableError.Description
+	}
+
+	if terraformOptions.MaxRetries < RETRYABLE_ERRORS_MAX_RETRIES {
+		terraformOptions.MaxRetries = RETRYABLE_ERRORS_MAX_RETRIES
+	}
+	if terraformOptions.TimeBetweenRetries < RETRYABLE_ERRORS_TIME_BETWEEN_RETRIES {
+		terraformOptions.TimeBetweenRetries = RETRYABLE_ERRORS_TIME_BETWEEN_RETRIES
+	}
+	return terraformOptions
+}
+
+// findRetryableError returns the entry of RETRYABLE_CLOUD_SQL_ERRORS matching the given Terraform output, if any.
+func findRetryableError(output string) (retryableError, bool) {
+	for _, retryableError := range RETRYABLE_CLOUD_SQL_ERRORS {
+		if regexp.MustCompile(retryableError.Pattern).MatchString(output) {
+			return retryableError, true
+		}
+	}
+	return retryableError{}, false
+}
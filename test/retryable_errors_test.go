@@ -0,0 +1,75 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryableCloudSqlErrorsMatchExamples(t *testing.T) {
+	t.Parallel()
+
+	for _, retryableError := range RETRYABLE_CLOUD_SQL_ERRORS {
+		// The following is necessary to make sure retryableError's values don't
+		// get updated due to concurrency within the scope of t.Run(..) below
+		retryableError := retryableError
+
+		t.Run(retryableError.Pattern, func(t *testing.T) {
+			t.Parallel()
+
+			require.NotEmpty(t, retryableError.Example)
+			require.NotEmpty(t, retryableError.Description)
+
+			// Terratest matches the whole output of the command, so check the example within the surrounding output
+			output := "google_sql_database_instance.master: Creating...\n\n" + retryableError.Example + "\n\n  on main.tf line 28"
+			assert.Regexp(t, regexp.MustCompile(retryableError.Pattern), output)
+
+			found, exists := findRetryableError(output)
+			require.True(t, exists)
+			assert.Equal(t, retryableError.Pattern, found.Pattern)
+		})
+	}
+}
+
+func TestRetryableCloudSqlErrorsIgnorePermanentErrors(t *testing.T) {
+	t.Parallel()
+
+	permanentErrors := []string{
+		"Error: Error, failed to create instance mysql-public-abc123: googleapi: Error 409: The Cloud SQL instance already exists. When you delete an instance, you can't reuse the name of the deleted instance until one week from the deletion date., instanceAlreadyExists",
+		"Error: Error, failed to create instance mysql-public-abc123: googleapi: Error 400: Invalid request: Invalid flag for instance role: Backups cannot be enabled for read replica instance.., invalid",
+		"Error: Error, failed to create instance mysql-public-abc123: googleapi: Error 403: The client is not authorized to make this request., notAuthorized",
+		"Error: Error waiting for Create Service Networking Connection: Error code 7, message: Required 'compute.globalAddresses.list' permission for 'projects/test-project'",
+	}
+
+	for _, permanentError := range permanentErrors {
+		_, exists := findRetryableError(permanentError)
+		assert.False(t, exists, permanentError)
+	}
+}
+
+func TestSetRetryableErrors(t *testing.T) {
+	t.Parallel()
+
+	terraformOptions := setRetryableErrors(&terraform.Options{
+		RetryableTerraformErrors: map[string]string{"custom error": "Custom transient error."},
+	})
+
+	assert.Equal(t, "Custom transient error.", terraformOptions.RetryableTerraformErrors["custom error"])
+	for pattern := range terraform.DefaultRetryableTerraformErrors {
+		assert.Contains(t, terraformOptions.RetryableTerraformErrors, pattern)
+	}
+	for _, retryableError := range RETRYABLE_CLOUD_SQL_ERRORS {
+		assert.Equal(t, retryableError.Description, terraformOptions.RetryableTerraformErrors[retryableError.Pattern])
+	}
+	assert.Equal(t, RETRYABLE_ERRORS_MAX_RETRIES, terraformOptions.MaxRetries)
+	assert.Equal(t, RETRYABLE_ERRORS_TIME_BETWEEN_RETRIES, terraformOptions.TimeBetweenRetries)
+
+	// The helpers creating the options all apply the catalog
+	clientCertOptions := createTerratestOptionsForClientCert("test-project", "us-central1", "/tmp/example", "client", "instance")
+	assert.Contains(t, clientCertOptions.RetryableTerraformErrors, RETRYABLE_CLOUD_SQL_ERRORS[0].Pattern)
+	assert.Equal(t, 2*time.Minute, clientCertOptions.TimeBetweenRetries)
+}
@@ -0,0 +1,158 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// Labels added to every instance the tests deploy, so orphaned instances can be tied to the CI run that created them
+// and cleaned up once they have expired
+const LABEL_TEST_NAME = "test-name"
+const LABEL_TEST_RUN_ID = "test-run-id"
+const LABEL_GIT_SHA = "test-git-sha"
+const LABEL_EXPIRES_AT = "test-expires-at"
+
+// The run id and git sha are taken from these environment variables if they are set. Otherwise the CircleCI variables
+// are used, and outside of CI a random run id and the sha of the checked out commit.
+const ENV_VAR_TEST_RUN_ID = "CLOUD_SQL_TEST_RUN_ID"
+const ENV_VAR_GIT_SHA = "CLOUD_SQL_TEST_GIT_SHA"
+
+var CI_ENV_VARS_TEST_RUN_ID = []string{"CIRCLE_WORKFLOW_ID"}
+var CI_ENV_VARS_GIT_SHA = []string{"CIRCLE_SHA1"}
+
+const UNKNOWN_GIT_SHA = "unknown"
+
+// Instances that still exist this long after they were deployed are considered orphaned. The expiry is a Unix timestamp,
+// as label values can't contain colons.
+const TEST_INSTANCE_TTL = 24 * time.Hour
+
+// GCP label values may only contain lowercase letters, digits, underscores and dashes, and be at most 63 characters long
+const MAX_LABEL_VALUE_LENGTH = 63
+
+var invalidLabelValueCharsRegexp = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// All tests of one `go test` run share the same generated run id
+var generatedTestRunId = strings.ToLower(random.UniqueId())
+
+// The sha of the checked out commit only has to be looked up once per run
+var gitShaOnce sync.Once
+var gitSha string
+
+// createRunLabels returns the labels that identify the given test, the current test run and the commit under test. The
+// labels expire TEST_INSTANCE_TTL after the given time.
+func createRunLabels(t *testing.T, now time.Time) map[string]string {
+	return map[string]string{
+		LABEL_TEST_NAME:   sanitizeLabelValue(t.Name()),
+		LABEL_TEST_RUN_ID: sanitizeLabelValue(getTestRunId()),
+		LABEL_GIT_SHA:     sanitizeLabelValue(getGitSha(t)),
+		LABEL_EXPIRES_AT:  strconv.FormatInt(now.Add(TEST_INSTANCE_TTL).Unix(), 10),
+	}
+}
+
+// sanitizeLabelValue converts the given string to a valid GCP label value, e.g. TestMySqlReplicas/MySql to
+// testmysqlreplicas-mysql.
+func sanitizeLabelValue(value string) string {
+	sanitized := invalidLabelValueCharsRegexp.ReplaceAllString(strings.ToLower(value), "-")
+	if len(sanitized) > MAX_LABEL_VALUE_LENGTH {
+		sanitized = sanitized[:MAX_LABEL_VALUE_LENGTH]
+	}
+	return sanitized
+}
+
+// getTestRunId returns the id of the current test run.
+func getTestRunId() string {
+	if runId := getFirstEnvVar(append([]string{ENV_VAR_TEST_RUN_ID}, CI_ENV_VARS_TEST_RUN_ID...)); runId != "" {
+		return runId
+	}
+	return generatedTestRunId
+}
+
+// getGitSha returns the sha of the commit under test, or UNKNOWN_GIT_SHA if it can't be determined.
+func getGitSha(t *testing.T) string {
+	if sha := getFirstEnvVar(append([]string{ENV_VAR_GIT_SHA}, CI_ENV_VARS_GIT_SHA...)); sha != "" {
+		return sha
+	}
+
+	gitShaOnce.Do(func() {
+		output, err := shell.RunCommandAndGetStdOutE(t, shell.Command{Command: "git", Args: []string{"rev-parse", "HEAD"}})
+		if err != nil {
+			gitSha = UNKNOWN_GIT_SHA
+			return
+		}
+		gitSha = strings.TrimSpace(output)
+	})
+	return gitSha
+}
+
+// getFirstEnvVar returns the value of the first of the given environment variables that is set.
+func getFirstEnvVar(names []string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// getRunLabels returns the run labels the given options deploy the instances with. The options may have been loaded with
+// test_structure.LoadTerraformOptions, which decodes the labels as generic JSON.
+func getRunLabels(t *testing.T, terraformOptions *terraform.Options) map[string]string {
+	labels := map[string]string{}
+	switch customLabels := terraformOptions.Vars["custom_labels"].(type) {
+	case map[string]string:
+		for key, value := range customLabels {
+			labels[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range customLabels {
+			labels[key] = fmt.Sprint(value)
+		}
+	default:
+		t.Fatalf("The options don't set custom_labels, got %v", customLabels)
+	}
+	return labels
+}
+
+// validateInstanceLabels fails the test unless all given instances have the expected labels.
+func validateInstanceLabels(t *testing.T, api cloudsql.AdminAPI, projectId string, instanceNames []string, expectedLabels map[string]string) {
+	err := validateInstanceLabelsE(api, projectId, instanceNames, expectedLabels)
+	require.NoError(t, err, "Instances don't have the expected labels")
+}
+
+// validateInstanceLabelsE returns an error unless the Admin API reports all of the expected labels for each of the given
+// instances. Other labels, e.g. test-id, are ignored.
+func validateInstanceLabelsE(api cloudsql.AdminAPI, projectId string, instanceNames []string, expectedLabels map[string]string) error {
+	for _, instanceName := range instanceNames {
+		instance, err := api.GetInstance(projectId, instanceName)
+		if err != nil {
+			return err
+		}
+
+		actualLabels := map[string]string{}
+		if instance.Settings != nil {
+			actualLabels = instance.Settings.UserLabels
+		}
+		for key, expectedValue := range expectedLabels {
+			actualValue, exists := actualLabels[key]
+			if !exists {
+				return fmt.Errorf("instance %s has no label %s", instanceName, key)
+			}
+			if actualValue != expectedValue {
+				return fmt.Errorf("instance %s has label %s=%s, expected %s", instanceName, key, actualValue, expectedValue)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+func TestSanitizeLabelValue(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "testmysqlreplicas-mysql", sanitizeLabelValue("TestMySqlReplicas/MySql"))
+	assert.Equal(t, "a-b_c-d", sanitizeLabelValue("a.b_c  d"))
+	assert.Equal(t, strings.Repeat("a", MAX_LABEL_VALUE_LENGTH), sanitizeLabelValue(strings.Repeat("A", 100)))
+}
+
+func TestCreateRunLabels(t *testing.T) {
+	// Not parallel, as it sets environment variables
+	for _, name := range []string{ENV_VAR_TEST_RUN_ID, ENV_VAR_GIT_SHA} {
+		previous, wasSet := os.LookupEnv(name)
+		defer func(name string) {
+			if wasSet {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		}(name)
+	}
+	os.Setenv(ENV_VAR_TEST_RUN_ID, "Workflow 42")
+	os.Setenv(ENV_VAR_GIT_SHA, "0123abcd")
+
+	now := time.Unix(1700000000, 0)
+	assert.Equal(
+		t,
+		map[string]string{
+			LABEL_TEST_NAME:   "testcreaterunlabels",
+			LABEL_TEST_RUN_ID: "workflow-42",
+			LABEL_GIT_SHA:     "0123abcd",
+			LABEL_EXPIRES_AT:  "1700086400",
+		},
+		createRunLabels(t, now),
+	)
+}
+
+func TestGetRunLabels(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{LABEL_TEST_RUN_ID: "42"}
+	assert.Equal(t, labels, getRunLabels(t, &terraform.Options{Vars: map[string]interface{}{"custom_labels": labels}}))
+
+	// As decoded by test_structure.LoadTerraformOptions
+	decoded := map[string]interface{}{LABEL_TEST_RUN_ID: "42"}
+	assert.Equal(t, labels, getRunLabels(t, &terraform.Options{Vars: map[string]interface{}{"custom_labels": decoded}}))
+}
+
+func TestValidateInstanceLabelsE(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{LABEL_TEST_RUN_ID: "42", LABEL_GIT_SHA: "0123abcd"}
+	api := cloudsql.NewFakeAdminAPI(
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "master", Settings: &sqladmin.Settings{UserLabels: map[string]string{"test-id": "example", LABEL_TEST_RUN_ID: "42", LABEL_GIT_SHA: "0123abcd"}}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "other-run", Settings: &sqladmin.Settings{UserLabels: map[string]string{LABEL_TEST_RUN_ID: "41", LABEL_GIT_SHA: "0123abcd"}}},
+		&sqladmin.DatabaseInstance{Project: "my-project", Name: "unlabeled", Settings: &sqladmin.Settings{}},
+	)
+
+	assert.NoError(t, validateInstanceLabelsE(api, "my-project", []string{"master"}, labels))
+	assert.Error(t, validateInstanceLabelsE(api, "my-project", []string{"master", "other-run"}, labels))
+	assert.Error(t, validateInstanceLabelsE(api, "my-project", []string{"unlabeled"}, labels))
+	assert.Error(t, validateInstanceLabelsE(api, "my-project", []string{"missing"}, labels))
+}
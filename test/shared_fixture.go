@@ -0,0 +1,132 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+const PRIVATE_NETWORK_FIXTURE_DIR = "test/fixtures/private-network"
+
+// The network is global, so the region only configures the provider
+const PRIVATE_NETWORK_FIXTURE_REGION = "us-central1"
+
+// privateNetworkFixture is the private network shared by the private IP tests in each project of a test run.
+var privateNetworkFixture = newSharedFixture("private_network", createPrivateNetworkFixtureOptions, applyFixture, destroyFixture)
+
+// sharedFixture is Terraform code that is deployed once per project and shared by several tests. Each test takes a
+// lease before calling t.Parallel(), so all leases are taken before the first test deploys the fixture. The fixture is
+// deployed in a project by the first test that needs it there and destroyed in all projects when the last lease is
+// released, after the last test and its deferred teardown stages are done.
+type sharedFixture struct {
+	name          string
+	createOptions func(t *testing.T, projectId string) *terraform.Options
+	apply         func(t *testing.T, options *terraform.Options)
+	destroy       func(t *testing.T, options *terraform.Options)
+
+	mutex       sync.Mutex
+	leases      int
+	deployments map[string]*fixtureDeployment
+}
+
+// fixtureDeployment is the deployment of a shared fixture in a project.
+type fixtureDeployment struct {
+	options  *terraform.Options
+	deployed bool
+}
+
+// fixtureLease is a test's lease of a shared fixture.
+type fixtureLease struct {
+	fixture *sharedFixture
+}
+
+func newSharedFixture(name string, createOptions func(t *testing.T, projectId string) *terraform.Options, apply func(t *testing.T, options *terraform.Options), destroy func(t *testing.T, options *terraform.Options)) *sharedFixture {
+	return &sharedFixture{name: name, createOptions: createOptions, apply: apply, destroy: destroy, deployments: map[string]*fixtureDeployment{}}
+}
+
+// Lease takes a lease of the fixture, which is released when the given test finishes. Call it before t.Parallel().
+func (fixture *sharedFixture) Lease(t *testing.T) *fixtureLease {
+	fixture.mutex.Lock()
+	defer fixture.mutex.Unlock()
+
+	fixture.leases++
+	t.Cleanup(func() { fixture.release(t) })
+	return &fixtureLease{fixture: fixture}
+}
+
+// Options returns the options of the fixture in the given project, deploying it first if no other test did yet. If
+// deploying the fixture in the project failed in another test, the given test fails too.
+func (lease *fixtureLease) Options(t *testing.T, projectId string) *terraform.Options {
+	fixture := lease.fixture
+	fixture.mutex.Lock()
+	defer fixture.mutex.Unlock()
+
+	deployment, exists := fixture.deployments[projectId]
+	if exists && deployment.deployed {
+		return deployment.options
+	}
+	if exists {
+		t.Fatalf("Deploying the shared fixture %s in project %s failed in another test", fixture.name, projectId)
+	}
+
+	// The options are kept before applying, so the resources created before a failure are destroyed too
+	logger.Logf(t, "Deploying the shared fixture %s in project %s", fixture.name, projectId)
+	deployment = &fixtureDeployment{options: fixture.createOptions(t, projectId)}
+	fixture.deployments[projectId] = deployment
+	fixture.apply(t, deployment.options)
+	deployment.deployed = true
+	return deployment.options
+}
+
+// release releases a lease and destroys the fixture in all projects with the last one. A fixture whose deployment
+// failed is destroyed too.
+func (fixture *sharedFixture) release(t *testing.T) {
+	fixture.mutex.Lock()
+	defer fixture.mutex.Unlock()
+
+	fixture.leases--
+	if fixture.leases > 0 {
+		logger.Logf(t, "The shared fixture %s is still leased by %d tests", fixture.name, fixture.leases)
+		return
+	}
+
+	for projectId, deployment := range fixture.deployments {
+		fixture.teardown(t, projectId, deployment)
+	}
+	fixture.deployments = map[string]*fixtureDeployment{}
+}
+
+// teardown destroys the given deployment of the fixture. Like the teardown of the examples, this can be skipped with
+// SKIP_teardown_<fixture name>, and it's recorded if it runs after the test run was interrupted. It runs through a stage
+// reporter of its own, as the fixture doesn't belong to the report of the test that happens to release it last.
+func (fixture *sharedFixture) teardown(t *testing.T, projectId string, deployment *fixtureDeployment) {
+	reporter := &stageReporter{t: t, interruption: testRunInterruption, startedAt: time.Now(), stages: []stageResult{}}
+	reporter.RunTestStage("teardown_"+fixture.name, func() {
+		logger.Logf(t, "Destroying the shared fixture %s in project %s", fixture.name, projectId)
+		fixture.destroy(t, deployment.options)
+	})
+}
+
+// createPrivateNetworkFixtureOptions returns the options for a network with a private services connection, which Cloud
+// SQL instances in any region of the given project can be connected to.
+func createPrivateNetworkFixtureOptions(t *testing.T, projectId string) *terraform.Options {
+	fixtureDir := test_structure.CopyTerraformFolderToTemp(t, "../", PRIVATE_NETWORK_FIXTURE_DIR)
+	name := fmt.Sprintf("private-network-%s", strings.ToLower(random.UniqueId()))
+
+	return createTerratestOptionsForPrivateNetwork(projectId, PRIVATE_NETWORK_FIXTURE_REGION, fixtureDir, name)
+}
+
+func applyFixture(t *testing.T, terraformOptions *terraform.Options) {
+	terraform.InitAndApply(t, terraformOptions)
+}
+
+func destroyFixture(t *testing.T, terraformOptions *terraform.Options) {
+	terraform.Destroy(t, terraformOptions)
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+// testFixtureCalls counts the calls of the fake functions of a shared fixture.
+type testFixtureCalls struct {
+	mutex     sync.Mutex
+	created   int
+	applied   int
+	destroyed int
+}
+
+func (calls *testFixtureCalls) newSharedFixture(name string) *sharedFixture {
+	return newSharedFixture(
+		name,
+		func(t *testing.T, projectId string) *terraform.Options {
+			calls.mutex.Lock()
+			defer calls.mutex.Unlock()
+			calls.created++
+			return &terraform.Options{TerraformDir: fmt.Sprintf("/tmp/%s-%s-%d", name, projectId, calls.created)}
+		},
+		func(t *testing.T, options *terraform.Options) {
+			calls.mutex.Lock()
+			defer calls.mutex.Unlock()
+			calls.applied++
+		},
+		func(t *testing.T, options *terraform.Options) {
+			calls.mutex.Lock()
+			defer calls.mutex.Unlock()
+			calls.destroyed++
+		},
+	)
+}
+
+func (calls *testFixtureCalls) get() (int, int, int) {
+	calls.mutex.Lock()
+	defer calls.mutex.Unlock()
+	return calls.created, calls.applied, calls.destroyed
+}
+
+func TestSharedFixtureIsDestroyedAfterLastLease(t *testing.T) {
+	t.Parallel()
+
+	calls := &testFixtureCalls{}
+	fixture := calls.newSharedFixture("test_fixture_shared")
+
+	t.Run("Consumers", func(t *testing.T) {
+		for _, name := range []string{"A", "B", "C"} {
+			t.Run(name, func(t *testing.T) {
+				lease := fixture.Lease(t)
+				t.Parallel()
+
+				options := lease.Options(t, "test-project")
+				assert.Equal(t, "/tmp/test_fixture_shared-test-project-1", options.TerraformDir)
+
+				_, _, destroyed := calls.get()
+				assert.Equal(t, 0, destroyed)
+			})
+		}
+
+		// A consumer that never uses the fixture, e.g. because it failed early, doesn't destroy it for the others
+		t.Run("Unused", func(t *testing.T) {
+			fixture.Lease(t)
+			t.Parallel()
+		})
+	})
+
+	created, applied, destroyed := calls.get()
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, 1, destroyed)
+	assert.Equal(t, 0, fixture.leases)
+}
+
+func TestSharedFixtureIsRedeployedForNewLeases(t *testing.T) {
+	t.Parallel()
+
+	calls := &testFixtureCalls{}
+	fixture := calls.newSharedFixture("test_fixture_redeployed")
+
+	for _, name := range []string{"First", "Second"} {
+		t.Run(name, func(t *testing.T) {
+			fixture.Lease(t).Options(t, "test-project")
+		})
+	}
+
+	created, applied, destroyed := calls.get()
+	assert.Equal(t, 2, created)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, 2, destroyed)
+}
+
+func TestSharedFixtureIsDeployedPerProject(t *testing.T) {
+	t.Parallel()
+
+	calls := &testFixtureCalls{}
+	fixture := calls.newSharedFixture("test_fixture_projects")
+
+	t.Run("Consumers", func(t *testing.T) {
+		for _, projectId := range []string{"project-a", "project-b", "project-a"} {
+			// Capture range variable so that it doesn't change in the parallel subtests
+			projectId := projectId
+			t.Run(projectId, func(t *testing.T) {
+				lease := fixture.Lease(t)
+				t.Parallel()
+
+				options := lease.Options(t, projectId)
+				assert.Contains(t, options.TerraformDir, projectId)
+			})
+		}
+	})
+
+	created, applied, destroyed := calls.get()
+	assert.Equal(t, 2, created)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, 2, destroyed)
+	assert.Empty(t, fixture.deployments)
+}
+
+func TestSharedFixtureIsNotDestroyedIfNeverDeployed(t *testing.T) {
+	t.Parallel()
+
+	calls := &testFixtureCalls{}
+	fixture := calls.newSharedFixture("test_fixture_unused")
+
+	t.Run("Unused", func(t *testing.T) {
+		fixture.Lease(t)
+	})
+
+	created, applied, destroyed := calls.get()
+	assert.Equal(t, 0, created)
+	assert.Equal(t, 0, applied)
+	assert.Equal(t, 0, destroyed)
+}
@@ -0,0 +1,155 @@
+// Package sshtunnel forwards a local TCP port to an address behind an SSH bastion host, so the tests can connect to the
+// private IP of a Cloud SQL instance from outside of its network.
+package sshtunnel
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const DEFAULT_SSH_PORT = 22
+const DEFAULT_LOCAL_ADDRESS = "127.0.0.1:0"
+const DEFAULT_CONNECT_TIMEOUT = 30 * time.Second
+
+// Options configures Open.
+type Options struct {
+	// The bastion host as "<host>" or "<host>:<port>"
+	BastionAddress string
+	User           string
+	// The private key of the user in PEM format
+	PrivateKey string
+	// Defaults to accepting any host key, as the tests create the bastion host and can't know its key in advance
+	HostKeyCallback ssh.HostKeyCallback
+	// The address to connect to from the bastion host, e.g. "10.1.0.3:5432"
+	TargetAddress string
+	// The local address to listen on. Defaults to DEFAULT_LOCAL_ADDRESS, i.e. a random free port.
+	LocalAddress string
+	// Defaults to DEFAULT_CONNECT_TIMEOUT
+	Timeout time.Duration
+	// Optional, called for errors of single forwarded connections, which otherwise just get closed
+	Logf func(format string, args ...interface{})
+}
+
+// Tunnel forwards the connections to its local address through the bastion host to the target address.
+type Tunnel struct {
+	options   Options
+	client    *ssh.Client
+	listener  net.Listener
+	waitGroup sync.WaitGroup
+}
+
+// Open connects to the bastion host and starts forwarding the connections to the local address. Close the tunnel when
+// done.
+func Open(options Options) (*Tunnel, error) {
+	if options.TargetAddress == "" {
+		return nil, fmt.Errorf("the tunnel needs a target address")
+	}
+	if options.HostKeyCallback == nil {
+		options.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	}
+	if options.LocalAddress == "" {
+		options.LocalAddress = DEFAULT_LOCAL_ADDRESS
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DEFAULT_CONNECT_TIMEOUT
+	}
+	if options.Logf == nil {
+		options.Logf = func(format string, args ...interface{}) {}
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(options.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key: %v", err)
+	}
+
+	bastionAddress := options.BastionAddress
+	if _, _, err := net.SplitHostPort(bastionAddress); err != nil {
+		bastionAddress = net.JoinHostPort(bastionAddress, fmt.Sprint(DEFAULT_SSH_PORT))
+	}
+	client, err := ssh.Dial("tcp", bastionAddress, &ssh.ClientConfig{
+		User:            options.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: options.HostKeyCallback,
+		Timeout:         options.Timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the bastion host %s: %v", bastionAddress, err)
+	}
+
+	listener, err := net.Listen("tcp", options.LocalAddress)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	tunnel := &Tunnel{options: options, client: client, listener: listener}
+	tunnel.waitGroup.Add(1)
+	go tunnel.acceptConnections()
+	return tunnel, nil
+}
+
+// LocalAddress returns the address the tunnel listens on, as "<host>:<port>".
+func (tunnel *Tunnel) LocalAddress() string {
+	return tunnel.listener.Addr().String()
+}
+
+// LocalPort returns the port the tunnel listens on.
+func (tunnel *Tunnel) LocalPort() int {
+	return tunnel.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Close stops accepting connections, closes the forwarded connections and the connection to the bastion host.
+func (tunnel *Tunnel) Close() error {
+	listenerErr := tunnel.listener.Close()
+	clientErr := tunnel.client.Close()
+	tunnel.waitGroup.Wait()
+
+	if listenerErr != nil {
+		return listenerErr
+	}
+	return clientErr
+}
+
+func (tunnel *Tunnel) acceptConnections() {
+	defer tunnel.waitGroup.Done()
+
+	for {
+		local, err := tunnel.listener.Accept()
+		if err != nil {
+			// The listener was closed
+			return
+		}
+		tunnel.waitGroup.Add(1)
+		go tunnel.forward(local)
+	}
+}
+
+// forward copies the data between the given local connection and a new connection to the target address, until
+// either side closes its connection.
+func (tunnel *Tunnel) forward(local net.Conn) {
+	defer tunnel.waitGroup.Done()
+	defer local.Close()
+
+	remote, err := tunnel.client.Dial("tcp", tunnel.options.TargetAddress)
+	if err != nil {
+		tunnel.options.Logf("Failed to connect to %s through the bastion host: %v", tunnel.options.TargetAddress, err)
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
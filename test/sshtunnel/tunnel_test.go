@@ -0,0 +1,239 @@
+package sshtunnel
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// directTCPIPRequest is the payload of a "direct-tcpip" channel, as defined in RFC 4254, section 7.2.
+type directTCPIPRequest struct {
+	TargetHost string
+	TargetPort uint32
+	OriginHost string
+	OriginPort uint32
+}
+
+// newTestPrivateKey returns a new private key in PEM format and its public key.
+func newTestPrivateKey(t *testing.T) (string, ssh.PublicKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	encoded, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encoded})), sshPublicKey
+}
+
+// startTestBastion starts an in-process SSH server that only accepts the given key for the given user and forwards
+// "direct-tcpip" channels like a bastion host. It returns the address of the server.
+func startTestBastion(t *testing.T, user string, authorizedKey ssh.PublicKey) string {
+	hostKey, _ := newTestPrivateKey(t)
+	hostSigner, err := ssh.ParsePrivateKey([]byte(hostKey))
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(metadata ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if metadata.User() == user && string(key.Marshal()) == string(authorizedKey.Marshal()) {
+				return &ssh.Permissions{}, nil
+			}
+			return nil, fmt.Errorf("unknown key for %s", metadata.User())
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestBastionConnection(conn, config)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveTestBastionConnection(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "only port forwarding is supported")
+			continue
+		}
+		request := directTCPIPRequest{}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &request); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		target, err := net.Dial("tcp", net.JoinHostPort(request.TargetHost, fmt.Sprint(request.TargetPort)))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			target.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelRequests)
+		go func() {
+			defer channel.Close()
+			defer target.Close()
+			go io.Copy(target, channel)
+			io.Copy(channel, target)
+		}()
+	}
+}
+
+// startTestEchoServer starts a TCP server that echoes every line it receives in upper case, standing in for a database
+// in the private network. It returns the address of the server.
+func startTestEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					fmt.Fprintln(conn, strings.ToUpper(scanner.Text()))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func sendTestLine(t *testing.T, address string, line string) string {
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	_, err = fmt.Fprintln(conn, line)
+	require.NoError(t, err)
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	return strings.TrimSpace(response)
+}
+
+func TestTunnelForwardsConnections(t *testing.T) {
+	t.Parallel()
+
+	privateKey, publicKey := newTestPrivateKey(t)
+	bastionAddress := startTestBastion(t, "tunnel", publicKey)
+	targetAddress := startTestEchoServer(t)
+
+	tunnel, err := Open(Options{
+		BastionAddress: bastionAddress,
+		User:           "tunnel",
+		PrivateKey:     privateKey,
+		TargetAddress:  targetAddress,
+	})
+	require.NoError(t, err)
+	defer tunnel.Close()
+
+	assert.True(t, strings.HasPrefix(tunnel.LocalAddress(), "127.0.0.1:"))
+	assert.NotZero(t, tunnel.LocalPort())
+
+	// Each connection gets its own channel through the bastion
+	assert.Equal(t, "SELECT 1", sendTestLine(t, tunnel.LocalAddress(), "select 1"))
+	assert.Equal(t, "SELECT 2", sendTestLine(t, tunnel.LocalAddress(), "select 2"))
+
+	require.NoError(t, tunnel.Close())
+	_, err = net.DialTimeout("tcp", tunnel.LocalAddress(), time.Second)
+	assert.Error(t, err)
+}
+
+func TestTunnelClosesConnectionsToUnreachableTargets(t *testing.T) {
+	t.Parallel()
+
+	privateKey, publicKey := newTestPrivateKey(t)
+	bastionAddress := startTestBastion(t, "tunnel", publicKey)
+
+	// Reserve a port and free it again, so nothing listens on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	targetAddress := listener.Addr().String()
+	listener.Close()
+
+	logged := make(chan string, 1)
+	tunnel, err := Open(Options{
+		BastionAddress: bastionAddress,
+		User:           "tunnel",
+		PrivateKey:     privateKey,
+		TargetAddress:  targetAddress,
+		Logf:           func(format string, args ...interface{}) { logged <- fmt.Sprintf(format, args...) },
+	})
+	require.NoError(t, err)
+	defer tunnel.Close()
+
+	conn, err := net.Dial("tcp", tunnel.LocalAddress())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	assert.Contains(t, <-logged, targetAddress)
+}
+
+func TestOpenFailsWithUnknownKey(t *testing.T) {
+	t.Parallel()
+
+	_, authorizedKey := newTestPrivateKey(t)
+	otherKey, _ := newTestPrivateKey(t)
+	bastionAddress := startTestBastion(t, "tunnel", authorizedKey)
+
+	_, err := Open(Options{
+		BastionAddress: bastionAddress,
+		User:           "tunnel",
+		PrivateKey:     otherKey,
+		TargetAddress:  "10.0.0.1:5432",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect to the bastion host")
+}
+
+func TestOpenValidatesOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := Open(Options{BastionAddress: "127.0.0.1", PrivateKey: "not a key", TargetAddress: "10.0.0.1:5432"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse the private key")
+
+	_, err = Open(Options{BastionAddress: "127.0.0.1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "target address")
+}
@@ -0,0 +1,259 @@
+package test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+// If set, each test writes a JUnit XML and a JSON report of its stages to this directory, e.g. so CI can show which
+// stage failed and how long creating the instances took
+const ENV_TEST_REPORT_DIR = "CLOUD_SQL_TEST_REPORT_DIR"
+
+const STAGE_STATUS_PASSED = "passed"
+const STAGE_STATUS_FAILED = "failed"
+const STAGE_STATUS_SKIPPED = "skipped"
+
+// Stages that didn't run because the test run was interrupted, see testInterruption
+const STAGE_STATUS_CANCELLED = "cancelled"
+
+// The testing package doesn't expose the messages of failed assertions, so the report of a stage that failed without
+// panicking points to the log instead
+const STAGE_FAILED_MESSAGE = "stage failed, see the test log for the failed assertion"
+
+var reportFileNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// stageResult is the outcome of a single test stage.
+type stageResult struct {
+	Name            string    `json:"name"`
+	Status          string    `json:"status"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// stageReport is the JSON report of all stages of a test.
+type stageReport struct {
+	Test            string        `json:"test"`
+	Status          string        `json:"status"`
+	StartedAt       time.Time     `json:"started_at"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	Stages          []stageResult `json:"stages"`
+}
+
+// stageTestingT is the part of testing.T the stage reporter uses, so it can be tested with a fake test that fails.
+type stageTestingT interface {
+	Fail()
+	FailNow()
+	Failed() bool
+	Fatal(args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Error(args ...interface{})
+	Errorf(format string, args ...interface{})
+	Name() string
+}
+
+// stageReporter records the status, duration and error of each stage run through it and writes the report when the
+// test finishes.
+type stageReporter struct {
+	t            stageTestingT
+	interruption *testInterruption
+	startedAt    time.Time
+	mutex        sync.Mutex
+	stages       []stageResult
+}
+
+// newStageReporter creates a reporter for the given test. If ENV_TEST_REPORT_DIR is set, the reports are written to it
+// once the test and all of its deferred stages are done.
+func newStageReporter(t *testing.T) *stageReporter {
+	reporter := &stageReporter{t: t, interruption: testRunInterruption, startedAt: time.Now(), stages: []stageResult{}}
+
+	if reportDir := os.Getenv(ENV_TEST_REPORT_DIR); reportDir != "" {
+		t.Cleanup(func() {
+			if err := reporter.writeReports(reportDir); err != nil {
+				logger.Logf(t, "Failed to write the stage reports to %s: %v", reportDir, err)
+			}
+		})
+	}
+	return reporter
+}
+
+// RunTestStage runs the given stage with test_structure.RunTestStage, so it can still be skipped with SKIP_<stage>, and
+// records its outcome. A stage that doesn't return, as it called t.FailNow, e.g. through require, or panicked, is
+// recorded as failed before the test exits, even if the test had already failed before the stage. Once the test run is
+// interrupted, all stages except the teardown stages are cancelled and fail the test, so it goes straight to its
+// deferred teardown stages.
+func (reporter *stageReporter) RunTestStage(stageName string, stage func()) {
+	result := stageResult{Name: stageName, Status: STAGE_STATUS_SKIPPED, StartedAt: time.Now()}
+	failedBefore := reporter.t.Failed()
+
+	if reporter.isCancelled(stageName) {
+		result.Status = STAGE_STATUS_CANCELLED
+		reporter.record(result)
+		reporter.t.Fatalf("Cancelled stage %s, as the tests were interrupted", stageName)
+	}
+
+	started := false
+	defer func() {
+		if started {
+			result.DurationSeconds = time.Since(result.StartedAt).Seconds()
+		}
+		recovered := recover()
+		if recovered != nil {
+			result.Status = STAGE_STATUS_FAILED
+			result.Error = fmt.Sprintf("panic: %v", recovered)
+		} else if started && result.Status != STAGE_STATUS_PASSED {
+			// The stage exited through runtime.Goexit
+			result.Status = STAGE_STATUS_FAILED
+			result.Error = STAGE_FAILED_MESSAGE
+		} else if reporter.t.Failed() && !failedBefore {
+			result.Status = STAGE_STATUS_FAILED
+			result.Error = STAGE_FAILED_MESSAGE
+		}
+		reporter.record(result)
+		if isTeardownStage(stageName) {
+			reporter.interruption.RecordTeardown(reporter.t.Name(), result)
+		}
+
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+
+	test_structure.RunTestStage(reporter.t, stageName, func() {
+		started = true
+		stage()
+		result.Status = STAGE_STATUS_PASSED
+	})
+}
+
+// isCancelled returns whether the given stage is cancelled, as the test run was interrupted and it isn't a teardown stage.
+func (reporter *stageReporter) isCancelled(stageName string) bool {
+	return !isTeardownStage(stageName) && reporter.interruption.IsInterrupted()
+}
+
+func (reporter *stageReporter) record(result stageResult) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	reporter.stages = append(reporter.stages, result)
+}
+
+// getReport returns the report of all stages recorded so far. The test failed if any stage or the test itself failed.
+func (reporter *stageReporter) getReport() stageReport {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	report := stageReport{
+		Test:            reporter.t.Name(),
+		Status:          STAGE_STATUS_PASSED,
+		StartedAt:       reporter.startedAt,
+		DurationSeconds: time.Since(reporter.startedAt).Seconds(),
+		Stages:          append([]stageResult{}, reporter.stages...),
+	}
+	if reporter.t.Failed() {
+		report.Status = STAGE_STATUS_FAILED
+	}
+	for _, stage := range report.Stages {
+		if stage.Status == STAGE_STATUS_FAILED {
+			report.Status = STAGE_STATUS_FAILED
+		}
+	}
+	return report
+}
+
+// writeReports writes the JUnit XML and the JSON report to the given directory, named after the test.
+func (reporter *stageReporter) writeReports(reportDir string) error {
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		return err
+	}
+
+	report := reporter.getReport()
+	basePath := filepath.Join(reportDir, getReportFileName(report.Test))
+
+	junitReport, err := formatJUnitReport(report)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(basePath+".xml", junitReport, 0644); err != nil {
+		return err
+	}
+
+	jsonReport, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(basePath+".json", jsonReport, 0644)
+}
+
+// getReportFileName turns the given test name, which contains a slash for subtests, into a file name.
+func getReportFileName(testName string) string {
+	return reportFileNameRegexp.ReplaceAllString(testName, "_")
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+// formatJUnitReport formats the given report as a JUnit test suite named after the test, with a test case per stage.
+func formatJUnitReport(report stageReport) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      report.Test,
+		Tests:     len(report.Stages),
+		Time:      formatJUnitSeconds(report.DurationSeconds),
+		Timestamp: report.StartedAt.UTC().Format(time.RFC3339),
+		TestCases: []junitTestCase{},
+	}
+	for _, stage := range report.Stages {
+		testCase := junitTestCase{ClassName: report.Test, Name: stage.Name, Time: formatJUnitSeconds(stage.DurationSeconds)}
+		switch stage.Status {
+		case STAGE_STATUS_FAILED:
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: stage.Error, Content: stage.Error}
+		case STAGE_STATUS_SKIPPED, STAGE_STATUS_CANCELLED:
+			suite.Skipped++
+			testCase.Skipped = &struct{}{}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	content, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+func formatJUnitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
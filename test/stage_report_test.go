@@ -0,0 +1,182 @@
+package test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageReporterRunTestStage(t *testing.T) {
+	// Not parallel, as it sets environment variables
+	os.Setenv("SKIP_report_skipped", "true")
+	defer os.Unsetenv("SKIP_report_skipped")
+
+	reporter := newStageReporter(t)
+	ran := false
+	reporter.RunTestStage("report_passed", func() { ran = true })
+	reporter.RunTestStage("report_skipped", func() { t.Fatal("skipped stage ran") })
+	assert.PanicsWithValue(t, "boom", func() {
+		reporter.RunTestStage("report_panicked", func() { panic("boom") })
+	})
+
+	assert.True(t, ran)
+	report := reporter.getReport()
+	require.Len(t, report.Stages, 3)
+	assert.Equal(t, STAGE_STATUS_FAILED, report.Status)
+	assert.Equal(t, "report_passed", report.Stages[0].Name)
+	assert.Equal(t, STAGE_STATUS_PASSED, report.Stages[0].Status)
+	assert.Equal(t, STAGE_STATUS_SKIPPED, report.Stages[1].Status)
+	assert.Zero(t, report.Stages[1].DurationSeconds)
+	assert.Equal(t, STAGE_STATUS_FAILED, report.Stages[2].Status)
+	assert.Equal(t, "panic: boom", report.Stages[2].Error)
+}
+
+// fakeStageTestingT is a test that can fail without failing the test using it. Like testing.T, FailNow exits the
+// goroutine of the test, so run the fake test with run.
+type fakeStageTestingT struct {
+	mutex  sync.Mutex
+	failed bool
+}
+
+// run runs the given function as the fake test in its own goroutine and waits for it to finish or exit.
+func (t *fakeStageTestingT) run(test func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		test()
+	}()
+	<-done
+}
+
+func (t *fakeStageTestingT) Fail() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.failed = true
+}
+
+func (t *fakeStageTestingT) FailNow() {
+	t.Fail()
+	runtime.Goexit()
+}
+
+func (t *fakeStageTestingT) Failed() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.failed
+}
+
+func (t *fakeStageTestingT) Fatal(args ...interface{})                 { t.FailNow() }
+func (t *fakeStageTestingT) Fatalf(format string, args ...interface{}) { t.FailNow() }
+func (t *fakeStageTestingT) Error(args ...interface{})                 { t.Fail() }
+func (t *fakeStageTestingT) Errorf(format string, args ...interface{}) { t.Fail() }
+func (t *fakeStageTestingT) Name() string                              { return "TestFake" }
+
+func TestStageReporterRecordsFailedTeardownOfFailedTest(t *testing.T) {
+	t.Parallel()
+
+	interruption := newTestInterruptionWithLog(t)
+	interruption.Interrupt("received interrupt")
+
+	fakeT := &fakeStageTestingT{}
+	reporter := &stageReporter{t: fakeT, interruption: interruption, startedAt: time.Now(), stages: []stageResult{}}
+
+	teardownDone := false
+	fakeT.run(func() {
+		defer reporter.RunTestStage("teardown", func() {
+			fakeT.FailNow()
+			teardownDone = true
+		})
+		defer reporter.RunTestStage("teardown_passed", func() {})
+
+		fakeT.Error("the test failed before its teardown")
+	})
+
+	assert.True(t, fakeT.Failed())
+	assert.False(t, teardownDone)
+
+	report := reporter.getReport()
+	assert.Equal(t, STAGE_STATUS_FAILED, report.Status)
+	require.Len(t, report.Stages, 2)
+	assert.Equal(t, "teardown_passed", report.Stages[0].Name)
+	assert.Equal(t, STAGE_STATUS_PASSED, report.Stages[0].Status)
+	assert.Equal(t, "teardown", report.Stages[1].Name)
+	assert.Equal(t, STAGE_STATUS_FAILED, report.Stages[1].Status)
+	assert.Equal(t, STAGE_FAILED_MESSAGE, report.Stages[1].Error)
+
+	summary, _ := interruption.getSummary()
+	require.Len(t, summary.Teardowns, 2)
+	assert.Equal(t, STAGE_STATUS_FAILED, summary.Teardowns[1].Status)
+}
+
+func TestFormatJUnitReport(t *testing.T) {
+	t.Parallel()
+
+	report := stageReport{
+		Test:            "TestMySqlReplicas",
+		Status:          STAGE_STATUS_FAILED,
+		StartedAt:       time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		DurationSeconds: 912.5,
+		Stages: []stageResult{
+			{Name: "deploy", Status: STAGE_STATUS_PASSED, DurationSeconds: 845.25},
+			{Name: "validate_outputs", Status: STAGE_STATUS_FAILED, DurationSeconds: 1.5, Error: STAGE_FAILED_MESSAGE},
+			{Name: "sql_tests", Status: STAGE_STATUS_SKIPPED},
+		},
+	}
+
+	content, err := formatJUnitReport(report)
+	require.NoError(t, err)
+
+	suite := junitTestSuite{}
+	require.NoError(t, xml.Unmarshal(content, &suite))
+	assert.Equal(t, "TestMySqlReplicas", suite.Name)
+	assert.Equal(t, 3, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	assert.Equal(t, 1, suite.Skipped)
+	assert.Equal(t, "912.500", suite.Time)
+	assert.Equal(t, "2026-10-01T12:00:00Z", suite.Timestamp)
+	require.Len(t, suite.TestCases, 3)
+	assert.Equal(t, "845.250", suite.TestCases[0].Time)
+	assert.Nil(t, suite.TestCases[0].Failure)
+	require.NotNil(t, suite.TestCases[1].Failure)
+	assert.Equal(t, STAGE_FAILED_MESSAGE, suite.TestCases[1].Failure.Message)
+	assert.NotNil(t, suite.TestCases[2].Skipped)
+}
+
+func TestStageReporterWriteReports(t *testing.T) {
+	t.Parallel()
+
+	reportDir, err := ioutil.TempDir("", "stage-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(reportDir)
+
+	reporter := newStageReporter(t)
+	reporter.RunTestStage("report_written", func() {})
+	require.NoError(t, reporter.writeReports(reportDir))
+
+	assert.FileExists(t, filepath.Join(reportDir, "TestStageReporterWriteReports.xml"))
+	content, err := ioutil.ReadFile(filepath.Join(reportDir, "TestStageReporterWriteReports.json"))
+	require.NoError(t, err)
+
+	report := stageReport{}
+	require.NoError(t, json.Unmarshal(content, &report))
+	assert.Equal(t, "TestStageReporterWriteReports", report.Test)
+	assert.Equal(t, STAGE_STATUS_PASSED, report.Status)
+	require.Len(t, report.Stages, 1)
+	assert.Equal(t, "report_written", report.Stages[0].Name)
+}
+
+func TestGetReportFileName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "TestEngineUpgrade_MySql5.7To8.0", getReportFileName("TestEngineUpgrade/MySql5.7To8.0"))
+	assert.Equal(t, "TestMySqlReplicas_a_b", getReportFileName("TestMySqlReplicas/a b"))
+}
@@ -4,7 +4,9 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/gruntwork-io/terraform-google-sql/test/cloudsql"
 	"github.com/gruntwork-io/terratest/modules/gcp"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
@@ -19,6 +21,11 @@ const KEY_PROJECT = "project"
 const KEY_MASTER_ZONE = "masterZone"
 const KEY_FAILOVER_REPLICA_ZONE = "failoverReplicaZone"
 const KEY_READ_REPLICA_ZONE = "readReplicaZone"
+const KEY_DR_REGION = "drRegion"
+const KEY_DR_READ_REPLICA_ZONE = "drReadReplicaZone"
+const KEY_IAM_USER_EMAIL = "iamUserEmail"
+const KEY_ENCRYPTION_KEY_NAME = "encryptionKeyName"
+const KEY_ROW_COUNT = "rowCount"
 
 const OUTPUT_MASTER_IP_ADDRESSES = "master_ip_addresses"
 const OUTPUT_MASTER_INSTANCE_NAME = "master_instance_name"
@@ -27,7 +34,9 @@ const OUTPUT_MASTER_PROXY_CONNECTION = "master_proxy_connection"
 const OUTPUT_FAILOVER_PROXY_CONNECTION = "failover_proxy_connection"
 const OUTPUT_READ_REPLICA_PROXY_CONNECTIONS = "read_replica_proxy_connections"
 const OUTPUT_READ_REPLICA_INSTANCE_NAMES = "read_replica_instance_names"
+const OUTPUT_READ_REPLICA_INSTANCE_NAMES_BY_KEY = "read_replica_instance_names_by_key"
 const OUTPUT_READ_REPLICA_PUBLIC_IPS = "read_replica_public_ips"
+const OUTPUT_READ_REPLICA_REGIONS = "read_replica_regions"
 const OUTPUT_MASTER_PUBLIC_IP = "master_public_ip"
 const OUTPUT_MASTER_PRIVATE_IP = "master_private_ip"
 const OUTPUT_MASTER_CA_CERT = "master_ca_cert"
@@ -35,6 +44,32 @@ const OUTPUT_CLIENT_CA_CERT = "client_ca_cert"
 const OUTPUT_CLIENT_PRIVATE_KEY = "client_private_key"
 
 const OUTPUT_DB_NAME = "db_name"
+const OUTPUT_ADDITIONAL_DB_NAMES = "additional_db_names"
+const OUTPUT_ADDITIONAL_USER_NAMES = "additional_user_names"
+const OUTPUT_IAM_USER_NAMES = "iam_user_names"
+
+// Additional databases and users created next to the default ones. The first database is created with an explicit
+// charset and collation, the second one with the engine defaults.
+const ADDITIONAL_DB_NAME_APP = "testdb_app"
+const ADDITIONAL_DB_NAME_REPORTING = "testdb_reporting"
+
+var ADDITIONAL_DB_USERS = map[string]string{
+	"testuser_app":       "testpassword_app",
+	"testuser_reporting": "testpassword_reporting",
+}
+
+const MYSQL_ADDITIONAL_DB_CHARSET = "utf8mb4"
+const MYSQL_ADDITIONAL_DB_COLLATION = "utf8mb4_general_ci"
+const POSTGRES_ADDITIONAL_DB_CHARSET = "UTF8"
+const POSTGRES_ADDITIONAL_DB_COLLATION = "en_US.UTF8"
+
+// Query Insights configuration applied to the replicas examples, using a non-default query length to check that the
+// configuration round-trips
+var QUERY_INSIGHTS_CONFIG = cloudsql.InsightsConfig{
+	QueryInsightsEnabled:  true,
+	QueryStringLength:     2048,
+	RecordApplicationTags: true,
+}
 
 const MYSQL_CREATE_TEST_TABLE_WITH_AUTO_INCREMENT_STATEMENT = "CREATE TABLE IF NOT EXISTS test (id int NOT NULL AUTO_INCREMENT, name varchar(10) NOT NULL, PRIMARY KEY (ID))"
 const MYSQL_INSERT_TEST_ROW = "INSERT INTO test(name) VALUES(?)"
@@ -52,6 +87,19 @@ func getRandomRegion(t *testing.T, projectID string) string {
 	return gcp.GetRandomRegion(t, projectID, approvedRegions, []string{})
 }
 
+func getTwoDistinctRandomRegions(t *testing.T, projectID string) (string, string) {
+	firstRegion := getRandomRegion(t, projectID)
+	secondRegion := getRandomRegion(t, projectID)
+	for {
+		if firstRegion != secondRegion {
+			break
+		}
+		secondRegion = getRandomRegion(t, projectID)
+	}
+
+	return firstRegion, secondRegion
+}
+
 func getTwoDistinctRandomZonesForRegion(t *testing.T, projectID string, region string) (string, string) {
 	firstZone := gcp.GetRandomZoneForRegion(t, projectID, region)
 	secondZone := gcp.GetRandomZoneForRegion(t, projectID, region)
@@ -65,7 +113,7 @@ func getTwoDistinctRandomZonesForRegion(t *testing.T, projectID string, region s
 	return firstZone, secondZone
 }
 
-func createTerratestOptionsForCloudSql(projectId string, region string, exampleDir string, namePrefix string) *terraform.Options {
+func createTerratestOptionsForCloudSql(t *testing.T, projectId string, region string, exampleDir string, namePrefix string) *terraform.Options {
 	terratestOptions := &terraform.Options{
 		// The path to where your Terraform code is located
 		TerraformDir: exampleDir,
@@ -76,13 +124,14 @@ func createTerratestOptionsForCloudSql(projectId string, region string, exampleD
 			"db_name":              DB_NAME,
 			"master_user_name":     DB_USER,
 			"master_user_password": DB_PASS,
+			"custom_labels":        createRunLabels(t, time.Now()),
 		},
 	}
 
-	return terratestOptions
+	return setRetryableErrors(terratestOptions)
 }
 
-func createTerratestOptionsForCloudSqlReplicas(projectId string, region string, exampleDir string, namePrefix string, masterZone string, failoverReplicaZone string, numReadReplicas int, readReplicaZone string) *terraform.Options {
+func createTerratestOptionsForCloudSqlReplicas(t *testing.T, projectId string, region string, exampleDir string, namePrefix string, masterZone string, failoverReplicaZone string, numReadReplicas int, readReplicaZone string) *terraform.Options {
 	terratestOptions := &terraform.Options{
 		// The path to where your Terraform code is located
 		TerraformDir: exampleDir,
@@ -97,10 +146,18 @@ func createTerratestOptionsForCloudSqlReplicas(projectId string, region string,
 			"db_name":               DB_NAME,
 			"master_user_name":      DB_USER,
 			"master_user_password":  DB_PASS,
+			"custom_labels":         createRunLabels(t, time.Now()),
 		},
 	}
 
-	return terratestOptions
+	return setRetryableErrors(terratestOptions)
+}
+
+// setQueryInsightsVars configures the given replicas example options with QUERY_INSIGHTS_CONFIG.
+func setQueryInsightsVars(terraformOptions *terraform.Options) {
+	terraformOptions.Vars["query_insights_enabled"] = QUERY_INSIGHTS_CONFIG.QueryInsightsEnabled
+	terraformOptions.Vars["query_string_length"] = QUERY_INSIGHTS_CONFIG.QueryStringLength
+	terraformOptions.Vars["record_application_tags"] = QUERY_INSIGHTS_CONFIG.RecordApplicationTags
 }
 
 func createTerratestOptionsForClientCert(projectId string, region string, exampleDir string, commonName string, instanceName string) *terraform.Options {
@@ -116,7 +173,67 @@ func createTerratestOptionsForClientCert(projectId string, region string, exampl
 		},
 	}
 
-	return terratestOptions
+	return setRetryableErrors(terratestOptions)
+}
+
+func createTerratestOptionsForBastion(projectId string, region string, zone string, bastionDir string, name string, network string, sshPublicKey string) *terraform.Options {
+	terratestOptions := &terraform.Options{
+		// The path to where your Terraform code is located
+		TerraformDir: bastionDir,
+		Vars: map[string]interface{}{
+			"region":         region,
+			"zone":           zone,
+			"project":        projectId,
+			"name":           name,
+			"network":        network,
+			"ssh_user":       BASTION_SSH_USER,
+			"ssh_public_key": sshPublicKey,
+		},
+	}
+
+	return setRetryableErrors(terratestOptions)
+}
+
+func createTerratestOptionsForPrivateNetwork(projectId string, region string, fixtureDir string, name string) *terraform.Options {
+	terratestOptions := &terraform.Options{
+		// The path to where your Terraform code is located
+		TerraformDir: fixtureDir,
+		Vars: map[string]interface{}{
+			"region":  region,
+			"project": projectId,
+			"name":    name,
+		},
+	}
+
+	return setRetryableErrors(terratestOptions)
+}
+
+// createAdditionalDatabasesVar builds the additional_databases input for the examples. Only the app database is given
+// an explicit charset and collation.
+func createAdditionalDatabasesVar(charset string, collation string) map[string]interface{} {
+	return map[string]interface{}{
+		ADDITIONAL_DB_NAME_APP: map[string]string{
+			"charset":   charset,
+			"collation": collation,
+		},
+		ADDITIONAL_DB_NAME_REPORTING: map[string]string{},
+	}
+}
+
+// createAdditionalUsersVar builds the additional_users input for the examples from ADDITIONAL_DB_USERS.
+func createAdditionalUsersVar() map[string]interface{} {
+	users := map[string]interface{}{}
+	for user, password := range ADDITIONAL_DB_USERS {
+		users[user] = map[string]string{
+			"password": password,
+			"host":     "%",
+		}
+	}
+	return users
+}
+
+func getAdditionalDBNames() []string {
+	return []string{ADDITIONAL_DB_NAME_APP, ADDITIONAL_DB_NAME_REPORTING}
 }
 
 func createTempFile(t *testing.T, content []byte) *os.File {